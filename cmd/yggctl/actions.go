@@ -8,6 +8,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"text/tabwriter"
 	"text/template"
@@ -237,6 +238,143 @@ func workersAction(c *cli.Context) error {
 	return nil
 }
 
+func workersUsageAction(c *cli.Context) error {
+	conn, err := connectBus()
+	if err != nil {
+		return cli.Exit(fmt.Errorf("cannot connect to bus: %w", err), 1)
+	}
+
+	obj := conn.Object("com.redhat.Yggdrasil1", "/com/redhat/Yggdrasil1")
+	var usage []map[string]string
+	if err := obj.Call("com.redhat.Yggdrasil1.ListTransmitUsage", dbus.Flags(0)).Store(&usage); err != nil {
+		return cli.Exit(fmt.Errorf("cannot list transmit usage: %v", err), 1)
+	}
+
+	switch c.String("format") {
+	case "json":
+		data, err := json.Marshal(usage)
+		if err != nil {
+			return cli.Exit(fmt.Errorf("cannot marshal transmit usage: %v", err), 1)
+		}
+		fmt.Println(string(data))
+	case "table":
+		writer := tabwriter.NewWriter(os.Stdout, 4, 4, 2, ' ', 0)
+		_, err = fmt.Fprint(writer, "WORKER\tMESSAGES\tBYTES\tTHROTTLED\tRATE\tDAILY MESSAGES\tDAILY BYTES\n")
+		if err != nil {
+			return cli.Exit(fmt.Errorf("cannot write header of table: %w", err), 1)
+		}
+		for _, u := range usage {
+			_, _ = fmt.Fprintf(
+				writer,
+				"%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				u["worker"],
+				u["messages"],
+				u["bytes"],
+				u["throttled"],
+				formatLimit(u["rate"]),
+				formatLimit(u["daily_messages"]),
+				formatLimit(u["daily_bytes"]),
+			)
+		}
+		err = writer.Flush()
+		if err != nil {
+			return cli.Exit(fmt.Errorf("unable to flush tab writer: %v", err), 1)
+		}
+	default:
+		return cli.Exit(fmt.Errorf("unknown format type: %v", c.String("format")), 1)
+	}
+
+	return nil
+}
+
+func workersInfoAction(c *cli.Context) error {
+	if c.Args().Len() > 1 {
+		return cli.Exit("at most one worker can be given", 1)
+	}
+
+	conn, err := connectBus()
+	if err != nil {
+		return cli.Exit(fmt.Errorf("cannot connect to bus: %w", err), 1)
+	}
+
+	obj := conn.Object("com.redhat.Yggdrasil1", "/com/redhat/Yggdrasil1")
+	var data map[string]string
+	if err := obj.Call("com.redhat.Yggdrasil1.ListWorkerManifests", dbus.Flags(0)).Store(&data); err != nil {
+		return cli.Exit(fmt.Errorf("cannot list worker manifests: %v", err), 1)
+	}
+
+	manifests := make(map[string]ipc.WorkerManifest, len(data))
+	for worker, value := range data {
+		if c.Args().Present() && worker != c.Args().First() {
+			continue
+		}
+		var manifest ipc.WorkerManifest
+		if err := json.Unmarshal([]byte(value), &manifest); err != nil {
+			return cli.Exit(fmt.Errorf("cannot unmarshal manifest of worker %v: %v", worker, err), 1)
+		}
+		manifests[worker] = manifest
+	}
+	if c.Args().Present() && len(manifests) == 0 {
+		return cli.Exit(fmt.Errorf("unknown worker: %v", c.Args().First()), 1)
+	}
+
+	switch c.String("format") {
+	case "json":
+		data, err := json.Marshal(manifests)
+		if err != nil {
+			return cli.Exit(fmt.Errorf("cannot marshal worker manifests: %v", err), 1)
+		}
+		fmt.Println(string(data))
+	case "text":
+		workers := make([]string, 0, len(manifests))
+		for worker := range manifests {
+			workers = append(workers, worker)
+		}
+		sort.Strings(workers)
+
+		for i, worker := range workers {
+			if i > 0 {
+				fmt.Println()
+			}
+			if err := printManifest(os.Stdout, worker, manifests[worker]); err != nil {
+				return cli.Exit(fmt.Errorf("cannot print manifest: %v", err), 1)
+			}
+		}
+	default:
+		return cli.Exit(fmt.Errorf("unknown format type: %v", c.String("format")), 1)
+	}
+
+	return nil
+}
+
+// printManifest writes the manifest of worker to w as a list of fields.
+func printManifest(w io.Writer, worker string, manifest ipc.WorkerManifest) error {
+	orNone := func(value string) string {
+		if value == "" {
+			return "-"
+		}
+		return value
+	}
+
+	writer := tabwriter.NewWriter(w, 4, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintf(writer, "Worker:\t%v\n", worker)
+	_, _ = fmt.Fprintf(writer, "Version:\t%v\n", orNone(manifest.Version))
+	_, _ = fmt.Fprintf(writer, "Description:\t%v\n", orNone(manifest.Description))
+	_, _ = fmt.Fprintf(writer, "Content types:\t%v\n", orNone(strings.Join(manifest.ContentTypes, ", ")))
+	_, _ = fmt.Fprintf(writer, "Content schema:\t%v\n", orNone(string(manifest.ContentSchema)))
+	_, _ = fmt.Fprintf(writer, "Metadata schema:\t%v\n", orNone(string(manifest.MetadataSchema)))
+	_, _ = fmt.Fprintf(writer, "Cancellable:\t%v\n", manifest.Cancellable)
+	return writer.Flush()
+}
+
+// formatLimit returns value, or "-" if value is a disabled limit.
+func formatLimit(value string) string {
+	if value == "" || value == "0" {
+		return "-"
+	}
+	return value
+}
+
 func dispatchAction(c *cli.Context) error {
 	conn, err := connectBus()
 	if err != nil {
@@ -275,6 +413,121 @@ func dispatchAction(c *cli.Context) error {
 	return nil
 }
 
+func jobsAction(c *cli.Context) error {
+	conn, err := connectBus()
+	if err != nil {
+		return cli.Exit(fmt.Errorf("cannot connect to bus: %w", err), 1)
+	}
+
+	obj := conn.Object("com.redhat.Yggdrasil1", "/com/redhat/Yggdrasil1")
+	var jobs []map[string]string
+	if err := obj.Call("com.redhat.Yggdrasil1.ListJobs", dbus.Flags(0)).Store(&jobs); err != nil {
+		return cli.Exit(fmt.Errorf("cannot list jobs: %v", err), 1)
+	}
+
+	switch c.String("format") {
+	case "json":
+		data, err := json.Marshal(jobs)
+		if err != nil {
+			return cli.Exit(fmt.Errorf("cannot marshal jobs: %v", err), 1)
+		}
+		fmt.Println(string(data))
+	case "table":
+		writer := tabwriter.NewWriter(os.Stdout, 4, 4, 2, ' ', 0)
+		_, err = fmt.Fprint(writer, "JOB\tWORKER\tSCHEDULE\tNEXT RUN\tLAST RUN\tLAST RESULT\n")
+		if err != nil {
+			return cli.Exit(fmt.Errorf("cannot write header of table: %w", err), 1)
+		}
+		for _, job := range jobs {
+			_, _ = fmt.Fprintf(
+				writer,
+				"%s\t%s\t%s\t%s\t%s\t%s\n",
+				job["name"],
+				job["directive"],
+				job["schedule"],
+				job["next_run"],
+				job["last_run"],
+				job["last_result"],
+			)
+		}
+		err = writer.Flush()
+		if err != nil {
+			return cli.Exit(fmt.Errorf("unable to flush tab writer: %v", err), 1)
+		}
+	default:
+		return cli.Exit(fmt.Errorf("unknown format type: %v", c.String("format")), 1)
+	}
+
+	return nil
+}
+
+func scheduleListAction(c *cli.Context) error {
+	conn, err := connectBus()
+	if err != nil {
+		return cli.Exit(fmt.Errorf("cannot connect to bus: %w", err), 1)
+	}
+
+	obj := conn.Object("com.redhat.Yggdrasil1", "/com/redhat/Yggdrasil1")
+	var scheduled []map[string]string
+	if err := obj.Call("com.redhat.Yggdrasil1.ListScheduled", dbus.Flags(0)).Store(&scheduled); err != nil {
+		return cli.Exit(fmt.Errorf("cannot list scheduled messages: %v", err), 1)
+	}
+
+	switch c.String("format") {
+	case "json":
+		data, err := json.Marshal(scheduled)
+		if err != nil {
+			return cli.Exit(fmt.Errorf("cannot marshal scheduled messages: %v", err), 1)
+		}
+		fmt.Println(string(data))
+	case "table":
+		writer := tabwriter.NewWriter(os.Stdout, 4, 4, 2, ' ', 0)
+		_, err = fmt.Fprint(writer, "MESSAGE ID\tWORKER\tNOT BEFORE\tNOT AFTER\n")
+		if err != nil {
+			return cli.Exit(fmt.Errorf("cannot write header of table: %w", err), 1)
+		}
+		for _, item := range scheduled {
+			_, _ = fmt.Fprintf(
+				writer,
+				"%s\t%s\t%s\t%s\n",
+				item["message_id"],
+				item["directive"],
+				item["not_before"],
+				item["not_after"],
+			)
+		}
+		err = writer.Flush()
+		if err != nil {
+			return cli.Exit(fmt.Errorf("unable to flush tab writer: %v", err), 1)
+		}
+	default:
+		return cli.Exit(fmt.Errorf("unknown format type: %v", c.String("format")), 1)
+	}
+
+	return nil
+}
+
+func scheduleCancelAction(c *cli.Context) error {
+	if c.Args().Len() != 1 {
+		return cli.Exit("a message ID is required", 1)
+	}
+
+	conn, err := connectBus()
+	if err != nil {
+		return cli.Exit(fmt.Errorf("cannot connect to bus: %w", err), 1)
+	}
+
+	messageID := c.Args().First()
+	obj := conn.Object("com.redhat.Yggdrasil1", "/com/redhat/Yggdrasil1")
+	if err := obj.Call("com.redhat.Yggdrasil1.CancelScheduled", dbus.Flags(0), messageID).Store(); err != nil {
+		return cli.Exit(fmt.Errorf("cannot cancel scheduled message: %v", err), 1)
+	}
+
+	fmt.Printf("Cancelled scheduled message %v\n", messageID)
+
+	return nil
+}
+
 func listenAction(ctx *cli.Context) error {
 	conn, err := connectBus()
 	if err != nil {
@@ -313,6 +566,22 @@ func listenAction(ctx *cli.Context) error {
 					return cli.Exit(fmt.Errorf("cannot cast %T as map[string]string", s.Body[4]), 1)
 				}
 			}
+			// Render progress reported by the worker in a human-readable
+			// form.
+			if ipc.WorkerEventName(name) == ipc.WorkerEventNameWorking {
+				progress, err := ipc.ParseProgress(data)
+				if err == nil && progress != nil {
+					log.Printf(
+						"%v: %v: %v: %v: %v",
+						worker,
+						messageID,
+						ipc.WorkerEventName(name),
+						responseTo,
+						progress,
+					)
+					continue
+				}
+			}
 			parsedData, err := json.Marshal(data)
 			if err != nil {
 				return cli.Exit(fmt.Errorf("unable to parse optional data: %v", data), 1)
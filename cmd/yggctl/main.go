@@ -185,6 +185,33 @@ communicate properly with yggd.`,
 					},
 					Action: workersAction,
 				},
+				{
+					Name:        "usage",
+					Usage:       "Show how much data workers have transmitted today",
+					Description: "The usage command prints the number of messages and bytes each worker has transmitted today, the number of Transmit calls refused because of its rate limit or quotas, and the limits that apply to it.",
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:  "format",
+							Usage: "Print output in `FORMAT` (json or table)",
+							Value: "table",
+						},
+					},
+					Action: workersUsageAction,
+				},
+				{
+					Name:        "info",
+					Usage:       "Show the manifests of connected workers",
+					UsageText:   "yggctl workers info [WORKER]",
+					Description: "The info command prints the manifest of WORKER, or of every connected worker if WORKER is omitted: its version and description, the content types it accepts, the JSON Schemas the content and metadata of data dispatched to it must be valid against, and whether it handles cancel messages.",
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:  "format",
+							Usage: "Print output in `FORMAT` (json or text)",
+							Value: "text",
+						},
+					},
+					Action: workersInfoAction,
+				},
 			},
 		},
 		{
@@ -254,6 +281,51 @@ communicate properly with yggd.`,
 			},
 			Action: messageJournalAction,
 		},
+		{
+			Name:  "jobs",
+			Usage: "Interact with locally configured recurring jobs",
+			Subcommands: []*cli.Command{
+				{
+					Name:        "list",
+					Usage:       "List jobs",
+					Description: "The list command prints the jobs defined in the jobs.d directory, along with the time of their next and last run.",
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:  "format",
+							Usage: "Print output in `FORMAT` (json or table)",
+							Value: "table",
+						},
+					},
+					Action: jobsAction,
+				},
+			},
+		},
+		{
+			Name:  "schedule",
+			Usage: "Interact with messages scheduled to be dispatched later",
+			Subcommands: []*cli.Command{
+				{
+					Name:        "list",
+					Usage:       "List scheduled messages",
+					Description: "The list command prints the messages received with a not_before time in the future that have not been dispatched yet.",
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:  "format",
+							Usage: "Print output in `FORMAT` (json or table)",
+							Value: "table",
+						},
+					},
+					Action: scheduleListAction,
+				},
+				{
+					Name:        "cancel",
+					Usage:       "Cancel a scheduled message",
+					UsageText:   "yggctl schedule cancel MESSAGE_ID",
+					Description: "The cancel command removes the message MESSAGE_ID from the schedule so that it is never dispatched.",
+					Action:      scheduleCancelAction,
+				},
+			},
+		},
 		{
 			Name:        "listen",
 			Usage:       "Listen to worker event output",
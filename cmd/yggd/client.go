@@ -64,15 +64,15 @@ func (c *Client) Connect() error {
 	// and publishes "connection-status" messages to MQTT.
 	go func() {
 		for dispatchers := range c.dispatcher.Dispatchers {
-			data, err := json.Marshal(dispatchers)
+			data, err := json.Marshal([]interface{}{dispatchers, c.dispatcher.ListManifests()})
 			if err != nil {
 				log.Errorf("cannot marshal dispatcher map to JSON: %v", err)
 				continue
 			}
 
-			// Create a checksum of the dispatchers map. If it's identical
-			// to the previous checksum, skip publishing a connection-status
-			// message.
+			// Create a checksum of the dispatchers map and worker manifests.
+			// If it's identical to the previous checksum, skip publishing a
+			// connection-status message.
 			sum := fmt.Sprintf("%x", sha256.Sum256(data))
 			oldSum := c.prevDispatchersHash.Load()
 			if oldSum != nil {
@@ -110,6 +110,16 @@ func (c *Client) Connect() error {
 		}
 	}()
 
+	// start receiving events from the dispatcher and publish them as event
+	// messages on the "control" destination.
+	go func() {
+		for event := range c.dispatcher.OutboundEvents {
+			if _, _, _, err := c.SendEventMessage(&event); err != nil {
+				log.Errorf("cannot send event message: %v", err)
+			}
+		}
+	}()
+
 	// set a transport RxHandlerFunc that calls the client's control and data
 	// receive handler functions.
 	err := c.transporter.SetRxHandler(
@@ -191,7 +201,10 @@ func (c *Client) Connect() error {
 		for e := range c.dispatcher.WorkerEvents {
 			args := []interface{}{e.Worker, e.Name, e.MessageID, e.ResponseTo}
 			switch e.Name {
-			case ipc.WorkerEventNameWorking:
+			case ipc.WorkerEventNameWorking,
+				ipc.WorkerEventNameTimeout,
+				ipc.WorkerEventNameFailed,
+				ipc.WorkerEventNameDenied:
 				args = append(args, e.Data)
 			}
 			if err := c.conn.Emit("/com/redhat/Yggdrasil1", "com.redhat.Yggdrasil1.WorkerEvent", args...); err != nil {
@@ -210,6 +223,20 @@ func (c *Client) ListWorkers() (map[string]map[string]string, *dbus.Error) {
 	return c.dispatcher.FlattenDispatchers(), nil
 }
 
+// ListWorkerManifests implements the com.redhat.Yggdrasil1.ListWorkerManifests
+// method.
+func (c *Client) ListWorkerManifests() (map[string]string, *dbus.Error) {
+	manifests := make(map[string]string)
+	for worker, manifest := range c.dispatcher.ListManifests() {
+		data, err := json.Marshal(manifest)
+		if err != nil {
+			return nil, dbus.MakeFailedError(fmt.Errorf("cannot marshal manifest: %w", err))
+		}
+		manifests[worker] = string(data)
+	}
+	return manifests, nil
+}
+
 // MessageJournal implements the com.redhat.Yggdrasil1.MessageJournal method.
 func (c *Client) MessageJournal(
 	messageID string,
@@ -236,6 +263,83 @@ func (c *Client) MessageJournal(
 	return journal, nil
 }
 
+// ListScheduled implements the com.redhat.Yggdrasil1.ListScheduled method.
+func (c *Client) ListScheduled() ([]map[string]string, *dbus.Error) {
+	entries := c.dispatcher.ListScheduled()
+	scheduled := make([]map[string]string, 0, len(entries))
+	for _, entry := range entries {
+		item := map[string]string{
+			"message_id":  entry.Data.MessageID,
+			"directive":   entry.Data.Directive,
+			"response_to": entry.Data.ResponseTo,
+			"not_before":  entry.NotBefore.Format(time.RFC3339),
+			"not_after":   "",
+		}
+		if !entry.NotAfter.IsZero() {
+			item["not_after"] = entry.NotAfter.Format(time.RFC3339)
+		}
+		scheduled = append(scheduled, item)
+	}
+	return scheduled, nil
+}
+
+// CancelScheduled implements the com.redhat.Yggdrasil1.CancelScheduled method.
+func (c *Client) CancelScheduled(messageID string) *dbus.Error {
+	cancelled, err := c.dispatcher.CancelScheduled(messageID)
+	if err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	if !cancelled {
+		return dbus.MakeFailedError(fmt.Errorf("message %v is not scheduled", messageID))
+	}
+	return nil
+}
+
+// ListJobs implements the com.redhat.Yggdrasil1.ListJobs method.
+func (c *Client) ListJobs() ([]map[string]string, *dbus.Error) {
+	statuses := c.dispatcher.ListJobs()
+	jobs := make([]map[string]string, 0, len(statuses))
+	for _, status := range statuses {
+		job := map[string]string{
+			"name":            status.Name,
+			"directive":       status.Directive,
+			"schedule":        status.Schedule,
+			"next_run":        "",
+			"last_run":        "",
+			"last_message_id": status.LastMessageID,
+			"last_result":     status.LastResult,
+		}
+		if !status.NextRun.IsZero() {
+			job["next_run"] = status.NextRun.Format(time.RFC3339)
+		}
+		if !status.LastRun.IsZero() {
+			job["last_run"] = status.LastRun.Format(time.RFC3339)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// ListTransmitUsage implements the com.redhat.Yggdrasil1.ListTransmitUsage
+// method.
+func (c *Client) ListTransmitUsage() ([]map[string]string, *dbus.Error) {
+	statuses := c.dispatcher.ListTransmitUsage()
+	usage := make([]map[string]string, 0, len(statuses))
+	for _, status := range statuses {
+		usage = append(usage, map[string]string{
+			"worker":         status.Worker,
+			"day":            status.Day,
+			"messages":       strconv.Itoa(status.Messages),
+			"bytes":          strconv.FormatInt(status.Bytes, 10),
+			"throttled":      strconv.Itoa(status.Throttled),
+			"rate":           strconv.Itoa(status.Rate),
+			"daily_messages": strconv.Itoa(status.DailyMessages),
+			"daily_bytes":    strconv.FormatInt(status.DailyBytes, 10),
+		})
+	}
+	return usage, nil
+}
+
 // Dispatch implements the com.redhat.Yggdrasil1.Dispatch method.
 func (c *Client) Dispatch(
 	directive string,
@@ -367,9 +471,14 @@ func (c *Client) ReceiveControlMessage(msg *yggdrasil.Control) error {
 				return fmt.Errorf("cancel command does not contain 'messageID' argument")
 			}
 
-			directive, err := work.ScrubName(directive)
+			// A message that has not been dispatched yet is removed from the
+			// schedule instead.
+			cancelled, err := c.dispatcher.CancelScheduled(cancelID)
 			if err != nil {
-				log.Debug(err)
+				return fmt.Errorf("cannot cancel scheduled message: %w", err)
+			}
+			if cancelled {
+				return nil
 			}
 
 			// Dispatch to appropriate worker.
@@ -412,23 +521,30 @@ func (c *Client) ConnectionStatus() (*yggdrasil.ConnectionStatus, error) {
 		}
 	}
 
+	manifests := make(map[string]yggdrasil.WorkerManifest)
+	for worker, manifest := range c.dispatcher.ListManifests() {
+		manifests[worker] = yggdrasil.WorkerManifest(manifest)
+	}
+
 	msg := yggdrasil.ConnectionStatus{
 		Type:      yggdrasil.MessageTypeConnectionStatus,
 		MessageID: uuid.New().String(),
 		Version:   1,
 		Sent:      time.Now(),
 		Content: struct {
-			CanonicalFacts map[string]interface{}       "json:\"canonical_facts\""
-			Dispatchers    map[string]map[string]string "json:\"dispatchers\""
-			State          yggdrasil.ConnectionState    "json:\"state\""
-			Tags           map[string]string            "json:\"tags,omitempty\""
-			ClientVersion  string                       "json:\"client_version,omitempty\""
+			CanonicalFacts map[string]interface{}              "json:\"canonical_facts\""
+			Dispatchers    map[string]map[string]string        "json:\"dispatchers\""
+			State          yggdrasil.ConnectionState           "json:\"state\""
+			Tags           map[string]string                   "json:\"tags,omitempty\""
+			ClientVersion  string                              "json:\"client_version,omitempty\""
+			Manifests      map[string]yggdrasil.WorkerManifest "json:\"manifests,omitempty\""
 		}{
 			CanonicalFacts: facts,
 			Dispatchers:    c.dispatcher.FlattenDispatchers(),
 			State:          yggdrasil.ConnectionStateOnline,
 			Tags:           tagMap,
 			ClientVersion:  constants.Version,
+			Manifests:      manifests,
 		},
 	}
 
@@ -12,10 +12,12 @@ import (
 	"time"
 
 	"github.com/coreos/go-systemd/v22/daemon"
+	"github.com/redhatinsights/yggdrasil/internal/cache"
 	"github.com/redhatinsights/yggdrasil/internal/config"
 	"github.com/redhatinsights/yggdrasil/internal/constants"
 	"github.com/redhatinsights/yggdrasil/internal/http"
 	"github.com/redhatinsights/yggdrasil/internal/messagejournal"
+	"github.com/redhatinsights/yggdrasil/internal/schedule"
 	"github.com/redhatinsights/yggdrasil/internal/transport"
 	"github.com/redhatinsights/yggdrasil/internal/work"
 
@@ -72,9 +74,30 @@ func setupDefaultConfig(c *cli.Context) {
 		MQTTConnectTimeout:       c.Duration(config.FlagNameMQTTConnectTimeout),
 		MQTTPublishTimeout:       c.Duration(config.FlagNameMQTTPublishTimeout),
 		MessageJournal:           c.String(config.FlagNameMessageJournal),
+		ProgressEventInterval:    c.Duration(config.FlagNameProgressEventInterval),
+		TransmitTimeout:          c.Duration(config.FlagNameTransmitTimeout),
+		ContentCacheSize:         c.Int64(config.FlagNameContentCacheSize),
 	}
 }
 
+// setupConfigTables reads per-worker configuration values, the directive
+// routing table and the content source allow-list from the "workers",
+// "routes" and "content-sources" tables of the config file, if one is in use.
+func setupConfigTables(c *cli.Context) error {
+	filePath := c.String("config")
+	if filePath == "" {
+		return nil
+	}
+	tables, err := config.ReadTablesFile(filePath)
+	if err != nil {
+		return cli.Exit(fmt.Errorf("cannot read configuration: %w", err), 1)
+	}
+	config.DefaultConfig.Workers = tables.Workers
+	config.DefaultConfig.Routes = tables.Routes
+	config.DefaultConfig.ContentSources = tables.ContentSources
+	return nil
+}
+
 // setupLogging sets up logging for yggd
 func setupLogging(c *cli.Context) error {
 	level, err := log.ParseLevel(config.DefaultConfig.LogLevel)
@@ -227,6 +250,69 @@ func setupMessageJournal(client *Client) error {
 	return nil
 }
 
+// setupSchedule opens the persistent store of scheduled messages in the
+// state directory and sets it as the dispatcher's schedule.
+func setupSchedule(dispatcher *work.Dispatcher) error {
+	store, err := schedule.Open(filepath.Join(constants.StateDir, "schedule.json"))
+	if err != nil {
+		return cli.Exit(fmt.Errorf("cannot open schedule: %w", err), 1)
+	}
+	dispatcher.Schedule = store
+	return nil
+}
+
+// setupContentDir creates the directory in the cache directory that detached
+// content is downloaded into, and the content cache if it is enabled.
+func setupContentDir(dispatcher *work.Dispatcher) error {
+	dir := filepath.Join(constants.CacheDir, "content")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return cli.Exit(fmt.Errorf("cannot create directory '%v': %w", dir, err), 1)
+	}
+	dispatcher.ContentDir = dir
+
+	if config.DefaultConfig.ContentCacheSize > 0 {
+		contentCache, err := cache.New(
+			filepath.Join(constants.CacheDir, "content-cache"),
+			config.DefaultConfig.ContentCacheSize,
+		)
+		if err != nil {
+			return cli.Exit(fmt.Errorf("cannot create content cache: %w", err), 1)
+		}
+		dispatcher.ContentCache = contentCache
+	}
+	return nil
+}
+
+// setupJobs reads the job definitions in the "jobs.d" directory next to the
+// config file and schedules them with the dispatcher.
+func setupJobs(c *cli.Context, dispatcher *work.Dispatcher) error {
+	dir := filepath.Join(constants.ConfigDir, "jobs.d")
+	if filePath := c.String("config"); filePath != "" {
+		dir = filepath.Join(filepath.Dir(filePath), "jobs.d")
+	}
+	jobs, err := config.ReadJobConfigDir(dir)
+	if err != nil {
+		return cli.Exit(fmt.Errorf("cannot read jobs: %w", err), 1)
+	}
+	dispatcher.StartJobs(jobs)
+	return nil
+}
+
+// setupExecWorkers reads the exec worker definitions in the "workers.d"
+// directory next to the config file and registers them with the dispatcher.
+func setupExecWorkers(c *cli.Context, dispatcher *work.Dispatcher) error {
+	dir := filepath.Join(constants.ConfigDir, "workers.d")
+	if filePath := c.String("config"); filePath != "" {
+		dir = filepath.Join(filepath.Dir(filePath), "workers.d")
+	}
+	workers, err := config.ReadExecWorkerConfigDir(dir)
+	if err != nil {
+		return cli.Exit(fmt.Errorf("cannot read exec workers: %w", err), 1)
+	}
+	dispatcher.StartExecWorkers(workers)
+	return nil
+}
+
 // setupTLS tries to set up new TLS config and HTTP client
 func setupTLS() (*http.Client, *tls.Config, error) {
 	tlsConfig, err := config.DefaultConfig.CreateTLSConfig()
@@ -404,6 +490,13 @@ func mainAction(c *cli.Context) error {
 	}
 	log.Infof("starting %v version %v", c.App.Name, c.App.Version)
 
+	// Read per-worker configuration, the directive routing table and the
+	// content source allow-list from the config file
+	err = setupConfigTables(c)
+	if err != nil {
+		return err
+	}
+
 	// When no protocol is defined in the config, detect it from the first server entry
 	if config.DefaultConfig.Protocol == "none" && len(config.DefaultConfig.Server) > 0 {
 		log.Warnf(
@@ -441,6 +534,28 @@ func mainAction(c *cli.Context) error {
 	// Create Dispatcher service
 	dispatcher := work.NewDispatcher(httpClient)
 
+	// Open the schedule of messages to be dispatched later, so that messages
+	// scheduled before a restart are still dispatched.
+	err = setupSchedule(dispatcher)
+	if err != nil {
+		return err
+	}
+
+	// Download detached content streamed to workers into the cache directory
+	// rather than a possibly memory-backed temporary directory.
+	err = setupContentDir(dispatcher)
+	if err != nil {
+		return err
+	}
+
+	// Register the exec workers defined in the workers.d directory next to the
+	// config file before connecting, so that they are included in the first
+	// connection-status message.
+	err = setupExecWorkers(c, dispatcher)
+	if err != nil {
+		return err
+	}
+
 	// Create Transporter service (it could be HTTP or MQTT according to configuration)
 	// This also starts probably the most important goroutine waiting for messages
 	// from the Transporter
@@ -449,6 +564,13 @@ func mainAction(c *cli.Context) error {
 		return cli.Exit(fmt.Errorf("cannot setup client: %w", err), 1)
 	}
 
+	// Schedule the jobs defined in the jobs.d directory next to the config
+	// file.
+	err = setupJobs(c, dispatcher)
+	if err != nil {
+		return err
+	}
+
 	// Create a message journal if a journal path is provided
 	// or if it is enabled in the config.
 	// This message journal contains a persistent database that
@@ -641,6 +763,21 @@ func main() {
 			Name:  config.FlagNameMessageJournal,
 			Usage: "Record worker events and messages in the database `FILE`",
 		}),
+		altsrc.NewDurationFlag(&cli.DurationFlag{
+			Name:   config.FlagNameProgressEventInterval,
+			Usage:  "Send worker progress to the server at most once every `DURATION` per message (0 disables)",
+			Value:  0,
+			Hidden: true,
+		}),
+		altsrc.NewDurationFlag(&cli.DurationFlag{
+			Name:  config.FlagNameTransmitTimeout,
+			Usage: "Wait up to `DURATION` for data transmitted by a worker to be sent to the server",
+			Value: 1 * time.Second,
+		}),
+		altsrc.NewInt64Flag(&cli.Int64Flag{
+			Name:  config.FlagNameContentCacheSize,
+			Usage: "Keep up to `BYTES` of detached message content in the cache directory (0 disables)",
+		}),
 	}
 
 	app.EnableBashCompletion = true
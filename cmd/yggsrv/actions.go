@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/redhatinsights/yggdrasil"
+	"github.com/redhatinsights/yggdrasil/internal/broker"
+	"github.com/subpop/go-log"
+	"github.com/urfave/cli/v2"
+)
+
+func serveAction(c *cli.Context) error {
+	level, err := log.ParseLevel(c.String("log-level"))
+	if err != nil {
+		return cli.Exit(fmt.Errorf("cannot parse log level: %w", err), 1)
+	}
+	log.SetLevel(level)
+	log.SetPrefix(fmt.Sprintf("[%v] ", c.App.Name))
+
+	var tlsConfig *tls.Config
+	if c.String("cert-file") != "" || c.String("key-file") != "" {
+		cert, err := tls.LoadX509KeyPair(c.String("cert-file"), c.String("key-file"))
+		if err != nil {
+			return cli.Exit(fmt.Errorf("cannot load key pair: %w", err), 1)
+		}
+		tlsConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+	}
+
+	listen := func(addr string) (net.Listener, error) {
+		if tlsConfig != nil {
+			return tls.Listen("tcp", addr, tlsConfig)
+		}
+		return net.Listen("tcp", addr)
+	}
+
+	mqttListener, err := listen(c.String("mqtt-listen"))
+	if err != nil {
+		return cli.Exit(fmt.Errorf("cannot listen for MQTT connections: %w", err), 1)
+	}
+	httpListener, err := listen(c.String("http-listen"))
+	if err != nil {
+		return cli.Exit(fmt.Errorf("cannot listen for HTTP connections: %w", err), 1)
+	}
+
+	b := broker.New()
+	srv := &http.Server{
+		Handler:           newServer(c.String("path-prefix"), b),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errs := make(chan error, 2)
+	go func() {
+		log.Infof("accepting MQTT connections on %v", mqttListener.Addr())
+		errs <- b.Serve(mqttListener)
+	}()
+	go func() {
+		log.Infof("accepting HTTP connections on %v", httpListener.Addr())
+		if err := srv.Serve(httpListener); !errors.Is(err, http.ErrServerClosed) {
+			errs <- err
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
+
+	select {
+	case <-quit:
+	case err := <-errs:
+		log.Errorf("cannot serve: %v", err)
+	}
+
+	log.Info("shutting down")
+	if err := b.Close(); err != nil {
+		log.Errorf("cannot close broker: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Errorf("cannot shut down HTTP server: %v", err)
+	}
+
+	return nil
+}
+
+func clientsAction(c *cli.Context) error {
+	var clients []ClientInfo
+	if err := callAPI(c, http.MethodGet, "clients", nil, &clients); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	switch c.String("format") {
+	case "json":
+		data, err := json.Marshal(clients)
+		if err != nil {
+			return cli.Exit(fmt.Errorf("cannot marshal clients: %w", err), 1)
+		}
+		fmt.Println(string(data))
+	case "table":
+		writer := tabwriter.NewWriter(os.Stdout, 4, 4, 2, ' ', 0)
+		_, _ = fmt.Fprint(writer, "CLIENT ID\tTRANSPORT\tSTATE\tVERSION\tLAST SEEN\n")
+		for _, client := range clients {
+			var state, version string
+			if client.Status != nil {
+				state = string(client.Status.Content.State)
+				version = client.Status.Content.ClientVersion
+			}
+			_, _ = fmt.Fprintf(
+				writer,
+				"%v\t%v\t%v\t%v\t%v\n",
+				client.ClientID,
+				client.Transport,
+				state,
+				version,
+				client.LastSeen.Format(time.RFC3339),
+			)
+		}
+		if err := writer.Flush(); err != nil {
+			return cli.Exit(fmt.Errorf("cannot flush clients: %w", err), 1)
+		}
+	default:
+		return cli.Exit(fmt.Errorf("unknown format type: %v", c.String("format")), 1)
+	}
+
+	return nil
+}
+
+func messagesAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.Exit("error: CLIENT_ID is required", 1)
+	}
+
+	var messages []Record
+	if err := callAPI(c, http.MethodGet, "clients/"+c.Args().First()+"/messages", nil, &messages); err != nil {
+		return cli.Exit(err, 1)
+	}
+	for _, message := range messages {
+		data, err := json.Marshal(message)
+		if err != nil {
+			return cli.Exit(fmt.Errorf("cannot marshal message: %w", err), 1)
+		}
+		fmt.Println(string(data))
+	}
+
+	return nil
+}
+
+func sendAction(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return cli.Exit("error: CLIENT_ID and FILE are required", 1)
+	}
+
+	var metadata map[string]string
+	if err := json.Unmarshal([]byte(c.String("metadata")), &metadata); err != nil {
+		return cli.Exit(fmt.Errorf("cannot unmarshal metadata: %w", err), 1)
+	}
+	content, err := readFile(c.Args().Get(1))
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+	if !json.Valid(content) {
+		return cli.Exit("error: content is not valid JSON", 1)
+	}
+
+	req := DataRequest{
+		Directive: c.String("directive"),
+		Metadata:  metadata,
+		Content:   content,
+	}
+	var resp MessageResponse
+	if err := callAPI(c, http.MethodPost, "clients/"+c.Args().First()+"/data", req, &resp); err != nil {
+		return cli.Exit(err, 1)
+	}
+	fmt.Println(resp.MessageID)
+
+	return nil
+}
+
+func commandAction(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return cli.Exit("error: CLIENT_ID and COMMAND are required", 1)
+	}
+
+	command := yggdrasil.Command{
+		Command:   yggdrasil.CommandName(c.Args().Get(1)),
+		Arguments: map[string]string{},
+	}
+	for _, argument := range c.StringSlice("argument") {
+		key, value, ok := strings.Cut(argument, "=")
+		if !ok {
+			return cli.Exit(fmt.Errorf("cannot parse argument %v: expected KEY=VALUE", argument), 1)
+		}
+		command.Arguments[key] = value
+	}
+
+	var resp MessageResponse
+	if err := callAPI(c, http.MethodPost, "clients/"+c.Args().First()+"/control", command, &resp); err != nil {
+		return cli.Exit(err, 1)
+	}
+	fmt.Println(resp.MessageID)
+
+	return nil
+}
+
+func contentAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.Exit("error: FILE is required", 1)
+	}
+
+	content, err := readFile(c.Args().First())
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	var resp ContentResponse
+	if err := callAPI(c, http.MethodPost, "content", json.RawMessage(content), &resp); err != nil {
+		return cli.Exit(err, 1)
+	}
+	fmt.Println(resp.URL)
+
+	return nil
+}
+
+func uploadsAction(c *cli.Context) error {
+	if c.NArg() == 1 {
+		var data json.RawMessage
+		if err := callAPI(c, http.MethodGet, "uploads/"+c.Args().First(), nil, &data); err != nil {
+			return cli.Exit(err, 1)
+		}
+		_, _ = os.Stdout.Write(data)
+		return nil
+	}
+
+	var uploads []Upload
+	if err := callAPI(c, http.MethodGet, "uploads", nil, &uploads); err != nil {
+		return cli.Exit(err, 1)
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 4, 4, 2, ' ', 0)
+	_, _ = fmt.Fprint(writer, "ID\tPATH\tSIZE\tRECEIVED\n")
+	for _, upload := range uploads {
+		_, _ = fmt.Fprintf(
+			writer,
+			"%v\t%v\t%v\t%v\n",
+			upload.ID,
+			upload.Path,
+			upload.Size,
+			upload.Received.Format(time.RFC3339),
+		)
+	}
+	if err := writer.Flush(); err != nil {
+		return cli.Exit(fmt.Errorf("cannot flush uploads: %w", err), 1)
+	}
+
+	return nil
+}
+
+// readFile reads the file at name, or stdin if name is "-".
+func readFile(name string) ([]byte, error) {
+	var reader io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open file for reading: %w", err)
+		}
+		defer f.Close()
+		reader = f
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read data: %w", err)
+	}
+	return data, nil
+}
+
+// callAPI sends a request to the endpoint of the REST API, encoding body as
+// JSON (json.RawMessage values are sent as is), and decodes the response into
+// v. A json.RawMessage v receives the raw response body.
+func callAPI(c *cli.Context, method string, endpoint string, body interface{}, v interface{}) error {
+	var reader io.Reader
+	switch body := body.(type) {
+	case nil:
+	case json.RawMessage:
+		reader = bytes.NewReader(body)
+	default:
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("cannot marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	url := strings.TrimSuffix(c.String("api"), "/") + "/api/v1/" + endpoint
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return fmt.Errorf("cannot create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot call API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("cannot read response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		var apiError struct {
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(data, &apiError); err == nil && apiError.Error != "" {
+			return fmt.Errorf("cannot call API: %v", apiError.Error)
+		}
+		return fmt.Errorf("cannot call API: %v", resp.Status)
+	}
+
+	if raw, ok := v.(*json.RawMessage); ok {
+		*raw = data
+		return nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("cannot unmarshal response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/redhatinsights/yggdrasil"
+)
+
+// DataRequest is the body of a request to send a data message to a client.
+type DataRequest struct {
+	Directive string            `json:"directive"`
+	Metadata  map[string]string `json:"metadata"`
+	Content   json.RawMessage   `json:"content"`
+}
+
+// MessageResponse is the response to a request to send a message to a client.
+type MessageResponse struct {
+	MessageID string `json:"message_id"`
+}
+
+// ContentResponse is the response to a request to add detached content.
+type ContentResponse struct {
+	URL string `json:"url"`
+}
+
+// serveAPI handles requests to the REST API:
+//
+//	GET  clients                  list clients
+//	GET  clients/{id}/messages    list messages sent by a client
+//	POST clients/{id}/data        send a data message (DataRequest)
+//	POST clients/{id}/control     send a command (yggdrasil.Command)
+//	POST content                  add detached content, returning its URL
+//	GET  uploads                  list uploads
+//	GET  uploads/{id}             get the content of an upload
+func (s *server) serveAPI(w http.ResponseWriter, r *http.Request, path string) {
+	levels := strings.Split(path, "/")
+
+	switch {
+	case path == "clients" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, s.clientList())
+	case len(levels) == 3 && levels[0] == "clients" && levels[2] == "messages" && r.Method == http.MethodGet:
+		messages, ok := s.messages(levels[1])
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("unknown client %v", levels[1]))
+			return
+		}
+		writeJSON(w, http.StatusOK, messages)
+	case len(levels) == 3 && levels[0] == "clients" && levels[2] == "data" && r.Method == http.MethodPost:
+		var req DataRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("cannot decode request: %w", err))
+			return
+		}
+		if req.Directive == "" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("missing directive"))
+			return
+		}
+		id, err := s.sendData(levels[1], req.Directive, req.Metadata, req.Content)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, MessageResponse{MessageID: id})
+	case len(levels) == 3 && levels[0] == "clients" && levels[2] == "control" && r.Method == http.MethodPost:
+		var command yggdrasil.Command
+		if err := json.NewDecoder(r.Body).Decode(&command); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("cannot decode request: %w", err))
+			return
+		}
+		if command.Command == "" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("missing command"))
+			return
+		}
+		id, err := s.sendCommand(levels[1], command)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, MessageResponse{MessageID: id})
+	case path == "content" && r.Method == http.MethodPost:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("cannot read request: %w", err))
+			return
+		}
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		writeJSON(w, http.StatusCreated, ContentResponse{
+			URL: fmt.Sprintf("%v://%v%v", scheme, r.Host, s.addContent(data)),
+		})
+	case path == "uploads" && r.Method == http.MethodGet:
+		s.mu.Lock()
+		uploads := append([]Upload{}, s.index...)
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, uploads)
+	case len(levels) == 2 && levels[0] == "uploads" && r.Method == http.MethodGet:
+		s.mu.Lock()
+		data, ok := s.uploads[levels[1]]
+		s.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("unknown upload %v", levels[1]))
+			return
+		}
+		_, _ = w.Write(data)
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown endpoint %v %v", r.Method, path))
+	}
+}
+
+// writeError writes err to w as a JSON object with the given status code.
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
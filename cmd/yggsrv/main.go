@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/subpop/go-log"
+
+	"github.com/redhatinsights/yggdrasil/internal/constants"
+	"github.com/urfave/cli/v2"
+)
+
+func main() {
+	app := cli.NewApp()
+	app.Name = "yggsrv"
+	app.Version = constants.Version
+	app.Usage = "run a local yggdrasil server for testing"
+	app.Description = `yggsrv is a small yggdrasil server intended for end-to-end testing on a single
+host. It embeds an MQTT broker and serves the HTTP polling transport, records
+the messages clients send, serves detached content and accepts uploads. The
+remaining commands interact with a running server through its REST API.`
+
+	app.Flags = []cli.Flag{
+		&cli.BoolFlag{
+			Name:   "generate-man-page",
+			Hidden: true,
+		},
+		&cli.BoolFlag{
+			Name:   "generate-markdown",
+			Hidden: true,
+		},
+		&cli.StringFlag{
+			Name:    "api",
+			Aliases: []string{"a"},
+			Usage:   "Connect to the server REST API at `URL`",
+			Value:   "http://127.0.0.1:8080",
+			EnvVars: []string{"YGGSRV_API"},
+		},
+	}
+
+	app.Commands = []*cli.Command{
+		{
+			Name:        "serve",
+			Usage:       "Run the server",
+			Description: "The serve command runs the server until it is interrupted.",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "mqtt-listen",
+					Usage: "Accept MQTT connections on `ADDRESS`",
+					Value: "127.0.0.1:1883",
+				},
+				&cli.StringFlag{
+					Name:  "http-listen",
+					Usage: "Accept HTTP connections on `ADDRESS`",
+					Value: "127.0.0.1:8080",
+				},
+				&cli.StringFlag{
+					Name:  "path-prefix",
+					Usage: "Use `PREFIX` as the MQTT topic and HTTP path prefix",
+					Value: constants.DefaultPathPrefix,
+				},
+				&cli.PathFlag{
+					Name:  "cert-file",
+					Usage: "Use `FILE` as the server certificate, enabling TLS",
+				},
+				&cli.PathFlag{
+					Name:  "key-file",
+					Usage: "Use `FILE` as the server's private key",
+				},
+				&cli.StringFlag{
+					Name:  "log-level",
+					Usage: "Set the logging output level to `LEVEL`",
+					Value: log.LevelInfo.String(),
+				},
+			},
+			Action: serveAction,
+		},
+		{
+			Name:        "clients",
+			Usage:       "List clients that have connected to the server",
+			Description: "The clients command prints the clients that have connected to the server, along with their most recent connection status.",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "format",
+					Usage: "Print output in `FORMAT` (json or table)",
+					Value: "table",
+				},
+			},
+			Action: clientsAction,
+		},
+		{
+			Name:        "messages",
+			Usage:       "List messages sent by a client",
+			UsageText:   "yggsrv messages CLIENT_ID",
+			Description: "The messages command prints the messages the client CLIENT_ID has sent to the server, one JSON object per line.",
+			Action:      messagesAction,
+		},
+		{
+			Name:        "send",
+			Usage:       "Send a data message to a client",
+			UsageText:   "yggsrv send [command options] CLIENT_ID FILE",
+			Description: "The send command reads FILE and sends its content to the client CLIENT_ID in a data message. If FILE is -, content is read from stdin. Content must be valid JSON.",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "directive",
+					Aliases:  []string{"d"},
+					Usage:    "Set directive to `STRING`",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:    "metadata",
+					Aliases: []string{"m"},
+					Usage:   "Attach `JSON` as metadata to the message",
+					Value:   "{}",
+				},
+			},
+			Action: sendAction,
+		},
+		{
+			Name:        "command",
+			Usage:       "Send a command to a client",
+			UsageText:   "yggsrv command [command options] CLIENT_ID COMMAND",
+			Description: "The command command sends COMMAND (ping, disconnect, reconnect or cancel) to the client CLIENT_ID in a control message.",
+			Flags: []cli.Flag{
+				&cli.StringSliceFlag{
+					Name:  "argument",
+					Usage: "Set argument `KEY=VALUE` of the command",
+				},
+			},
+			Action: commandAction,
+		},
+		{
+			Name:        "content",
+			Usage:       "Serve detached content",
+			UsageText:   "yggsrv content FILE",
+			Description: "The content command reads FILE and adds it to the server's detached content, printing the URL it is served at. If FILE is -, content is read from stdin.",
+			Action:      contentAction,
+		},
+		{
+			Name:        "uploads",
+			Usage:       "List uploads",
+			UsageText:   "yggsrv uploads [ID]",
+			Description: "The uploads command prints the uploads the server has received. If ID is given, the content of that upload is printed instead.",
+			Action:      uploadsAction,
+		},
+	}
+
+	app.Action = generateManPage
+	app.EnableBashCompletion = true
+
+	if err := app.Run(os.Args); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func generateManPage(c *cli.Context) error {
+	if c.Bool("generate-man-page") || c.Bool("generate-markdown") {
+		type GenerationFunc func() (string, error)
+		var generationFunc GenerationFunc
+		if c.Bool("generate-man-page") {
+			generationFunc = c.App.ToMan
+		} else if c.Bool("generate-markdown") {
+			generationFunc = c.App.ToMarkdown
+		}
+		data, err := generationFunc()
+		if err != nil {
+			return err
+		}
+		fmt.Println(data)
+		return nil
+	}
+
+	return cli.ShowAppHelp(c)
+}
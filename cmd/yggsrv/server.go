@@ -0,0 +1,370 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redhatinsights/yggdrasil"
+	"github.com/redhatinsights/yggdrasil/internal/broker"
+	"github.com/subpop/go-log"
+)
+
+// The transports a client can connect over.
+const (
+	transportMQTT = "mqtt"
+	transportHTTP = "http"
+)
+
+// maxMessages is the number of messages kept for each client. Older messages
+// are discarded.
+const maxMessages = 1000
+
+// Record is a message sent by a client to the server.
+type Record struct {
+	Received  time.Time       `json:"received"`
+	Transport string          `json:"transport"`
+	Addr      string          `json:"addr"`
+	Message   json.RawMessage `json:"message"`
+}
+
+// ClientInfo describes a client that has connected to the server.
+type ClientInfo struct {
+	ClientID  string                      `json:"client_id"`
+	Transport string                      `json:"transport"`
+	LastSeen  time.Time                   `json:"last_seen"`
+	Status    *yggdrasil.ConnectionStatus `json:"status,omitempty"`
+}
+
+// Upload is a file uploaded to the server by a worker.
+type Upload struct {
+	ID       string            `json:"id"`
+	Path     string            `json:"path"`
+	Received time.Time         `json:"received"`
+	Metadata map[string]string `json:"metadata"`
+	Size     int               `json:"size"`
+}
+
+// client is the state the server keeps about a client.
+type client struct {
+	info     ClientInfo
+	messages []Record
+	queues   map[string][][]byte
+}
+
+// server is a small yggdrasil server. It routes messages to clients over MQTT
+// (through an embedded broker) or HTTP polling, records messages clients send,
+// serves detached content and accepts uploads.
+type server struct {
+	prefix string
+	broker *broker.Broker
+
+	mu      sync.Mutex
+	clients map[string]*client
+	content map[string][]byte
+	uploads map[string][]byte
+	index   []Upload
+}
+
+// newServer creates a server that uses prefix as the path prefix of topics and
+// URLs. Messages published by MQTT clients to the broker are recorded.
+func newServer(prefix string, b *broker.Broker) *server {
+	s := &server{
+		prefix:  prefix,
+		broker:  b,
+		clients: map[string]*client{},
+		content: map[string][]byte{},
+		uploads: map[string][]byte{},
+	}
+
+	b.Subscribe(prefix+"/+/+/out", func(msg broker.Message) {
+		levels := strings.Split(msg.Topic, "/")
+		s.receive(transportMQTT, levels[len(levels)-3], levels[len(levels)-2], msg.Payload)
+	})
+
+	return s
+}
+
+// client returns the state of the client with ID clientID, creating it if
+// necessary. The caller must hold s.mu.
+func (s *server) client(clientID string, transport string) *client {
+	c, ok := s.clients[clientID]
+	if !ok {
+		c = &client{
+			info:   ClientInfo{ClientID: clientID},
+			queues: map[string][][]byte{},
+		}
+		s.clients[clientID] = c
+	}
+	c.info.Transport = transport
+	c.info.LastSeen = time.Now()
+	return c
+}
+
+// receive records a message sent by a client to addr. Connection status
+// messages update the status of the client.
+func (s *server) receive(transport string, clientID string, addr string, data []byte) {
+	if len(data) == 0 {
+		// yggd publishes an empty message when it connects to create topics
+		// on brokers that require it.
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.client(clientID, transport)
+	c.messages = append(c.messages, Record{
+		Received:  time.Now(),
+		Transport: transport,
+		Addr:      addr,
+		Message:   json.RawMessage(data),
+	})
+	if len(c.messages) > maxMessages {
+		c.messages = c.messages[len(c.messages)-maxMessages:]
+	}
+
+	if addr != "control" {
+		return
+	}
+	var message struct {
+		Type yggdrasil.MessageType `json:"type"`
+	}
+	if err := json.Unmarshal(data, &message); err != nil {
+		log.Debugf("cannot unmarshal control message from %v: %v", clientID, err)
+		return
+	}
+	if message.Type != yggdrasil.MessageTypeConnectionStatus {
+		return
+	}
+	var status yggdrasil.ConnectionStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		log.Debugf("cannot unmarshal connection status from %v: %v", clientID, err)
+		return
+	}
+	log.Infof("client %v is %v", clientID, status.Content.State)
+	c.info.Status = &status
+}
+
+// send delivers data to a client over the channel addr, either "data" or
+// "control", using the transport the client last connected with.
+func (s *server) send(clientID string, addr string, data []byte) error {
+	s.mu.Lock()
+	c, ok := s.clients[clientID]
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("cannot send message: unknown client %v", clientID)
+	}
+	transport := c.info.Transport
+	if transport == transportHTTP {
+		c.queues[addr] = append(c.queues[addr], data)
+	}
+	s.mu.Unlock()
+
+	if transport == transportMQTT {
+		s.broker.Publish(fmt.Sprintf("%v/%v/%v/in", s.prefix, clientID, addr), data)
+	}
+
+	return nil
+}
+
+// sendData sends a data message with the given directive, metadata and
+// content to a client, returning the ID of the message.
+func (s *server) sendData(
+	clientID string,
+	directive string,
+	metadata map[string]string,
+	content json.RawMessage,
+) (string, error) {
+	msg := yggdrasil.Data{
+		Type:      yggdrasil.MessageTypeData,
+		MessageID: uuid.New().String(),
+		Version:   1,
+		Sent:      time.Now(),
+		Directive: directive,
+		Metadata:  metadata,
+		Content:   content,
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return "", fmt.Errorf("cannot marshal message: %w", err)
+	}
+
+	return msg.MessageID, s.send(clientID, "data", data)
+}
+
+// sendCommand sends a command message to a client, returning the ID of the
+// message.
+func (s *server) sendCommand(clientID string, command yggdrasil.Command) (string, error) {
+	content, err := json.Marshal(command)
+	if err != nil {
+		return "", fmt.Errorf("cannot marshal command: %w", err)
+	}
+	msg := yggdrasil.Control{
+		Type:      yggdrasil.MessageTypeCommand,
+		MessageID: uuid.New().String(),
+		Version:   1,
+		Sent:      time.Now(),
+		Content:   content,
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return "", fmt.Errorf("cannot marshal message: %w", err)
+	}
+
+	return msg.MessageID, s.send(clientID, "control", data)
+}
+
+// poll returns the next message queued for an HTTP client on channel, or nil
+// if there is none.
+func (s *server) poll(clientID string, channel string) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.client(clientID, transportHTTP)
+	queue := c.queues[channel]
+	if len(queue) == 0 {
+		return nil
+	}
+	c.queues[channel] = queue[1:]
+	return queue[0]
+}
+
+// clientList returns information about every client that has connected.
+func (s *server) clientList() []ClientInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clients := make([]ClientInfo, 0, len(s.clients))
+	for _, c := range s.clients {
+		clients = append(clients, c.info)
+	}
+	return clients
+}
+
+// messages returns the messages a client has sent, or false if the client has
+// never connected.
+func (s *server) messages(clientID string) ([]Record, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.clients[clientID]
+	if !ok {
+		return nil, false
+	}
+	return append([]Record{}, c.messages...), true
+}
+
+// addContent stores data to be served as detached content, returning the path
+// it is served at.
+func (s *server) addContent(data []byte) string {
+	id := uuid.New().String()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.content[id] = data
+	return "/content/" + id
+}
+
+// addUpload stores data uploaded to path.
+func (s *server) addUpload(path string, metadata map[string]string, data []byte) Upload {
+	upload := Upload{
+		ID:       uuid.New().String(),
+		Path:     path,
+		Received: time.Now(),
+		Metadata: metadata,
+		Size:     len(data),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.uploads[upload.ID] = data
+	s.index = append(s.index, upload)
+	return upload
+}
+
+// ServeHTTP routes requests to the transport, content, upload and API
+// handlers.
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+
+	switch {
+	case strings.HasPrefix(path, "api/v1/"):
+		s.serveAPI(w, r, strings.TrimPrefix(path, "api/v1/"))
+	case strings.HasPrefix(path, "content/") && r.Method == http.MethodGet:
+		s.mu.Lock()
+		data, ok := s.content[strings.TrimPrefix(path, "content/")]
+		s.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	case strings.HasPrefix(path, "upload/") && r.Method == http.MethodPost:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		metadata := make(map[string]string)
+		for k := range r.Header {
+			metadata[k] = r.Header.Get(k)
+		}
+		upload := s.addUpload(strings.TrimPrefix(path, "upload/"), metadata, data)
+		log.Infof("received upload %v (%v bytes)", upload.ID, upload.Size)
+		writeJSON(w, http.StatusCreated, upload)
+	case strings.HasPrefix(path, s.prefix+"/"):
+		s.serveTransport(w, r, strings.TrimPrefix(path, s.prefix+"/"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveTransport handles requests from clients using the HTTP transport: GET
+// {channel}/{clientID}/in polls for a message and POST {addr}/{clientID}/out
+// sends one.
+func (s *server) serveTransport(w http.ResponseWriter, r *http.Request, path string) {
+	levels := strings.Split(path, "/")
+	if len(levels) != 3 {
+		http.NotFound(w, r)
+		return
+	}
+	addr, clientID, direction := levels[0], levels[1], levels[2]
+
+	switch {
+	case direction == "in" && r.Method == http.MethodGet:
+		data := s.poll(clientID, addr)
+		if data == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	case direction == "out" && r.Method == http.MethodPost:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.receive(transportHTTP, clientID, addr, data)
+		w.WriteHeader(http.StatusOK)
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// writeJSON writes v to w as JSON with the given status code.
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("cannot encode response: %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/redhatinsights/yggdrasil"
+	"github.com/redhatinsights/yggdrasil/internal/broker"
+)
+
+func request(t *testing.T, method string, url string, body []byte) (int, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, data
+}
+
+func TestHTTPTransport(t *testing.T) {
+	ts := httptest.NewServer(newServer("yggdrasil", broker.New()))
+	defer ts.Close()
+
+	code, _ := request(t, http.MethodPost, ts.URL+"/api/v1/clients/c1/data", []byte(`{"directive":"echo","content":"hi"}`))
+	if code != http.StatusNotFound {
+		t.Errorf("%v != %v", code, http.StatusNotFound)
+	}
+
+	status := `{"type":"connection-status","message_id":"1","version":1,"content":{"state":"online"}}`
+	code, _ = request(t, http.MethodPost, ts.URL+"/yggdrasil/control/c1/out", []byte(status))
+	if code != http.StatusOK {
+		t.Fatalf("%v != %v", code, http.StatusOK)
+	}
+
+	_, data := request(t, http.MethodGet, ts.URL+"/api/v1/clients", nil)
+	var clients []ClientInfo
+	if err := json.Unmarshal(data, &clients); err != nil {
+		t.Fatal(err)
+	}
+	if len(clients) != 1 || clients[0].Status == nil {
+		t.Fatalf("unexpected clients: %v", string(data))
+	}
+	if clients[0].Transport != transportHTTP {
+		t.Errorf("%v != %v", clients[0].Transport, transportHTTP)
+	}
+	if clients[0].Status.Content.State != yggdrasil.ConnectionStateOnline {
+		t.Errorf("%v != %v", clients[0].Status.Content.State, yggdrasil.ConnectionStateOnline)
+	}
+
+	code, data = request(t, http.MethodPost, ts.URL+"/api/v1/clients/c1/data", []byte(`{"directive":"echo","content":"hi"}`))
+	if code != http.StatusAccepted {
+		t.Fatalf("%v != %v: %v", code, http.StatusAccepted, string(data))
+	}
+	var resp MessageResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	code, data = request(t, http.MethodGet, ts.URL+"/yggdrasil/data/c1/in", nil)
+	if code != http.StatusOK {
+		t.Fatalf("%v != %v", code, http.StatusOK)
+	}
+	var got yggdrasil.Data
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.MessageID != resp.MessageID || got.Directive != "echo" || string(got.Content) != `"hi"` {
+		t.Errorf("unexpected data message: %v", string(data))
+	}
+
+	code, _ = request(t, http.MethodGet, ts.URL+"/yggdrasil/data/c1/in", nil)
+	if code != http.StatusNoContent {
+		t.Errorf("%v != %v", code, http.StatusNoContent)
+	}
+}
+
+func TestMQTTTransport(t *testing.T) {
+	b := broker.New()
+	ts := httptest.NewServer(newServer("yggdrasil", b))
+	defer ts.Close()
+
+	published := make(chan broker.Message, 1)
+	b.Subscribe("yggdrasil/+/control/in", func(m broker.Message) {
+		published <- m
+	})
+
+	// Simulate the client publishing its connection status.
+	b.Publish("yggdrasil/c1/control/out", []byte(`{"type":"connection-status","content":{"state":"online"}}`))
+
+	code, data := request(t, http.MethodPost, ts.URL+"/api/v1/clients/c1/control", []byte(`{"command":"ping"}`))
+	if code != http.StatusAccepted {
+		t.Fatalf("%v != %v: %v", code, http.StatusAccepted, string(data))
+	}
+
+	m := <-published
+	if m.Topic != "yggdrasil/c1/control/in" {
+		t.Errorf("%v != %v", m.Topic, "yggdrasil/c1/control/in")
+	}
+	var control yggdrasil.Control
+	if err := json.Unmarshal(m.Payload, &control); err != nil {
+		t.Fatal(err)
+	}
+	var command yggdrasil.Command
+	if err := json.Unmarshal(control.Content, &command); err != nil {
+		t.Fatal(err)
+	}
+	if control.Type != yggdrasil.MessageTypeCommand || command.Command != yggdrasil.CommandNamePing {
+		t.Errorf("unexpected control message: %v", string(m.Payload))
+	}
+
+	_, data = request(t, http.MethodGet, ts.URL+"/api/v1/clients/c1/messages", nil)
+	var messages []Record
+	if err := json.Unmarshal(data, &messages); err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 || messages[0].Addr != "control" || messages[0].Transport != transportMQTT {
+		t.Errorf("unexpected messages: %v", string(data))
+	}
+}
+
+func TestContentAndUploads(t *testing.T) {
+	ts := httptest.NewServer(newServer("yggdrasil", broker.New()))
+	defer ts.Close()
+
+	_, data := request(t, http.MethodPost, ts.URL+"/api/v1/content", []byte("payload"))
+	var content ContentResponse
+	if err := json.Unmarshal(data, &content); err != nil {
+		t.Fatal(err)
+	}
+	_, got := request(t, http.MethodGet, content.URL, nil)
+	if !cmp.Equal(got, []byte("payload")) {
+		t.Errorf("%v != %v", string(got), "payload")
+	}
+
+	code, data := request(t, http.MethodPost, ts.URL+"/upload/reports/1", []byte("report"))
+	if code != http.StatusCreated {
+		t.Fatalf("%v != %v", code, http.StatusCreated)
+	}
+	var upload Upload
+	if err := json.Unmarshal(data, &upload); err != nil {
+		t.Fatal(err)
+	}
+	if upload.Path != "reports/1" || upload.Size != len("report") {
+		t.Errorf("unexpected upload: %v", string(data))
+	}
+	_, got = request(t, http.MethodGet, ts.URL+"/api/v1/uploads/"+upload.ID, nil)
+	if !cmp.Equal(got, []byte("report")) {
+		t.Errorf("%v != %v", string(got), "report")
+	}
+}
@@ -0,0 +1,475 @@
+// Package broker implements a minimal MQTT 3.1.1 broker. It supports the subset
+// of the protocol used by yggd: QoS 0 and 1 delivery (QoS 2 is acknowledged and
+// delivered at QoS 1), topic wildcards, will messages and session takeover.
+// Sessions are not persisted and retained messages are not stored.
+//
+// It is embedded in yggsrv, the reference server, and used by the transport
+// conformance suite. yggd does not use it.
+package broker
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/subpop/go-log"
+)
+
+// Message is a message published to a topic.
+type Message struct {
+	Topic   string
+	Payload []byte
+}
+
+// Broker is an MQTT broker.
+type Broker struct {
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	clients   map[string]*client
+	observers map[*observer]struct{}
+	closed    bool
+}
+
+// observer is a function subscribed to messages by a caller of the broker
+// rather than a network client.
+type observer struct {
+	filter string
+	f      func(Message)
+}
+
+// New creates a broker.
+func New() *Broker {
+	return &Broker{
+		listeners: map[net.Listener]struct{}{},
+		clients:   map[string]*client{},
+		observers: map[*observer]struct{}{},
+	}
+}
+
+// Serve accepts connections on l and serves MQTT clients over them. It blocks
+// until l is closed, returning nil if the broker was closed.
+func (b *Broker) Serve(l net.Listener) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return fmt.Errorf("broker closed")
+	}
+	b.listeners[l] = struct{}{}
+	b.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			b.mu.Lock()
+			closed := b.closed
+			delete(b.listeners, l)
+			b.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return fmt.Errorf("cannot accept connection: %w", err)
+		}
+		go b.serveConn(conn)
+	}
+}
+
+// Close closes all listeners and client connections.
+func (b *Broker) Close() error {
+	b.mu.Lock()
+	b.closed = true
+	listeners := b.listeners
+	b.listeners = map[net.Listener]struct{}{}
+	b.mu.Unlock()
+
+	var errs []error
+	for l := range listeners {
+		if err := l.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	b.DisconnectClients()
+
+	return errors.Join(errs...)
+}
+
+// Publish publishes payload to topic, delivering it to all subscribers.
+func (b *Broker) Publish(topic string, payload []byte) {
+	b.route(Message{Topic: topic, Payload: payload}, 1)
+}
+
+// Subscribe calls f for every message published to a topic matching filter,
+// until the returned function is called. f is called synchronously and must not
+// block.
+func (b *Broker) Subscribe(filter string, f func(Message)) func() {
+	o := &observer{filter: filter, f: f}
+
+	b.mu.Lock()
+	b.observers[o] = struct{}{}
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		delete(b.observers, o)
+		b.mu.Unlock()
+	}
+}
+
+// Clients returns the IDs of the connected clients.
+func (b *Broker) Clients() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ids := make([]string, 0, len(b.clients))
+	for id := range b.clients {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Subscribed returns true if a connected client is subscribed to topic.
+func (b *Broker) Subscribed(topic string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, c := range b.clients {
+		if _, ok := c.subscribed(topic); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// DisconnectClients closes the network connection of every client without
+// sending a DISCONNECT packet, as though the network failed. Will messages of
+// the clients are published.
+func (b *Broker) DisconnectClients() {
+	b.mu.Lock()
+	clients := make([]*client, 0, len(b.clients))
+	for _, c := range b.clients {
+		clients = append(clients, c)
+	}
+	b.mu.Unlock()
+
+	for _, c := range clients {
+		c.close()
+	}
+}
+
+// route delivers msg to all network clients and observers subscribed to its
+// topic.
+func (b *Broker) route(msg Message, qos byte) {
+	b.mu.Lock()
+	var observers []*observer
+	for o := range b.observers {
+		if matchTopic(o.filter, msg.Topic) {
+			observers = append(observers, o)
+		}
+	}
+	var clients []*client
+	var grants []byte
+	for _, c := range b.clients {
+		if granted, ok := c.subscribed(msg.Topic); ok {
+			clients = append(clients, c)
+			grants = append(grants, min(granted, qos))
+		}
+	}
+	b.mu.Unlock()
+
+	for _, o := range observers {
+		o.f(msg)
+	}
+	for i, c := range clients {
+		if err := c.publish(msg, grants[i]); err != nil {
+			log.Debugf("cannot deliver message to client %v: %v", c.id, err)
+		}
+	}
+}
+
+// serveConn reads and handles packets from a client connection until the
+// connection is closed.
+func (b *Broker) serveConn(conn net.Conn) {
+	r := bufio.NewReader(conn)
+
+	p, err := readPacket(r)
+	if err != nil || p.typ != packetConnect {
+		_ = conn.Close()
+		return
+	}
+
+	c, err := b.connect(conn, p)
+	if err != nil {
+		log.Debugf("cannot accept connection from %v: %v", conn.RemoteAddr(), err)
+		_ = conn.Close()
+		return
+	}
+
+	graceful := false
+	for {
+		p, err := readPacket(r)
+		if err != nil {
+			break
+		}
+		if p.typ == packetDisconnect {
+			graceful = true
+			break
+		}
+		if err := b.handle(c, p); err != nil {
+			log.Debugf("cannot handle packet from client %v: %v", c.id, err)
+			break
+		}
+	}
+
+	c.close()
+
+	b.mu.Lock()
+	if b.clients[c.id] == c {
+		delete(b.clients, c.id)
+	}
+	b.mu.Unlock()
+
+	if !graceful && c.will != nil {
+		b.route(*c.will, c.willQoS)
+	}
+}
+
+// connect handles a CONNECT packet, registering the client and acknowledging
+// the connection. An existing client with the same ID is disconnected.
+func (b *Broker) connect(conn net.Conn, p *packet) (*client, error) {
+	d := decoder{buf: p.body}
+	protocol := d.string()
+	level := d.byte()
+	flags := d.byte()
+	_ = d.uint16() // keep alive
+	id := d.string()
+	c := &client{
+		id:   id,
+		conn: conn,
+		subs: map[string]byte{},
+	}
+	if flags&0x04 != 0 {
+		c.will = &Message{Topic: d.string(), Payload: d.bytes()}
+		c.willQoS = (flags >> 3) & 0x03
+	}
+	if flags&0x80 != 0 {
+		_ = d.string() // user name
+	}
+	if flags&0x40 != 0 {
+		_ = d.bytes() // password
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("cannot decode CONNECT packet: %w", d.err)
+	}
+
+	if (protocol != "MQTT" || level != 4) && (protocol != "MQIsdp" || level != 3) {
+		// Refuse the connection: unacceptable protocol version.
+		_ = c.write(&packet{typ: packetConnAck, body: []byte{0, 1}})
+		return nil, fmt.Errorf("unsupported protocol %v level %v", protocol, level)
+	}
+	if id == "" {
+		// Refuse the connection: identifier rejected.
+		_ = c.write(&packet{typ: packetConnAck, body: []byte{0, 2}})
+		return nil, fmt.Errorf("empty client ID")
+	}
+
+	b.mu.Lock()
+	existing := b.clients[id]
+	b.clients[id] = c
+	b.mu.Unlock()
+
+	if existing != nil {
+		log.Debugf("client %v reconnected; disconnecting existing connection", id)
+		existing.close()
+	}
+
+	if err := c.write(&packet{typ: packetConnAck, body: []byte{0, 0}}); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// handle handles a packet received from c.
+func (b *Broker) handle(c *client, p *packet) error {
+	d := decoder{buf: p.body}
+
+	switch p.typ {
+	case packetPublish:
+		qos := (p.flags >> 1) & 0x03
+		topic := d.string()
+		var id uint16
+		if qos > 0 {
+			id = d.uint16()
+		}
+		payload := d.rest()
+		if d.err != nil {
+			return fmt.Errorf("cannot decode PUBLISH packet: %w", d.err)
+		}
+
+		switch qos {
+		case 1:
+			if err := c.write(&packet{typ: packetPubAck, body: binary.BigEndian.AppendUint16(nil, id)}); err != nil {
+				return err
+			}
+		case 2:
+			if err := c.write(&packet{typ: packetPubRec, body: binary.BigEndian.AppendUint16(nil, id)}); err != nil {
+				return err
+			}
+		}
+
+		b.route(Message{Topic: topic, Payload: payload}, min(qos, 1))
+	case packetPubRel:
+		id := d.uint16()
+		if d.err != nil {
+			return fmt.Errorf("cannot decode PUBREL packet: %w", d.err)
+		}
+		return c.write(&packet{typ: packetPubComp, body: binary.BigEndian.AppendUint16(nil, id)})
+	case packetPubAck, packetPubRec, packetPubComp:
+		// Messages are delivered at most once; acknowledgements are ignored.
+	case packetSubscribe:
+		id := d.uint16()
+		body := binary.BigEndian.AppendUint16(nil, id)
+		for d.err == nil && len(d.buf) > 0 {
+			filter := d.string()
+			qos := min(d.byte()&0x03, 1)
+			if d.err != nil {
+				break
+			}
+			c.subscribe(filter, qos)
+			body = append(body, qos)
+		}
+		if d.err != nil {
+			return fmt.Errorf("cannot decode SUBSCRIBE packet: %w", d.err)
+		}
+		return c.write(&packet{typ: packetSubAck, body: body})
+	case packetUnsubscribe:
+		id := d.uint16()
+		for d.err == nil && len(d.buf) > 0 {
+			c.unsubscribe(d.string())
+		}
+		if d.err != nil {
+			return fmt.Errorf("cannot decode UNSUBSCRIBE packet: %w", d.err)
+		}
+		return c.write(&packet{typ: packetUnsubAck, body: binary.BigEndian.AppendUint16(nil, id)})
+	case packetPingReq:
+		return c.write(&packet{typ: packetPingResp})
+	default:
+		return fmt.Errorf("unexpected packet type %v", p.typ)
+	}
+
+	return nil
+}
+
+// client is a network client connected to the broker.
+type client struct {
+	id      string
+	conn    net.Conn
+	will    *Message
+	willQoS byte
+
+	mu       sync.Mutex
+	subs     map[string]byte
+	packetID uint16
+	closed   bool
+}
+
+// write sends p to the client.
+func (c *client) write(p *packet) error {
+	buf, err := p.encode()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return net.ErrClosed
+	}
+	_, err = c.conn.Write(buf)
+	return err
+}
+
+// publish sends msg to the client at the given QoS.
+func (c *client) publish(msg Message, qos byte) error {
+	body := appendString(nil, msg.Topic)
+	if qos > 0 {
+		c.mu.Lock()
+		c.packetID++
+		if c.packetID == 0 {
+			c.packetID++
+		}
+		id := c.packetID
+		c.mu.Unlock()
+		body = binary.BigEndian.AppendUint16(body, id)
+	}
+	body = append(body, msg.Payload...)
+
+	return c.write(&packet{typ: packetPublish, flags: qos << 1, body: body})
+}
+
+func (c *client) subscribe(filter string, qos byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.subs[filter] = qos
+}
+
+func (c *client) unsubscribe(filter string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.subs, filter)
+}
+
+// subscribed returns the highest QoS granted to the client for a subscription
+// matching topic, and whether the client has such a subscription.
+func (c *client) subscribed(topic string) (byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var qos byte
+	var found bool
+	for filter, granted := range c.subs {
+		if matchTopic(filter, topic) {
+			found = true
+			qos = max(qos, granted)
+		}
+	}
+	return qos, found
+}
+
+// close closes the client's network connection.
+func (c *client) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+	c.closed = true
+	_ = c.conn.Close()
+}
+
+// matchTopic returns true if topic matches filter. Filters may contain the
+// single-level wildcard "+" and the multi-level wildcard "#".
+func matchTopic(filter string, topic string) bool {
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+		if i >= len(topicLevels) {
+			return false
+		}
+		if level != "+" && level != topicLevels[i] {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
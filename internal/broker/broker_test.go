@@ -0,0 +1,152 @@
+package broker
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		description string
+		filter      string
+		topic       string
+		want        bool
+	}{
+		{
+			description: "exact match",
+			filter:      "a/b/c",
+			topic:       "a/b/c",
+			want:        true,
+		},
+		{
+			description: "different topic",
+			filter:      "a/b/c",
+			topic:       "a/b/d",
+			want:        false,
+		},
+		{
+			description: "single-level wildcard",
+			filter:      "a/+/c",
+			topic:       "a/b/c",
+			want:        true,
+		},
+		{
+			description: "single-level wildcard does not match multiple levels",
+			filter:      "a/+",
+			topic:       "a/b/c",
+			want:        false,
+		},
+		{
+			description: "multi-level wildcard",
+			filter:      "a/#",
+			topic:       "a/b/c",
+			want:        true,
+		},
+		{
+			description: "multi-level wildcard matches parent level",
+			filter:      "a/#",
+			topic:       "a",
+			want:        true,
+		},
+		{
+			description: "filter longer than topic",
+			filter:      "a/b/c",
+			topic:       "a/b",
+			want:        false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			got := matchTopic(test.filter, test.topic)
+
+			if got != test.want {
+				t.Errorf("%v != %v", got, test.want)
+			}
+		})
+	}
+}
+
+func startBroker(t *testing.T) (*Broker, string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := New()
+	go func() {
+		_ = b.Serve(l)
+	}()
+	t.Cleanup(func() {
+		_ = b.Close()
+	})
+	return b, "tcp://" + l.Addr().String()
+}
+
+func connectClient(t *testing.T, url string, opts *mqtt.ClientOptions) mqtt.Client {
+	opts.AddBroker(url)
+	opts.SetAutoReconnect(false)
+	c := mqtt.NewClient(opts)
+	token := c.Connect()
+	if !token.WaitTimeout(5 * time.Second) {
+		t.Fatal("timed out connecting to broker")
+	}
+	if err := token.Error(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Disconnect(0) })
+	return c
+}
+
+func TestPublish(t *testing.T) {
+	b, url := startBroker(t)
+
+	received := make(chan Message, 1)
+	c := connectClient(t, url, mqtt.NewClientOptions().SetClientID("sub"))
+	token := c.Subscribe("a/+/in", 1, func(c mqtt.Client, m mqtt.Message) {
+		received <- Message{Topic: m.Topic(), Payload: m.Payload()}
+	})
+	if !token.WaitTimeout(5 * time.Second) {
+		t.Fatal("timed out subscribing")
+	}
+
+	b.Publish("a/b/in", []byte("hello"))
+
+	select {
+	case got := <-received:
+		want := Message{Topic: "a/b/in", Payload: []byte("hello")}
+		if !cmp.Equal(got, want) {
+			t.Errorf("%v", cmp.Diff(got, want))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+}
+
+func TestWill(t *testing.T) {
+	b, url := startBroker(t)
+
+	received := make(chan Message, 1)
+	b.Subscribe("will/#", func(m Message) {
+		received <- m
+	})
+
+	opts := mqtt.NewClientOptions().SetClientID("client")
+	opts.SetBinaryWill("will/client", []byte("offline"), 1, false)
+	connectClient(t, url, opts)
+
+	b.DisconnectClients()
+
+	select {
+	case got := <-received:
+		want := Message{Topic: "will/client", Payload: []byte("offline")}
+		if !cmp.Equal(got, want) {
+			t.Errorf("%v", cmp.Diff(got, want))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for will message")
+	}
+}
@@ -0,0 +1,163 @@
+package broker
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// packetType is the type of an MQTT control packet.
+type packetType byte
+
+const (
+	packetConnect     packetType = 1
+	packetConnAck     packetType = 2
+	packetPublish     packetType = 3
+	packetPubAck      packetType = 4
+	packetPubRec      packetType = 5
+	packetPubRel      packetType = 6
+	packetPubComp     packetType = 7
+	packetSubscribe   packetType = 8
+	packetSubAck      packetType = 9
+	packetUnsubscribe packetType = 10
+	packetUnsubAck    packetType = 11
+	packetPingReq     packetType = 12
+	packetPingResp    packetType = 13
+	packetDisconnect  packetType = 14
+)
+
+// maxRemainingLength is the largest remaining length that can be encoded in
+// the fixed header of an MQTT control packet.
+const maxRemainingLength = 268435455
+
+// packet is an MQTT control packet.
+type packet struct {
+	typ   packetType
+	flags byte
+	body  []byte
+}
+
+// readPacket reads an MQTT control packet from r.
+func readPacket(r *bufio.Reader) (*packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	var length int
+	for shift := 0; ; shift += 7 {
+		if shift > 21 {
+			return nil, fmt.Errorf("malformed remaining length")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		length |= int(b&0x7f) << shift
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+
+	return &packet{
+		typ:   packetType(header >> 4),
+		flags: header & 0x0f,
+		body:  body,
+	}, nil
+}
+
+// encode returns the wire representation of p.
+func (p *packet) encode() ([]byte, error) {
+	length := len(p.body)
+	if length > maxRemainingLength {
+		return nil, fmt.Errorf("packet too large: %v bytes", length)
+	}
+
+	buf := []byte{byte(p.typ)<<4 | p.flags}
+	for {
+		b := byte(length & 0x7f)
+		length >>= 7
+		if length > 0 {
+			b |= 0x80
+		}
+		buf = append(buf, b)
+		if length == 0 {
+			break
+		}
+	}
+	return append(buf, p.body...), nil
+}
+
+// errShortPacket is returned when a packet body ends before all of its fields
+// have been read.
+var errShortPacket = errors.New("packet too short")
+
+// decoder reads fields from the body of an MQTT control packet.
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.buf) < 1 {
+		d.err = errShortPacket
+		return 0
+	}
+	b := d.buf[0]
+	d.buf = d.buf[1:]
+	return b
+}
+
+func (d *decoder) uint16() uint16 {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.buf) < 2 {
+		d.err = errShortPacket
+		return 0
+	}
+	v := binary.BigEndian.Uint16(d.buf)
+	d.buf = d.buf[2:]
+	return v
+}
+
+func (d *decoder) bytes() []byte {
+	n := int(d.uint16())
+	if d.err != nil {
+		return nil
+	}
+	if len(d.buf) < n {
+		d.err = errShortPacket
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes())
+}
+
+// rest returns the remaining bytes of the packet body.
+func (d *decoder) rest() []byte {
+	b := d.buf
+	d.buf = nil
+	return b
+}
+
+// appendString appends s to buf, prefixed by its length.
+func appendString(buf []byte, s string) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(s)))
+	return append(buf, s...)
+}
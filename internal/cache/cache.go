@@ -0,0 +1,249 @@
+// Package cache implements an on-disk cache of detached message content,
+// limited in size by evicting the least recently used entries.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metadataSuffix is appended to the name of an entry's content file to name
+// the file its metadata is saved in.
+const metadataSuffix = ".json"
+
+// Entry describes content stored in a Cache.
+type Entry struct {
+	// Key is the value the content is stored under, such as its URL or digest.
+	Key string `json:"key"`
+
+	// ETag is the entity tag of the content, if it was downloaded from a
+	// server that provided one.
+	ETag string `json:"etag,omitempty"`
+
+	// Size is the length of the content in bytes.
+	Size int64 `json:"size"`
+}
+
+// Cache is a set of files in a directory, each storing content under a key.
+// The total size of the content is kept at or below MaxSize by removing the
+// entries that were least recently opened or stored.
+type Cache struct {
+	dir     string
+	maxSize int64
+	mu      sync.Mutex
+}
+
+// New creates a Cache storing content in dir, creating the directory if it
+// does not exist. The cache holds at most maxSize bytes of content.
+func New(dir string, maxSize int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("cannot create directory '%v': %w", dir, err)
+	}
+	return &Cache{
+		dir:     dir,
+		maxSize: maxSize,
+	}, nil
+}
+
+// Open opens the content stored under key, returning it with its entry and
+// marking it as recently used. If no content is stored under key, the returned
+// error wraps os.ErrNotExist. The caller must close the returned file.
+func (c *Cache) Open(key string) (*os.File, Entry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name := c.path(key)
+	entry, err := readEntry(name + metadataSuffix)
+	if err != nil {
+		return nil, Entry{}, err
+	}
+	if entry.Key != key {
+		return nil, Entry{}, fmt.Errorf("cannot open cache entry '%v': %w", key, os.ErrNotExist)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, Entry{}, fmt.Errorf("cannot open cache entry '%v': %w", key, err)
+	}
+
+	now := time.Now()
+	if err := os.Chtimes(name, now, now); err != nil {
+		_ = f.Close()
+		return nil, Entry{}, fmt.Errorf("cannot update cache entry '%v': %w", key, err)
+	}
+
+	return f, entry, nil
+}
+
+// Store copies the content read from r into the cache under entry.Key,
+// replacing any content already stored under it, and evicts the least recently
+// used entries until the cache holds at most its maximum size. Content larger
+// than the maximum size of the cache is not stored.
+func (c *Cache) Store(entry Entry, r io.Reader) error {
+	if entry.Size > c.maxSize {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name := c.path(entry.Key)
+	tmp, err := os.CreateTemp(c.dir, ".tmp-")
+	if err != nil {
+		return fmt.Errorf("cannot create temporary file: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	n, err := io.Copy(tmp, r)
+	if err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("cannot write cache entry '%v': %w", entry.Key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("cannot write cache entry '%v': %w", entry.Key, err)
+	}
+	entry.Size = n
+
+	metadata, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("cannot marshal cache entry '%v': %w", entry.Key, err)
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return fmt.Errorf("cannot write cache entry '%v': %w", entry.Key, err)
+	}
+	if err := os.WriteFile(name+metadataSuffix, metadata, 0600); err != nil {
+		_ = os.Remove(name)
+		return fmt.Errorf("cannot write cache entry '%v': %w", entry.Key, err)
+	}
+
+	return c.evict()
+}
+
+// Remove removes the content stored under key, if any.
+func (c *Cache) Remove(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return removeEntry(c.path(key))
+}
+
+// Size returns the total size of the content stored in the cache.
+func (c *Cache) Size() (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	files, err := c.files()
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	for _, file := range files {
+		size += file.size
+	}
+	return size, nil
+}
+
+// file is a content file in the cache directory.
+type file struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+// files returns the content files in the cache directory, least recently used
+// first.
+func (c *Cache) files() ([]file, error) {
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read directory '%v': %w", c.dir, err)
+	}
+
+	var files []file
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if !dirEntry.Type().IsRegular() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, metadataSuffix) {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("cannot stat file '%v': %w", name, err)
+		}
+		files = append(files, file{
+			name:    filepath.Join(c.dir, name),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	return files, nil
+}
+
+// evict removes the least recently used entries until the total size of the
+// content in the cache is at most its maximum size.
+func (c *Cache) evict() error {
+	files, err := c.files()
+	if err != nil {
+		return err
+	}
+	var size int64
+	for _, file := range files {
+		size += file.size
+	}
+	for _, file := range files {
+		if size <= c.maxSize {
+			break
+		}
+		if err := removeEntry(file.name); err != nil {
+			return err
+		}
+		size -= file.size
+	}
+	return nil
+}
+
+// path returns the path of the file content stored under key is saved in.
+func (c *Cache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
+
+// readEntry reads the entry saved in the metadata file at path.
+func readEntry(path string) (Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Entry{}, fmt.Errorf("cannot read cache entry: %w", err)
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return Entry{}, fmt.Errorf("cannot parse cache entry '%v': %w", path, err)
+	}
+	return entry, nil
+}
+
+// removeEntry removes the content file at name and its metadata file.
+func removeEntry(name string) error {
+	for _, path := range []string{name, name + metadataSuffix} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("cannot remove cache entry: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStoreOpen(t *testing.T) {
+	c, err := New(t.TempDir(), 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Entry{Key: "https://example.com/a", ETag: `"1"`, Size: 5}
+	if err := c.Store(Entry{Key: want.Key, ETag: want.ETag}, strings.NewReader("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	f, got, err := c.Open(want.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	content, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !cmp.Equal(got, want) {
+		t.Errorf("%v", cmp.Diff(got, want))
+	}
+	if string(content) != "hello" {
+		t.Errorf("%#v != %#v", string(content), "hello")
+	}
+
+	if _, _, err := c.Open("https://example.com/b"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist, got %v", err)
+	}
+}
+
+func TestEvict(t *testing.T) {
+	// op stores content under key, or opens the content stored under key if
+	// content is empty.
+	type op struct {
+		key     string
+		content string
+	}
+
+	tests := []struct {
+		description string
+		maxSize     int64
+		ops         []op
+		wantKeys    []string
+		wantSize    int64
+	}{
+		{
+			description: "within size",
+			maxSize:     10,
+			ops:         []op{{"a", "12345"}, {"b", "12345"}},
+			wantKeys:    []string{"a", "b"},
+			wantSize:    10,
+		},
+		{
+			description: "least recently stored",
+			maxSize:     10,
+			ops:         []op{{"a", "12345"}, {"b", "12345"}, {"c", "123"}},
+			wantKeys:    []string{"b", "c"},
+			wantSize:    8,
+		},
+		{
+			description: "least recently opened",
+			maxSize:     10,
+			ops:         []op{{"a", "12345"}, {"b", "12345"}, {"a", ""}, {"c", "123"}},
+			wantKeys:    []string{"a", "c"},
+			wantSize:    8,
+		},
+		{
+			description: "too large",
+			maxSize:     4,
+			ops:         []op{{"a", "12345"}},
+			wantKeys:    []string{},
+			wantSize:    0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			c, err := New(t.TempDir(), test.maxSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Space out modification times, as some file systems only
+			// record them with a coarse resolution.
+			modTime := time.Now().Add(-time.Hour)
+			for _, o := range test.ops {
+				if o.content == "" {
+					f, _, err := c.Open(o.key)
+					if err != nil {
+						t.Fatal(err)
+					}
+					_ = f.Close()
+				} else {
+					entry := Entry{Key: o.key, Size: int64(len(o.content))}
+					if err := c.Store(entry, strings.NewReader(o.content)); err != nil {
+						t.Fatal(err)
+					}
+				}
+				modTime = modTime.Add(time.Second)
+				if err := os.Chtimes(c.path(o.key), modTime, modTime); err != nil && !errors.Is(err, os.ErrNotExist) {
+					t.Fatal(err)
+				}
+			}
+
+			got := []string{}
+			for _, key := range []string{"a", "b", "c"} {
+				if _, err := os.Stat(c.path(key)); err == nil {
+					got = append(got, key)
+				}
+			}
+			if !cmp.Equal(got, test.wantKeys) {
+				t.Errorf("%v", cmp.Diff(got, test.wantKeys))
+			}
+
+			size, err := c.Size()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size != test.wantSize {
+				t.Errorf("%v != %v", size, test.wantSize)
+			}
+		})
+	}
+}
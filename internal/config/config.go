@@ -31,10 +31,14 @@ const (
 	FlagNameMQTTConnectTimeout       = "mqtt-connect-timeout"
 	FlagNameMQTTPublishTimeout       = "mqtt-publish-timeout"
 	FlagNameMessageJournal           = "message-journal"
+	FlagNameProgressEventInterval    = "progress-event-interval"
+	FlagNameTransmitTimeout          = "transmit-timeout"
+	FlagNameContentCacheSize         = "content-cache-size"
 )
 
 var DefaultConfig = Config{
-	PathPrefix: constants.DefaultPathPrefix,
+	PathPrefix:      constants.DefaultPathPrefix,
+	TransmitTimeout: 1 * time.Second,
 }
 
 // Config contains current configuration state for yggdrasil.
@@ -111,6 +115,32 @@ type Config struct {
 	// MessageJournal is used to enable the storage of worker events
 	// and message data in a SQLite file at the specified file path.
 	MessageJournal string
+
+	// ProgressEventInterval is the minimum duration between progress events
+	// sent to the server for a single message. A zero value disables sending
+	// progress events.
+	ProgressEventInterval time.Duration
+
+	// TransmitTimeout is the duration the dispatcher waits for the client to
+	// send data a worker transmits to the server before failing the Transmit
+	// call.
+	TransmitTimeout time.Duration
+
+	// ContentCacheSize is the maximum number of bytes of detached content
+	// kept in the content cache. A zero value disables the cache.
+	ContentCacheSize int64
+
+	// Workers is a map of worker names to configuration values that apply to
+	// each worker.
+	Workers map[string]WorkerConfig
+
+	// Routes is the table mapping the directives of incoming messages to
+	// workers.
+	Routes RouteConfig
+
+	// ContentSources is the allow-list of locations the detached content of
+	// messages may be fetched from. A nil value uses DefaultContentSources.
+	ContentSources ContentSourceConfig
 }
 
 // CreateTLSConfig creates a tls.Config object from the current configuration.
@@ -0,0 +1,21 @@
+package config
+
+// DefaultContentSources is the allow-list used when the config file has no
+// "content-sources" table: detached content may be fetched from any HTTP(S)
+// host and from data URLs, but not from local files.
+var DefaultContentSources = ContentSourceConfig{
+	"http":  {"*"},
+	"https": {"*"},
+	"data":  {"*"},
+}
+
+// ContentSourceConfig is the allow-list of locations the detached content of
+// messages may be fetched from. It maps URL schemes to the patterns of the
+// hosts content may be fetched from with the scheme, or for the "file" scheme,
+// the patterns of the paths of files content may be read from. Content sources
+// are read from the "content-sources" table of the config file:
+//
+//	[content-sources]
+//	https = ["cert.cloud.redhat.com", "*.example.com"]
+//	file = ["/run/media/*/*"]
+type ContentSourceConfig map[string][]string
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestReadContentSourceConfig(t *testing.T) {
+	tests := []struct {
+		description string
+		input       io.Reader
+		want        ContentSourceConfig
+		wantError   error
+	}{
+		{
+			description: "valid",
+			input: strings.NewReader(strings.Join([]string{
+				`log-level = "debug"`,
+				`[content-sources]`,
+				`https = ["cert.cloud.redhat.com", "*.example.com"]`,
+				`file = ["/run/media/*/*"]`,
+				`data = []`,
+			}, "\n")),
+			want: ContentSourceConfig{
+				"https": {"cert.cloud.redhat.com", "*.example.com"},
+				"file":  {"/run/media/*/*"},
+				"data":  {},
+			},
+		},
+		{
+			description: "no content sources",
+			input:       strings.NewReader(`log-level = "debug"`),
+			want:        nil,
+		},
+		{
+			description: "invalid - patterns",
+			input:       strings.NewReader(strings.Join([]string{`[content-sources]`, `https = "example.com"`}, "\n")),
+			wantError:   cmpopts.AnyError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			tables, err := readTables(test.input)
+			got := tables.ContentSources
+
+			if test.wantError != nil {
+				if !cmp.Equal(err, test.wantError, cmpopts.EquateErrors()) {
+					t.Errorf("%#v != %#v", err, test.wantError)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !cmp.Equal(got, test.want) {
+					t.Errorf("%v", cmp.Diff(got, test.want))
+				}
+			}
+		})
+	}
+}
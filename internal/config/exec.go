@@ -0,0 +1,128 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/pelletier/go-toml"
+	"github.com/subpop/go-log"
+)
+
+// ExecWorkerConfig defines a worker that yggd runs as a command for each
+// message dispatched to it, instead of a program implementing the
+// com.redhat.Yggdrasil1.Worker1 D-Bus interface. Exec workers are read from
+// TOML files in the "workers.d" directory next to the config file, one worker
+// per file:
+//
+//	# /etc/yggdrasil/workers.d/hostname.toml
+//	directive = "hostname"
+//	command = "/usr/libexec/yggdrasil/hostname.sh"
+//	args = ["--short"]
+//	user = "nobody"
+//
+//	[environment]
+//	LANG = "C.UTF-8"
+type ExecWorkerConfig struct {
+	// Directive is the directive of the messages the worker handles.
+	Directive string `toml:"directive"`
+
+	// Command is the absolute path of the program run for each message.
+	Command string `toml:"command"`
+
+	// Args are the arguments the command is run with.
+	Args []string `toml:"args"`
+
+	// User is the name of the user the command is run as. If empty, the
+	// command is run as the user running yggd.
+	User string `toml:"user"`
+
+	// Environment is the set of environment variables the command is run
+	// with, in addition to those describing the message.
+	Environment map[string]string `toml:"environment"`
+}
+
+// execDirectiveRegexp matches directives that are valid worker names.
+var execDirectiveRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// readExecWorkerConfig reads an exec worker definition from its input.
+func readExecWorkerConfig(in io.Reader) (ExecWorkerConfig, error) {
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return ExecWorkerConfig{}, fmt.Errorf("cannot read input: %w", err)
+	}
+
+	var worker ExecWorkerConfig
+	if err := toml.Unmarshal(data, &worker); err != nil {
+		return ExecWorkerConfig{}, fmt.Errorf("cannot parse TOML: %w", err)
+	}
+	if worker.Directive == "" {
+		return ExecWorkerConfig{}, fmt.Errorf("missing directive")
+	}
+	if !execDirectiveRegexp.MatchString(worker.Directive) {
+		return ExecWorkerConfig{}, fmt.Errorf("invalid directive '%v'", worker.Directive)
+	}
+	if worker.Command == "" {
+		return ExecWorkerConfig{}, fmt.Errorf("missing command")
+	}
+	if !filepath.IsAbs(worker.Command) {
+		return ExecWorkerConfig{}, fmt.Errorf("command '%v' is not an absolute path", worker.Command)
+	}
+	if worker.Environment == nil {
+		worker.Environment = map[string]string{}
+	}
+
+	return worker, nil
+}
+
+// ReadExecWorkerConfigDir reads the exec worker definitions in the TOML files
+// of dir, returning a map of directives to their definition. A missing
+// directory defines no workers. Invalid definitions, and definitions of a
+// directive already defined by another file, are logged and skipped.
+func ReadExecWorkerConfigDir(dir string) (map[string]ExecWorkerConfig, error) {
+	workers := map[string]ExecWorkerConfig{}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return workers, nil
+		}
+		return nil, fmt.Errorf("cannot read directory '%v': %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".toml" {
+			continue
+		}
+		file := filepath.Join(dir, entry.Name())
+		worker, err := readExecWorkerConfigFile(file)
+		if err != nil {
+			log.Errorf("cannot read worker '%v': %v", file, err)
+			continue
+		}
+		if _, has := workers[worker.Directive]; has {
+			log.Errorf("cannot read worker '%v': directive %v is already defined", file, worker.Directive)
+			continue
+		}
+		workers[worker.Directive] = worker
+	}
+
+	return workers, nil
+}
+
+// readExecWorkerConfigFile reads an exec worker definition from file.
+func readExecWorkerConfigFile(file string) (ExecWorkerConfig, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return ExecWorkerConfig{}, fmt.Errorf("cannot open '%v' for reading: %w", file, err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Errorf("cannot close worker file: %v", err)
+		}
+	}()
+
+	return readExecWorkerConfig(f)
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadExecWorkerConfig(t *testing.T) {
+	tests := []struct {
+		description string
+		input       io.Reader
+		want        ExecWorkerConfig
+		wantError   bool
+	}{
+		{
+			description: "valid",
+			input: strings.NewReader(strings.Join([]string{
+				`directive = "hostname"`,
+				`command = "/usr/libexec/yggdrasil/hostname.sh"`,
+				`args = ["--short"]`,
+				`user = "nobody"`,
+				`[environment]`,
+				`LANG = "C.UTF-8"`,
+			}, "\n")),
+			want: ExecWorkerConfig{
+				Directive:   "hostname",
+				Command:     "/usr/libexec/yggdrasil/hostname.sh",
+				Args:        []string{"--short"},
+				User:        "nobody",
+				Environment: map[string]string{"LANG": "C.UTF-8"},
+			},
+		},
+		{
+			description: "no environment",
+			input: strings.NewReader(strings.Join([]string{
+				`directive = "echo"`,
+				`command = "/bin/cat"`,
+			}, "\n")),
+			want: ExecWorkerConfig{
+				Directive:   "echo",
+				Command:     "/bin/cat",
+				Environment: map[string]string{},
+			},
+		},
+		{
+			description: "missing directive",
+			input:       strings.NewReader(`command = "/bin/cat"`),
+			wantError:   true,
+		},
+		{
+			description: "invalid directive",
+			input: strings.NewReader(strings.Join([]string{
+				`directive = "host-name"`,
+				`command = "/bin/hostname"`,
+			}, "\n")),
+			wantError: true,
+		},
+		{
+			description: "missing command",
+			input:       strings.NewReader(`directive = "echo"`),
+			wantError:   true,
+		},
+		{
+			description: "relative command",
+			input: strings.NewReader(strings.Join([]string{
+				`directive = "echo"`,
+				`command = "cat"`,
+			}, "\n")),
+			wantError: true,
+		},
+		{
+			description: "invalid TOML",
+			input:       strings.NewReader(`directive = `),
+			wantError:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			got, err := readExecWorkerConfig(test.input)
+
+			if test.wantError {
+				if err == nil {
+					t.Errorf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got, test.want) {
+				t.Errorf("%v", cmp.Diff(got, test.want))
+			}
+		})
+	}
+}
+
+func TestReadExecWorkerConfigDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.toml":       "directive = \"echo\"\ncommand = \"/bin/cat\"\n",
+		"b.toml":       "directive = \"echo\"\ncommand = \"/bin/echo\"\n",
+		"invalid.toml": "directive = \"echo\"\n",
+		"README":       "not a worker",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := ReadExecWorkerConfigDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]ExecWorkerConfig{
+		"echo": {Directive: "echo", Command: "/bin/cat", Environment: map[string]string{}},
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("%v", cmp.Diff(got, want))
+	}
+
+	got, err = ReadExecWorkerConfigDir(filepath.Join(dir, "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("unexpected workers: %v", got)
+	}
+}
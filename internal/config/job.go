@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+	"github.com/subpop/go-log"
+)
+
+// JobConfig defines a message dispatched to a worker on a recurring schedule.
+// Jobs are read from TOML files in the "jobs.d" directory next to the config
+// file, one job per file, named after the file:
+//
+//	# /etc/yggdrasil/jobs.d/compliance-scan.toml
+//	schedule = "0 2 * * *"
+//	directive = "compliance"
+//	content = '{"profile": "cis"}'
+//
+//	[metadata]
+//	report = "true"
+type JobConfig struct {
+	// Schedule is a cron expression defining when the job runs, in local
+	// time.
+	Schedule string `toml:"schedule"`
+
+	// Directive is the worker the job's message is dispatched to.
+	Directive string `toml:"directive"`
+
+	// Metadata is the metadata of the job's message.
+	Metadata map[string]string `toml:"metadata"`
+
+	// Content is the content of the job's message.
+	Content string `toml:"content"`
+}
+
+// readJobConfig reads a job definition from its input.
+func readJobConfig(in io.Reader) (JobConfig, error) {
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return JobConfig{}, fmt.Errorf("cannot read input: %w", err)
+	}
+
+	var job JobConfig
+	if err := toml.Unmarshal(data, &job); err != nil {
+		return JobConfig{}, fmt.Errorf("cannot parse TOML: %w", err)
+	}
+	if job.Schedule == "" {
+		return JobConfig{}, fmt.Errorf("missing schedule")
+	}
+	if job.Directive == "" {
+		return JobConfig{}, fmt.Errorf("missing directive")
+	}
+	if job.Metadata == nil {
+		job.Metadata = map[string]string{}
+	}
+
+	return job, nil
+}
+
+// ReadJobConfigDir reads the job definitions in the TOML files of dir,
+// returning a map of job names to their definition. A missing directory
+// defines no jobs. Invalid definitions are logged and skipped.
+func ReadJobConfigDir(dir string) (map[string]JobConfig, error) {
+	jobs := map[string]JobConfig{}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return jobs, nil
+		}
+		return nil, fmt.Errorf("cannot read directory '%v': %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".toml" {
+			continue
+		}
+		file := filepath.Join(dir, entry.Name())
+		job, err := readJobConfigFile(file)
+		if err != nil {
+			log.Errorf("cannot read job '%v': %v", file, err)
+			continue
+		}
+		jobs[strings.TrimSuffix(entry.Name(), ".toml")] = job
+	}
+
+	return jobs, nil
+}
+
+// readJobConfigFile reads a job definition from file.
+func readJobConfigFile(file string) (JobConfig, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return JobConfig{}, fmt.Errorf("cannot open '%v' for reading: %w", file, err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Errorf("cannot close job file: %v", err)
+		}
+	}()
+
+	return readJobConfig(f)
+}
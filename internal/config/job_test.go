@@ -0,0 +1,116 @@
+package config
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadJobConfig(t *testing.T) {
+	tests := []struct {
+		description string
+		input       io.Reader
+		want        JobConfig
+		wantError   bool
+	}{
+		{
+			description: "valid",
+			input: strings.NewReader(strings.Join([]string{
+				`schedule = "0 2 * * *"`,
+				`directive = "compliance"`,
+				`content = '{"profile": "cis"}'`,
+				`[metadata]`,
+				`report = "true"`,
+			}, "\n")),
+			want: JobConfig{
+				Schedule:  "0 2 * * *",
+				Directive: "compliance",
+				Metadata:  map[string]string{"report": "true"},
+				Content:   `{"profile": "cis"}`,
+			},
+		},
+		{
+			description: "no metadata",
+			input: strings.NewReader(strings.Join([]string{
+				`schedule = "@hourly"`,
+				`directive = "echo"`,
+			}, "\n")),
+			want: JobConfig{
+				Schedule:  "@hourly",
+				Directive: "echo",
+				Metadata:  map[string]string{},
+			},
+		},
+		{
+			description: "missing schedule",
+			input:       strings.NewReader(`directive = "echo"`),
+			wantError:   true,
+		},
+		{
+			description: "missing directive",
+			input:       strings.NewReader(`schedule = "@hourly"`),
+			wantError:   true,
+		},
+		{
+			description: "invalid TOML",
+			input:       strings.NewReader(`schedule = `),
+			wantError:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			got, err := readJobConfig(test.input)
+
+			if test.wantError {
+				if err == nil {
+					t.Errorf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got, test.want) {
+				t.Errorf("%v", cmp.Diff(got, test.want))
+			}
+		})
+	}
+}
+
+func TestReadJobConfigDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"scan.toml":    "schedule = \"@daily\"\ndirective = \"compliance\"\n",
+		"invalid.toml": "directive = \"echo\"\n",
+		"README":       "not a job",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := ReadJobConfigDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]JobConfig{
+		"scan": {Schedule: "@daily", Directive: "compliance", Metadata: map[string]string{}},
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("%v", cmp.Diff(got, want))
+	}
+
+	got, err = ReadJobConfigDir(filepath.Join(dir, "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("unexpected jobs: %v", got)
+	}
+}
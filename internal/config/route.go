@@ -0,0 +1,23 @@
+package config
+
+// RouteConfig maps the directives of incoming messages to the workers they
+// are dispatched to. Routes are read from the "routes" table of the config
+// file:
+//
+//	[routes]
+//	fallback = "catchall"
+//
+//	[routes.directives]
+//	"rhc-worker-playbook" = "rhc_worker_playbook"
+//	"package-manager@v2" = "package_manager_v2"
+type RouteConfig struct {
+	// Directives maps directives to the worker that handles them. A directive
+	// may be an alias for a renamed worker, or a versioned directive of the
+	// form "name@version". A versioned directive without a route of its own is
+	// routed like its name.
+	Directives map[string]string `toml:"directives"`
+
+	// Fallback is the worker messages are dispatched to when no worker exists
+	// for their directive. An empty value disables the fallback.
+	Fallback string `toml:"fallback"`
+}
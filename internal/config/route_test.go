@@ -0,0 +1,70 @@
+package config
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestReadRouteConfig(t *testing.T) {
+	tests := []struct {
+		description string
+		input       io.Reader
+		want        RouteConfig
+		wantError   error
+	}{
+		{
+			description: "valid",
+			input: strings.NewReader(strings.Join([]string{
+				`log-level = "debug"`,
+				`[routes]`,
+				`fallback = "catchall"`,
+				`[routes.directives]`,
+				`"rhc-worker-playbook" = "rhc_worker_playbook"`,
+				`"package-manager@v2" = "package_manager_v2"`,
+				`[workers.echo]`,
+				`timeout = "5m"`,
+			}, "\n")),
+			want: RouteConfig{
+				Directives: map[string]string{
+					"rhc-worker-playbook": "rhc_worker_playbook",
+					"package-manager@v2":  "package_manager_v2",
+				},
+				Fallback: "catchall",
+			},
+		},
+		{
+			description: "no routes",
+			input:       strings.NewReader(`log-level = "debug"`),
+			want:        RouteConfig{Directives: map[string]string{}},
+		},
+		{
+			description: "invalid - directives",
+			input:       strings.NewReader(strings.Join([]string{`[routes]`, `directives = "echo"`}, "\n")),
+			wantError:   cmpopts.AnyError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			tables, err := readTables(test.input)
+			got := tables.Routes
+
+			if test.wantError != nil {
+				if !cmp.Equal(err, test.wantError, cmpopts.EquateErrors()) {
+					t.Errorf("%#v != %#v", err, test.wantError)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !cmp.Equal(got, test.want) {
+					t.Errorf("%v", cmp.Diff(got, test.want))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pelletier/go-toml"
+	"github.com/subpop/go-log"
+)
+
+// Tables holds the tables of the config file that have no command-line flag
+// equivalent.
+type Tables struct {
+	// Workers maps worker names to their configuration.
+	Workers map[string]WorkerConfig `toml:"workers"`
+
+	// Routes is the directive routing table.
+	Routes RouteConfig `toml:"routes"`
+
+	// ContentSources is the allow-list of locations detached content may be
+	// fetched from. It is nil if the config file has no "content-sources"
+	// table.
+	ContentSources ContentSourceConfig `toml:"content-sources"`
+}
+
+// readTables reads from its input, unmarshalling the "workers", "routes" and
+// "content-sources" tables of the TOML-encoded value.
+func readTables(in io.Reader) (Tables, error) {
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return Tables{}, fmt.Errorf("cannot read input: %w", err)
+	}
+
+	var tables Tables
+	if err := toml.Unmarshal(data, &tables); err != nil {
+		return Tables{}, fmt.Errorf("cannot parse TOML: %w", err)
+	}
+
+	if tables.Workers == nil {
+		tables.Workers = map[string]WorkerConfig{}
+	}
+	if tables.Routes.Directives == nil {
+		tables.Routes.Directives = map[string]string{}
+	}
+
+	return tables, nil
+}
+
+// ReadTablesFile reads the "workers", "routes" and "content-sources" tables
+// from file.
+func ReadTablesFile(file string) (Tables, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return Tables{}, fmt.Errorf("cannot open '%v' for reading: %w", file, err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Errorf("cannot close config file: %v", err)
+		}
+	}()
+
+	tables, err := readTables(f)
+	if err != nil {
+		return Tables{}, fmt.Errorf("cannot read configuration tables: %w", err)
+	}
+	return tables, nil
+}
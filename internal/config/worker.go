@@ -0,0 +1,61 @@
+package config
+
+import "time"
+
+// WorkerConfig contains configuration values that apply to a single worker.
+// Worker configuration is read from tables named after the worker in the
+// "workers" table of the config file:
+//
+//	[workers.echo]
+//	timeout = "5m"
+//	redispatch = true
+//	local-targets = ["facts"]
+//	transmit-directives = ["echo"]
+//	transmit-hosts = ["*.example.com"]
+//	transmit-rate = 60
+//	transmit-daily-messages = 1000
+type WorkerConfig struct {
+	// Timeout is the duration a worker is given to finish working on a message
+	// when the message does not include a deadline. A zero value disables the
+	// timeout.
+	Timeout time.Duration `toml:"timeout"`
+
+	// Redispatch enables dispatching messages marked as idempotent to the
+	// worker again when the worker exits before finishing them.
+	Redispatch bool `toml:"redispatch"`
+
+	// LocalTargets is the list of directives of workers this worker may
+	// transmit messages to through a "local:" address. The value "*" allows
+	// any worker. A worker may always reply to the sender of a local message
+	// it is working on.
+	LocalTargets []string `toml:"local-targets"`
+
+	// TransmitDirectives is the list of directives this worker may transmit
+	// data to upstream. Each entry is a pattern as accepted by path.Match. If
+	// the list is not set, the worker may transmit to any directive; an empty
+	// list denies every directive.
+	TransmitDirectives []string `toml:"transmit-directives"`
+
+	// TransmitHosts is the list of hosts this worker may transmit data to
+	// through an HTTP URL. Each entry is a pattern as accepted by path.Match,
+	// such as "*.example.com". If the list is not set, the worker may transmit
+	// to any host; an empty list denies every host.
+	TransmitHosts []string `toml:"transmit-hosts"`
+
+	// TransmitRate is the number of times per minute this worker may call
+	// Transmit on average. A zero value disables the rate limit.
+	TransmitRate int `toml:"transmit-rate"`
+
+	// TransmitBurst is the number of times this worker may call Transmit in
+	// quick succession before TransmitRate applies. If zero, the burst is
+	// TransmitRate.
+	TransmitBurst int `toml:"transmit-burst"`
+
+	// TransmitDailyMessages is the number of messages this worker may
+	// transmit per day. A zero value disables the quota.
+	TransmitDailyMessages int `toml:"transmit-daily-messages"`
+
+	// TransmitDailyBytes is the number of bytes of data this worker may
+	// transmit per day. A zero value disables the quota.
+	TransmitDailyBytes int64 `toml:"transmit-daily-bytes"`
+}
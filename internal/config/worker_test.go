@@ -0,0 +1,86 @@
+package config
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestReadWorkerConfig(t *testing.T) {
+	tests := []struct {
+		description string
+		input       io.Reader
+		want        map[string]WorkerConfig
+		wantError   error
+	}{
+		{
+			description: "valid",
+			input: strings.NewReader(strings.Join([]string{
+				`log-level = "debug"`,
+				`[workers.echo]`,
+				`timeout = "5m"`,
+				`[workers.rhc_worker_playbook]`,
+				`timeout = "1h30m"`,
+				`redispatch = true`,
+				`local-targets = ["facts"]`,
+				`[workers.insights]`,
+				`transmit-directives = ["insights", "insights@*"]`,
+				`transmit-hosts = []`,
+				`transmit-rate = 60`,
+				`transmit-burst = 10`,
+				`transmit-daily-messages = 1000`,
+				`transmit-daily-bytes = 1048576`,
+			}, "\n")),
+			want: map[string]WorkerConfig{
+				"echo": {Timeout: 5 * time.Minute},
+				"rhc_worker_playbook": {
+					Timeout:      90 * time.Minute,
+					Redispatch:   true,
+					LocalTargets: []string{"facts"},
+				},
+				"insights": {
+					TransmitDirectives:    []string{"insights", "insights@*"},
+					TransmitHosts:         []string{},
+					TransmitRate:          60,
+					TransmitBurst:         10,
+					TransmitDailyMessages: 1000,
+					TransmitDailyBytes:    1048576,
+				},
+			},
+		},
+		{
+			description: "no workers",
+			input:       strings.NewReader(`log-level = "debug"`),
+			want:        map[string]WorkerConfig{},
+		},
+		{
+			description: "invalid - timeout",
+			input:       strings.NewReader(strings.Join([]string{`[workers.echo]`, `timeout = "soon"`}, "\n")),
+			wantError:   cmpopts.AnyError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			tables, err := readTables(test.input)
+			got := tables.Workers
+
+			if test.wantError != nil {
+				if !cmp.Equal(err, test.wantError, cmpopts.EquateErrors()) {
+					t.Errorf("%#v != %#v", err, test.wantError)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !cmp.Equal(got, test.want) {
+					t.Errorf("%v", cmp.Diff(got, test.want))
+				}
+			}
+		})
+	}
+}
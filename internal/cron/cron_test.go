@@ -0,0 +1,119 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		description string
+		input       string
+		wantError   bool
+	}{
+		{description: "every minute", input: "* * * * *"},
+		{description: "lists, ranges and steps", input: "0,30 9-17/2 1-7 */3 mon-fri"},
+		{description: "names", input: "0 2 * jan,jul SUN"},
+		{description: "descriptor", input: "@daily"},
+		{description: "sunday as 7", input: "0 0 * * 7"},
+		{description: "too few fields", input: "0 2 * *", wantError: true},
+		{description: "out of range", input: "60 * * * *", wantError: true},
+		{description: "invalid range", input: "0 5-1 * * *", wantError: true},
+		{description: "invalid step", input: "*/0 * * * *", wantError: true},
+		{description: "unknown name", input: "0 0 * * someday", wantError: true},
+		{description: "unknown descriptor", input: "@fortnightly", wantError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			got, err := Parse(test.input)
+
+			if test.wantError {
+				if err == nil {
+					t.Errorf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	// Saturday, January 1, 2000.
+	from := time.Date(2000, time.January, 1, 10, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		description string
+		input       string
+		want        time.Time
+	}{
+		{
+			description: "every minute",
+			input:       "* * * * *",
+			want:        time.Date(2000, time.January, 1, 10, 31, 0, 0, time.UTC),
+		},
+		{
+			description: "nightly",
+			input:       "0 2 * * *",
+			want:        time.Date(2000, time.January, 2, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			description: "later today",
+			input:       "45 10,12 * * *",
+			want:        time.Date(2000, time.January, 1, 10, 45, 0, 0, time.UTC),
+		},
+		{
+			description: "step",
+			input:       "*/20 * * * *",
+			want:        time.Date(2000, time.January, 1, 10, 40, 0, 0, time.UTC),
+		},
+		{
+			description: "weekday",
+			input:       "0 9 * * mon-fri",
+			want:        time.Date(2000, time.January, 3, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			description: "weekly on sunday as 7",
+			input:       "0 0 * * 7",
+			want:        time.Date(2000, time.January, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			description: "monthly",
+			input:       "@monthly",
+			want:        time.Date(2000, time.February, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			description: "day of month or day of week",
+			input:       "0 0 15 * mon",
+			want:        time.Date(2000, time.January, 3, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			description: "leap day",
+			input:       "0 0 29 2 *",
+			want:        time.Date(2000, time.February, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			description: "never",
+			input:       "0 0 31 2 *",
+			want:        time.Time{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			s, err := Parse(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := s.Next(from)
+
+			if !got.Equal(test.want) {
+				t.Errorf("%v != %v", got, test.want)
+			}
+		})
+	}
+}
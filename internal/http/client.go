@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/tls"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strings"
@@ -39,10 +40,20 @@ func NewHTTPClient(config *tls.Config, ua string) *Client {
 }
 
 func (c *Client) Get(url string) (*http.Response, error) {
+	return c.GetWithHeaders(url, nil)
+}
+
+// GetWithHeaders sends a GET request to url with the given additional headers,
+// such as the Range header of a request resuming a download.
+func (c *Client) GetWithHeaders(url string, headers map[string]string) (*http.Response, error) {
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create HTTP request: %w", err)
 	}
+
+	for k, v := range headers {
+		req.Header.Add(k, strings.TrimSpace(v))
+	}
 	req.Header.Add("User-Agent", c.userAgent)
 
 	log.Debugf("sending HTTP request: %v %v", req.Method, req.URL)
@@ -68,6 +79,26 @@ func (c *Client) Post(url string, headers map[string]string, body []byte) (*http
 	return c.Do(req)
 }
 
+// Send sends a request with the given method, additional headers and body to
+// url. A body that is not an in-memory buffer is sent with chunked transfer
+// encoding.
+func (c *Client) Send(method string, url string, headers map[string]string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create HTTP request: %w", err)
+	}
+
+	for k, v := range headers {
+		req.Header.Add(k, strings.TrimSpace(v))
+	}
+	req.Header.Add("User-Agent", c.userAgent)
+
+	log.Debugf("sending HTTP request: %v %v", req.Method, req.URL)
+	log.Tracef("request: %v", req)
+
+	return c.Do(req)
+}
+
 func (c *Client) Do(req *http.Request) (*http.Response, error) {
 	var resp *http.Response
 	var err error
@@ -83,7 +114,7 @@ func (c *Client) Do(req *http.Request) (*http.Response, error) {
 				attempt++
 				continue
 			}
-			return nil, fmt.Errorf("cannot do HTTP request: %v", err)
+			return nil, fmt.Errorf("cannot do HTTP request: %w", err)
 		}
 
 		switch resp.StatusCode {
@@ -0,0 +1,173 @@
+// Package schedule implements a store of data messages whose dispatch is
+// delayed until a later time.
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redhatinsights/yggdrasil"
+)
+
+// Entry is a data message scheduled to be dispatched at NotBefore. If NotAfter
+// is not zero, the message must not be dispatched after NotAfter.
+type Entry struct {
+	Data      yggdrasil.Data `json:"data"`
+	NotBefore time.Time      `json:"not_before"`
+	NotAfter  time.Time      `json:"not_after"`
+}
+
+// Store is a set of scheduled entries, keyed by message ID. A Store created
+// with Open saves its entries to a file each time they change, so that they
+// survive restarts.
+type Store struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]Entry
+}
+
+// New creates an empty Store that is not saved to a file.
+func New() *Store {
+	return &Store{
+		entries: map[string]Entry{},
+	}
+}
+
+// Open creates a Store saved to the file at path, reading any entries
+// previously saved to it.
+func Open(path string) (*Store, error) {
+	s := New()
+	s.path = path
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("cannot read schedule: %w", err)
+	}
+
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("cannot parse schedule '%v': %w", path, err)
+	}
+	for _, entry := range entries {
+		s.entries[entry.Data.MessageID] = entry
+	}
+
+	return s, nil
+}
+
+// Add adds entry to the store, replacing any entry for the same message.
+func (s *Store) Add(entry Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[entry.Data.MessageID] = entry
+	return s.save()
+}
+
+// Remove removes the entry for the message with the given ID from the store,
+// returning it. It returns false if no entry exists for the message.
+func (s *Store) Remove(messageID string) (Entry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, has := s.entries[messageID]
+	if !has {
+		return Entry{}, false, nil
+	}
+	delete(s.entries, messageID)
+	return entry, true, s.save()
+}
+
+// List returns the entries in the store, ordered by NotBefore.
+func (s *Store) List() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.list()
+}
+
+// list returns the entries in the store, ordered by NotBefore. The caller must
+// hold s.mu.
+func (s *Store) list() []Entry {
+	entries := make([]Entry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].NotBefore.Equal(entries[j].NotBefore) {
+			return entries[i].Data.MessageID < entries[j].Data.MessageID
+		}
+		return entries[i].NotBefore.Before(entries[j].NotBefore)
+	})
+	return entries
+}
+
+// save writes the entries to the store's file, if it has one. The file is
+// replaced atomically so that a crash cannot leave it partially written. The
+// caller must hold s.mu.
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(s.list())
+	if err != nil {
+		return fmt.Errorf("cannot marshal schedule: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("cannot create directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("cannot write schedule: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("cannot write schedule: %w", err)
+	}
+
+	return nil
+}
+
+// clockLayout is the layout of the wall-clock times accepted by ParseTime.
+const clockLayout = "15:04"
+
+// IsClockTime returns true if value is a wall-clock time of the form "HH:MM".
+func IsClockTime(value string) bool {
+	_, err := time.Parse(clockLayout, value)
+	return err == nil
+}
+
+// ParseTime parses value as an RFC 3339 timestamp, a duration (such as "90m")
+// relative to now, or a wall-clock time of the form "HH:MM" (such as "02:00"),
+// which is the first time at or after now that the clock in the time zone of
+// now reads that time. An empty value returns the zero time.
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if clock, err := time.Parse(clockLayout, value); err == nil {
+		t := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+		if t.Before(now) {
+			t = time.Date(now.Year(), now.Month(), now.Day()+1, clock.Hour(), clock.Minute(), 0, 0, now.Location())
+		}
+		return t, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot parse '%v' as a timestamp, duration or time of day", value)
+	}
+	return now.Add(d), nil
+}
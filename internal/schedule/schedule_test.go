@@ -0,0 +1,158 @@
+package schedule
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/redhatinsights/yggdrasil"
+)
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		description string
+		input       string
+		// now is the time the input is relative to, if not the default.
+		now       time.Time
+		want      time.Time
+		wantError bool
+	}{
+		{
+			description: "empty",
+			input:       "",
+			want:        time.Time{},
+		},
+		{
+			description: "timestamp",
+			input:       "2000-01-01T02:00:00+01:00",
+			want:        time.Date(2000, time.January, 1, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			description: "duration",
+			input:       "90m",
+			want:        time.Date(2000, time.January, 1, 1, 30, 0, 0, time.UTC),
+		},
+		{
+			description: "time of day",
+			input:       "02:00",
+			want:        time.Date(2000, time.January, 1, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			description: "time of day now",
+			input:       "00:00",
+			want:        time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			description: "time of day tomorrow",
+			input:       "23:30",
+			now:         time.Date(2000, time.January, 1, 23, 45, 0, 0, time.UTC),
+			want:        time.Date(2000, time.January, 2, 23, 30, 0, 0, time.UTC),
+		},
+		{
+			description: "time of day in local zone",
+			input:       "02:00",
+			now:         time.Date(2000, time.January, 1, 12, 0, 0, 0, time.FixedZone("UTC+2", 2*60*60)),
+			want:        time.Date(2000, time.January, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			description: "invalid time of day",
+			input:       "25:00",
+			wantError:   true,
+		},
+		{
+			description: "invalid",
+			input:       "tomorrow",
+			wantError:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			now := now
+			if !test.now.IsZero() {
+				now = test.now
+			}
+			got, err := ParseTime(test.input, now)
+
+			if test.wantError {
+				if err == nil {
+					t.Errorf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(test.want) {
+				t.Errorf("%v != %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedule.json")
+	notBefore := time.Date(2000, time.January, 1, 2, 0, 0, 0, time.UTC)
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []Entry{
+		{
+			Data:      yggdrasil.Data{MessageID: "2", Directive: "echo", Content: []byte(`{}`)},
+			NotBefore: notBefore.Add(time.Hour),
+		},
+		{
+			Data:      yggdrasil.Data{MessageID: "1", Directive: "echo", Content: []byte(`{}`)},
+			NotBefore: notBefore,
+			NotAfter:  notBefore.Add(time.Hour),
+		},
+		{
+			Data:      yggdrasil.Data{MessageID: "3", Directive: "echo", Content: []byte(`{}`)},
+			NotBefore: notBefore,
+		},
+	}
+	for _, entry := range entries {
+		if err := s.Add(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, has, err := s.Remove("3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !has || removed.Data.MessageID != "3" {
+		t.Errorf("cannot remove entry: got %v, %v", removed, has)
+	}
+	if _, has, _ := s.Remove("3"); has {
+		t.Errorf("entry removed twice")
+	}
+
+	// Reopen the store to read the entries saved to its file.
+	s, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := s.List()
+	want := []Entry{entries[1], entries[0]}
+	if !cmp.Equal(got, want) {
+		t.Errorf("%v", cmp.Diff(got, want))
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedule.json")
+	if err := os.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open(path); err == nil {
+		t.Errorf("expected error")
+	}
+}
@@ -0,0 +1,496 @@
+// Package schema validates JSON values against a JSON Schema.
+//
+// Only the subset of JSON Schema (draft 2020-12) needed to describe the content
+// and metadata of data messages is supported: the type, enum, const, properties,
+// required, additionalProperties, items, minItems, maxItems, minLength,
+// maxLength, pattern, minimum, maximum, exclusiveMinimum, exclusiveMaximum,
+// allOf, anyOf, oneOf and not keywords. Annotations, such as $schema, title and
+// description, are ignored. Schemas using any other keyword, such as $ref or
+// patternProperties, are rejected rather than validating less than their
+// authors expect.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// A Schema is a parsed JSON Schema.
+type Schema struct {
+	// always is the result of validating any value against a boolean schema.
+	always *bool
+
+	types                []string
+	enum                 []interface{}
+	constant             interface{}
+	hasConstant          bool
+	properties           map[string]*Schema
+	required             []string
+	additionalProperties *Schema
+	items                *Schema
+	minItems             *int
+	maxItems             *int
+	minLength            *int
+	maxLength            *int
+	pattern              *regexp.Regexp
+	minimum              *float64
+	maximum              *float64
+	exclusiveMinimum     *float64
+	exclusiveMaximum     *float64
+	allOf                []*Schema
+	anyOf                []*Schema
+	oneOf                []*Schema
+	not                  *Schema
+}
+
+// A ValidationError describes a value that is not valid against a schema.
+type ValidationError struct {
+	// Path is the location of the invalid value, as a JSON Pointer.
+	Path string
+
+	// Reason describes why the value is not valid.
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "/"
+	}
+	return fmt.Sprintf("value at %v %v", path, e.Reason)
+}
+
+// Parse parses the JSON Schema data.
+func Parse(data []byte) (*Schema, error) {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("cannot parse schema: %w", err)
+	}
+	s, err := compile(raw, "")
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse schema: %w", err)
+	}
+	return s, nil
+}
+
+// Validate returns a *ValidationError if the JSON value data is not valid
+// against the schema.
+func (s *Schema) Validate(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return &ValidationError{Reason: fmt.Sprintf("is not valid JSON: %v", err)}
+	}
+	return s.validate(v, "")
+}
+
+// ValidateValue returns a *ValidationError if v is not valid against the
+// schema. v is encoded as JSON before it is validated.
+func (s *Schema) ValidateValue(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("cannot marshal value: %w", err)
+	}
+	return s.Validate(data)
+}
+
+// keywords are the keywords a schema object may contain: the validation
+// keywords that are implemented, and annotations that do not affect
+// validation.
+var keywords = map[string]bool{
+	"type":                 true,
+	"enum":                 true,
+	"const":                true,
+	"properties":           true,
+	"required":             true,
+	"additionalProperties": true,
+	"items":                true,
+	"minItems":             true,
+	"maxItems":             true,
+	"minLength":            true,
+	"maxLength":            true,
+	"pattern":              true,
+	"minimum":              true,
+	"maximum":              true,
+	"exclusiveMinimum":     true,
+	"exclusiveMaximum":     true,
+	"allOf":                true,
+	"anyOf":                true,
+	"oneOf":                true,
+	"not":                  true,
+
+	"$schema":     true,
+	"$id":         true,
+	"$comment":    true,
+	"title":       true,
+	"description": true,
+	"default":     true,
+	"examples":    true,
+	"deprecated":  true,
+	"readOnly":    true,
+	"writeOnly":   true,
+}
+
+// compile compiles the decoded schema raw, found at path within the document.
+func compile(raw interface{}, path string) (*Schema, error) {
+	switch raw := raw.(type) {
+	case bool:
+		return &Schema{always: &raw}, nil
+	case map[string]interface{}:
+		return compileObject(raw, path)
+	default:
+		return nil, fmt.Errorf("%v: schema must be an object or a boolean", pointer(path))
+	}
+}
+
+func compileObject(raw map[string]interface{}, path string) (*Schema, error) {
+	var s Schema
+	var err error
+
+	// Report unsupported keywords in a stable order.
+	var unsupported []string
+	for keyword := range raw {
+		if !keywords[keyword] {
+			unsupported = append(unsupported, keyword)
+		}
+	}
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		return nil, fmt.Errorf("%v: %v is not supported", pointer(path), unsupported[0])
+	}
+
+	switch v := raw["type"].(type) {
+	case nil:
+	case string:
+		s.types = []string{v}
+	case []interface{}:
+		for _, t := range v {
+			name, ok := t.(string)
+			if !ok {
+				return nil, fmt.Errorf("%v/type: must be a string or an array of strings", path)
+			}
+			s.types = append(s.types, name)
+		}
+	default:
+		return nil, fmt.Errorf("%v/type: must be a string or an array of strings", path)
+	}
+	for _, t := range s.types {
+		switch t {
+		case "null", "boolean", "object", "array", "number", "integer", "string":
+		default:
+			return nil, fmt.Errorf("%v/type: unknown type '%v'", path, t)
+		}
+	}
+
+	if v, has := raw["enum"]; has {
+		enum, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%v/enum: must be an array", path)
+		}
+		s.enum = enum
+	}
+	s.constant, s.hasConstant = raw["const"]
+
+	if v, has := raw["properties"]; has {
+		properties, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%v/properties: must be an object", path)
+		}
+		s.properties = make(map[string]*Schema, len(properties))
+		for name, property := range properties {
+			s.properties[name], err = compile(property, path+"/properties/"+escape(name))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if v, has := raw["required"]; has {
+		required, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%v/required: must be an array of strings", path)
+		}
+		for _, r := range required {
+			name, ok := r.(string)
+			if !ok {
+				return nil, fmt.Errorf("%v/required: must be an array of strings", path)
+			}
+			s.required = append(s.required, name)
+		}
+	}
+	if v, has := raw["additionalProperties"]; has {
+		if s.additionalProperties, err = compile(v, path+"/additionalProperties"); err != nil {
+			return nil, err
+		}
+	}
+	if v, has := raw["items"]; has {
+		if s.items, err = compile(v, path+"/items"); err != nil {
+			return nil, err
+		}
+	}
+
+	for keyword, dest := range map[string]**int{
+		"minItems":  &s.minItems,
+		"maxItems":  &s.maxItems,
+		"minLength": &s.minLength,
+		"maxLength": &s.maxLength,
+	} {
+		v, has := raw[keyword]
+		if !has {
+			continue
+		}
+		n, ok := v.(float64)
+		if !ok || n < 0 || n != float64(int(n)) {
+			return nil, fmt.Errorf("%v/%v: must be a non-negative integer", path, keyword)
+		}
+		i := int(n)
+		*dest = &i
+	}
+
+	for keyword, dest := range map[string]**float64{
+		"minimum":          &s.minimum,
+		"maximum":          &s.maximum,
+		"exclusiveMinimum": &s.exclusiveMinimum,
+		"exclusiveMaximum": &s.exclusiveMaximum,
+	} {
+		v, has := raw[keyword]
+		if !has {
+			continue
+		}
+		n, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%v/%v: must be a number", path, keyword)
+		}
+		*dest = &n
+	}
+
+	if v, has := raw["pattern"]; has {
+		pattern, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%v/pattern: must be a string", path)
+		}
+		if s.pattern, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("%v/pattern: %w", path, err)
+		}
+	}
+
+	for keyword, dest := range map[string]*[]*Schema{
+		"allOf": &s.allOf,
+		"anyOf": &s.anyOf,
+		"oneOf": &s.oneOf,
+	} {
+		v, has := raw[keyword]
+		if !has {
+			continue
+		}
+		subschemas, ok := v.([]interface{})
+		if !ok || len(subschemas) == 0 {
+			return nil, fmt.Errorf("%v/%v: must be a non-empty array", path, keyword)
+		}
+		for i, subschema := range subschemas {
+			compiled, err := compile(subschema, fmt.Sprintf("%v/%v/%v", path, keyword, i))
+			if err != nil {
+				return nil, err
+			}
+			*dest = append(*dest, compiled)
+		}
+	}
+	if v, has := raw["not"]; has {
+		if s.not, err = compile(v, path+"/not"); err != nil {
+			return nil, err
+		}
+	}
+
+	return &s, nil
+}
+
+// validate validates the decoded JSON value v, found at path within the
+// validated document.
+func (s *Schema) validate(v interface{}, path string) error {
+	invalid := func(format string, a ...interface{}) error {
+		return &ValidationError{Path: path, Reason: fmt.Sprintf(format, a...)}
+	}
+
+	if s.always != nil {
+		if !*s.always {
+			return invalid("is not allowed")
+		}
+		return nil
+	}
+
+	if len(s.types) > 0 {
+		matched := false
+		for _, t := range s.types {
+			if hasType(v, t) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return invalid("must be of type %v", strings.Join(s.types, " or "))
+		}
+	}
+
+	if s.enum != nil {
+		matched := false
+		for _, e := range s.enum {
+			if reflect.DeepEqual(v, e) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return invalid("must be one of %v", encode(s.enum))
+		}
+	}
+	if s.hasConstant && !reflect.DeepEqual(v, s.constant) {
+		return invalid("must be %v", encode(s.constant))
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, name := range s.required {
+			if _, has := v[name]; !has {
+				return invalid("is missing required property '%v'", name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, has := s.properties[name]
+			if !has {
+				property = s.additionalProperties
+			}
+			if property == nil {
+				continue
+			}
+			if err := property.validate(v[name], path+"/"+escape(name)); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if s.minItems != nil && len(v) < *s.minItems {
+			return invalid("must have at least %v items", *s.minItems)
+		}
+		if s.maxItems != nil && len(v) > *s.maxItems {
+			return invalid("must have at most %v items", *s.maxItems)
+		}
+		if s.items != nil {
+			for i, item := range v {
+				if err := s.items.validate(item, fmt.Sprintf("%v/%v", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.minLength != nil && length < *s.minLength {
+			return invalid("must be at least %v characters long", *s.minLength)
+		}
+		if s.maxLength != nil && length > *s.maxLength {
+			return invalid("must be at most %v characters long", *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			return invalid("must match pattern '%v'", s.pattern)
+		}
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			return invalid("must be greater than or equal to %v", *s.minimum)
+		}
+		if s.maximum != nil && v > *s.maximum {
+			return invalid("must be less than or equal to %v", *s.maximum)
+		}
+		if s.exclusiveMinimum != nil && v <= *s.exclusiveMinimum {
+			return invalid("must be greater than %v", *s.exclusiveMinimum)
+		}
+		if s.exclusiveMaximum != nil && v >= *s.exclusiveMaximum {
+			return invalid("must be less than %v", *s.exclusiveMaximum)
+		}
+	}
+
+	for _, subschema := range s.allOf {
+		if err := subschema.validate(v, path); err != nil {
+			return err
+		}
+	}
+	if s.anyOf != nil {
+		matched := false
+		for _, subschema := range s.anyOf {
+			if subschema.validate(v, path) == nil {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return invalid("must be valid against at least one schema of anyOf")
+		}
+	}
+	if s.oneOf != nil {
+		matched := 0
+		for _, subschema := range s.oneOf {
+			if subschema.validate(v, path) == nil {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return invalid("must be valid against exactly one schema of oneOf")
+		}
+	}
+	if s.not != nil && s.not.validate(v, path) == nil {
+		return invalid("must not be valid against the schema of not")
+	}
+
+	return nil
+}
+
+// hasType returns true if the decoded JSON value v is of the JSON Schema type
+// t.
+func hasType(v interface{}, t string) bool {
+	switch v := v.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case map[string]interface{}:
+		return t == "object"
+	case []interface{}:
+		return t == "array"
+	case float64:
+		return t == "number" || (t == "integer" && v == float64(int64(v)))
+	case string:
+		return t == "string"
+	}
+	return false
+}
+
+// escape escapes name for use as a JSON Pointer reference token.
+func escape(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
+
+// pointer returns path as a JSON Pointer to the root of the document if it is
+// empty.
+func pointer(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// encode returns the JSON encoding of v for use in error messages.
+func encode(v interface{}) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	return strings.TrimSpace(buf.String())
+}
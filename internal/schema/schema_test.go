@@ -0,0 +1,270 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		description string
+		input       string
+		wantError   bool
+	}{
+		{
+			description: "empty",
+			input:       `{}`,
+		},
+		{
+			description: "boolean",
+			input:       `false`,
+		},
+		{
+			description: "nested",
+			input:       `{"type":"object","properties":{"a":{"type":"array","items":{"type":"string","pattern":"^a"}}}}`,
+		},
+		{
+			description: "not JSON",
+			input:       `{`,
+			wantError:   true,
+		},
+		{
+			description: "not a schema",
+			input:       `"object"`,
+			wantError:   true,
+		},
+		{
+			description: "unknown type",
+			input:       `{"type":"map"}`,
+			wantError:   true,
+		},
+		{
+			description: "invalid pattern",
+			input:       `{"properties":{"a":{"pattern":"("}}}`,
+			wantError:   true,
+		},
+		{
+			description: "negative length",
+			input:       `{"minLength":-1}`,
+			wantError:   true,
+		},
+		{
+			description: "reference",
+			input:       `{"$ref":"#/$defs/a"}`,
+			wantError:   true,
+		},
+		{
+			description: "annotations",
+			input:       `{"$schema":"https://json-schema.org/draft/2020-12/schema","title":"a","description":"b","default":{},"examples":[{}]}`,
+		},
+		{
+			description: "combinators",
+			input:       `{"allOf":[{"type":"object"}],"oneOf":[{"required":["a"]},{"required":["b"]}],"anyOf":[true],"not":false}`,
+		},
+		{
+			description: "patternProperties",
+			input:       `{"patternProperties":{"^a":{"type":"string"}}}`,
+			wantError:   true,
+		},
+		{
+			description: "minProperties",
+			input:       `{"minProperties":1}`,
+			wantError:   true,
+		},
+		{
+			description: "format",
+			input:       `{"type":"string","format":"date-time"}`,
+			wantError:   true,
+		},
+		{
+			description: "dependentRequired",
+			input:       `{"dependentRequired":{"a":["b"]}}`,
+			wantError:   true,
+		},
+		{
+			description: "definitions",
+			input:       `{"$defs":{"a":{"type":"string"}}}`,
+			wantError:   true,
+		},
+		{
+			description: "nested unsupported keyword",
+			input:       `{"properties":{"a":{"items":{"uniqueItems":true}}}}`,
+			wantError:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			_, err := Parse([]byte(test.input))
+			if test.wantError {
+				if err == nil {
+					t.Errorf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		description string
+		schema      string
+		input       string
+		want        *ValidationError
+	}{
+		{
+			description: "empty schema",
+			schema:      `{}`,
+			input:       `{"a":1}`,
+		},
+		{
+			description: "false schema",
+			schema:      `false`,
+			input:       `1`,
+			want:        &ValidationError{Reason: "is not allowed"},
+		},
+		{
+			description: "type",
+			schema:      `{"type":"object"}`,
+			input:       `"a"`,
+			want:        &ValidationError{Reason: "must be of type object"},
+		},
+		{
+			description: "types",
+			schema:      `{"type":["string","null"]}`,
+			input:       `null`,
+		},
+		{
+			description: "integer",
+			schema:      `{"type":"integer"}`,
+			input:       `1.5`,
+			want:        &ValidationError{Reason: "must be of type integer"},
+		},
+		{
+			description: "enum",
+			schema:      `{"enum":["a","b"]}`,
+			input:       `"c"`,
+			want:        &ValidationError{Reason: `must be one of ["a","b"]`},
+		},
+		{
+			description: "const",
+			schema:      `{"const":{"a":1}}`,
+			input:       `{"a":1}`,
+		},
+		{
+			description: "required",
+			schema:      `{"required":["name"]}`,
+			input:       `{}`,
+			want:        &ValidationError{Reason: "is missing required property 'name'"},
+		},
+		{
+			description: "property",
+			schema:      `{"properties":{"a/b":{"type":"string"}}}`,
+			input:       `{"a/b":1}`,
+			want:        &ValidationError{Path: "/a~1b", Reason: "must be of type string"},
+		},
+		{
+			description: "additional properties",
+			schema:      `{"properties":{"a":{}},"additionalProperties":false}`,
+			input:       `{"a":1,"b":2}`,
+			want:        &ValidationError{Path: "/b", Reason: "is not allowed"},
+		},
+		{
+			description: "items",
+			schema:      `{"items":{"type":"number","minimum":0}}`,
+			input:       `[1,-1]`,
+			want:        &ValidationError{Path: "/1", Reason: "must be greater than or equal to 0"},
+		},
+		{
+			description: "max items",
+			schema:      `{"maxItems":1}`,
+			input:       `[1,2]`,
+			want:        &ValidationError{Reason: "must have at most 1 items"},
+		},
+		{
+			description: "length",
+			schema:      `{"minLength":2}`,
+			input:       `"é"`,
+			want:        &ValidationError{Reason: "must be at least 2 characters long"},
+		},
+		{
+			description: "pattern",
+			schema:      `{"pattern":"^[a-z]+$"}`,
+			input:       `"abc1"`,
+			want:        &ValidationError{Reason: "must match pattern '^[a-z]+$'"},
+		},
+		{
+			description: "exclusive maximum",
+			schema:      `{"exclusiveMaximum":10}`,
+			input:       `10`,
+			want:        &ValidationError{Reason: "must be less than 10"},
+		},
+		{
+			description: "keywords of other types",
+			schema:      `{"minLength":5,"minimum":5}`,
+			input:       `[]`,
+		},
+		{
+			description: "any of",
+			schema:      `{"anyOf":[{"type":"string"},{"type":"number"}]}`,
+			input:       `true`,
+			want:        &ValidationError{Reason: "must be valid against at least one schema of anyOf"},
+		},
+		{
+			description: "one of",
+			schema:      `{"oneOf":[{"type":"number"},{"type":"integer"}]}`,
+			input:       `1`,
+			want:        &ValidationError{Reason: "must be valid against exactly one schema of oneOf"},
+		},
+		{
+			description: "not",
+			schema:      `{"not":{"type":"null"}}`,
+			input:       `null`,
+			want:        &ValidationError{Reason: "must not be valid against the schema of not"},
+		},
+		{
+			description: "not JSON",
+			schema:      `{}`,
+			input:       `{`,
+			want:        &ValidationError{Reason: "is not valid JSON: unexpected end of JSON input"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			s, err := Parse([]byte(test.schema))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got *ValidationError
+			if err := s.Validate([]byte(test.input)); err != nil {
+				if !errors.As(err, &got) {
+					t.Fatalf("expected ValidationError, got %v", err)
+				}
+			}
+			if !cmp.Equal(got, test.want) {
+				t.Errorf("%v", cmp.Diff(got, test.want))
+			}
+		})
+	}
+}
+
+func TestValidateValue(t *testing.T) {
+	s, err := Parse([]byte(`{"additionalProperties":{"type":"string","maxLength":3}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ValidateValue(map[string]string{"a": "abc"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := s.ValidateValue(map[string]string{"a": "abcd"}); err == nil {
+		t.Errorf("expected error")
+	}
+}
@@ -41,6 +41,23 @@ func (m *RWMutexMap[T]) Get(k string) (T, bool) {
 	return t, has
 }
 
+// GetOrSet locks the map, retrieving the value for k, or setting the key k to
+// the value t if k is not present. A second return value indicates whether the
+// key was present in the map.
+func (m *RWMutexMap[T]) GetOrSet(k string, t T) (T, bool) {
+	m.init()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if v, has := m.mp[k]; has {
+		return v, true
+	}
+	m.mp[k] = t
+
+	return t, false
+}
+
 // Del sets a read-write lock on the map and deletes the value for k from it.
 func (m *RWMutexMap[T]) Del(k string) {
 	m.init()
@@ -51,6 +68,20 @@ func (m *RWMutexMap[T]) Del(k string) {
 	delete(m.mp, k)
 }
 
+// Pop locks the map, retrieving and deleting the value for k. A second return
+// value indicates whether the key was present in the map.
+func (m *RWMutexMap[T]) Pop(k string) (T, bool) {
+	m.init()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, has := m.mp[k]
+	delete(m.mp, k)
+
+	return t, has
+}
+
 // Visit read-locks the map and calls the function f for each member.
 func (m *RWMutexMap[T]) Visit(f func(k string, v T)) {
 	m.init()
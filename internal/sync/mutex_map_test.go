@@ -85,6 +85,98 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func TestGetOrSet(t *testing.T) {
+	tests := []struct {
+		description string
+		input       struct {
+			m *RWMutexMap[string]
+			k string
+			t string
+		}
+		want struct {
+			val string
+			has bool
+			m   *RWMutexMap[string]
+		}
+	}{
+		{
+			description: "present",
+			input: struct {
+				m *RWMutexMap[string]
+				k string
+				t string
+			}{
+				m: &RWMutexMap[string]{
+					mp: map[string]string{
+						"key": "val",
+					},
+				},
+				k: "key",
+				t: "other",
+			},
+			want: struct {
+				val string
+				has bool
+				m   *RWMutexMap[string]
+			}{
+				val: "val",
+				has: true,
+				m: &RWMutexMap[string]{
+					mp: map[string]string{
+						"key": "val",
+					},
+				},
+			},
+		},
+		{
+			description: "absent",
+			input: struct {
+				m *RWMutexMap[string]
+				k string
+				t string
+			}{
+				m: &RWMutexMap[string]{},
+				k: "key",
+				t: "val",
+			},
+			want: struct {
+				val string
+				has bool
+				m   *RWMutexMap[string]
+			}{
+				val: "val",
+				has: false,
+				m: &RWMutexMap[string]{
+					mp: map[string]string{
+						"key": "val",
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			got, has := test.input.m.GetOrSet(test.input.k, test.input.t)
+
+			if !cmp.Equal(got, test.want.val) {
+				t.Errorf("%v != %v", got, test.want.val)
+			}
+			if !cmp.Equal(has, test.want.has) {
+				t.Errorf("%v != %v", has, test.want.has)
+			}
+			if !cmp.Equal(
+				test.input.m,
+				test.want.m,
+				cmp.AllowUnexported(RWMutexMap[string]{}),
+				cmpopts.IgnoreFields(RWMutexMap[string]{}, "mu"),
+			) {
+				t.Errorf("%v", cmp.Diff(test.input.m, test.want.m))
+			}
+		})
+	}
+}
+
 func TestDel(t *testing.T) {
 	tests := []struct {
 		description string
@@ -134,3 +226,93 @@ func TestDel(t *testing.T) {
 		})
 	}
 }
+
+func TestPop(t *testing.T) {
+	tests := []struct {
+		description string
+		input       struct {
+			m *RWMutexMap[string]
+			k string
+		}
+		want struct {
+			val string
+			has bool
+			m   *RWMutexMap[string]
+		}
+	}{
+		{
+			description: "present",
+			input: struct {
+				m *RWMutexMap[string]
+				k string
+			}{
+				m: &RWMutexMap[string]{
+					mp: map[string]string{
+						"key1": "val1",
+						"key2": "val2",
+					},
+				},
+				k: "key1",
+			},
+			want: struct {
+				val string
+				has bool
+				m   *RWMutexMap[string]
+			}{
+				val: "val1",
+				has: true,
+				m: &RWMutexMap[string]{
+					mp: map[string]string{
+						"key2": "val2",
+					},
+				},
+			},
+		},
+		{
+			description: "absent",
+			input: struct {
+				m *RWMutexMap[string]
+				k string
+			}{
+				m: &RWMutexMap[string]{
+					mp: map[string]string{
+						"key2": "val2",
+					},
+				},
+				k: "key1",
+			},
+			want: struct {
+				val string
+				has bool
+				m   *RWMutexMap[string]
+			}{
+				m: &RWMutexMap[string]{
+					mp: map[string]string{
+						"key2": "val2",
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			got, has := test.input.m.Pop(test.input.k)
+
+			if !cmp.Equal(got, test.want.val) {
+				t.Errorf("%v != %v", got, test.want.val)
+			}
+			if !cmp.Equal(has, test.want.has) {
+				t.Errorf("%v != %v", has, test.want.has)
+			}
+			if !cmp.Equal(
+				test.input.m,
+				test.want.m,
+				cmp.AllowUnexported(RWMutexMap[string]{}),
+				cmpopts.IgnoreFields(RWMutexMap[string]{}, "mu"),
+			) {
+				t.Errorf("%v", cmp.Diff(test.input.m, test.want.m))
+			}
+		})
+	}
+}
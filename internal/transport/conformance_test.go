@@ -0,0 +1,69 @@
+package transport_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/redhatinsights/yggdrasil/internal/config"
+	"github.com/redhatinsights/yggdrasil/internal/transport"
+	"github.com/redhatinsights/yggdrasil/internal/transport/transporttest"
+)
+
+const conformanceClientID = "conformance"
+
+func TestHTTPConformance(t *testing.T) {
+	transporttest.Run(t, transporttest.Target{
+		New: func(t *testing.T) (transport.Transporter, transporttest.Server) {
+			server := transporttest.NewHTTPServer(t, conformanceClientID)
+			tr, err := transport.NewHTTPTransport(
+				conformanceClientID,
+				server.Addr(),
+				server.TLSConfig(),
+				"testUA",
+				10*time.Millisecond,
+			)
+			if err != nil {
+				t.Fatalf("cannot create transport: %v", err)
+			}
+			return tr, server
+		},
+	})
+}
+
+func TestMQTTConformance(t *testing.T) {
+	defaultConfig := config.DefaultConfig
+	t.Cleanup(func() { config.DefaultConfig = defaultConfig })
+
+	config.DefaultConfig.MQTTConnectTimeout = 5 * time.Second
+	config.DefaultConfig.MQTTPublishTimeout = 5 * time.Second
+	// The suite replaces connections deliberately; reconnecting automatically
+	// would race the replacement for the client ID.
+	config.DefaultConfig.MQTTAutoReconnect = false
+
+	transporttest.Run(t, transporttest.Target{
+		New: func(t *testing.T) (transport.Transporter, transporttest.Server) {
+			server := transporttest.NewMQTTServer(t, conformanceClientID)
+			tr, err := transport.NewMQTTTransport(
+				conformanceClientID,
+				[]string{server.URL()},
+				server.TLSConfig(),
+			)
+			if err != nil {
+				t.Fatalf("cannot create transport: %v", err)
+			}
+			return tr, server
+		},
+	})
+}
+
+func TestNoopConformance(t *testing.T) {
+	transporttest.Run(t, transporttest.Target{
+		New: func(t *testing.T) (transport.Transporter, transporttest.Server) {
+			tr, err := transport.NewNoopTransport()
+			if err != nil {
+				t.Fatalf("cannot create transport: %v", err)
+			}
+			return tr, nil
+		},
+	})
+}
@@ -28,7 +28,7 @@ type HTTPResponse struct {
 // messages by sending HTTP requests to a URL.
 type HTTP struct {
 	clientID        string
-	client          *internalhttp.Client
+	client          atomic.Pointer[internalhttp.Client]
 	server          string
 	dataHandler     RxHandlerFunc
 	pollingInterval time.Duration
@@ -50,16 +50,17 @@ func NewHTTPTransport(
 	disconnected.Store(false)
 	isTls := atomic.Value{}
 	isTls.Store(tlsConfig != nil)
-	return &HTTP{
+	t := &HTTP{
 		clientID:        clientID,
-		client:          internalhttp.NewHTTPClient(tlsConfig.Clone(), userAgent),
 		pollingInterval: pollingInterval,
 		disconnected:    disconnected,
 		server:          server,
 		userAgent:       userAgent,
 		isTLS:           isTls,
 		events:          make(chan TransporterEvent),
-	}, nil
+	}
+	t.client.Store(internalhttp.NewHTTPClient(tlsConfig.Clone(), userAgent))
+	return t, nil
 }
 
 func (t *HTTP) Connect() error {
@@ -79,7 +80,7 @@ func (t *HTTP) Connect() error {
 			if t.disconnected.Load().(bool) {
 				return
 			}
-			resp, err := t.client.Get(t.getUrl("in", "control"))
+			resp, err := t.client.Load().Get(t.getUrl("in", "control"))
 			if err != nil {
 				log.Tracef("cannot get HTTP request: %v", err)
 			}
@@ -89,7 +90,8 @@ func (t *HTTP) Connect() error {
 					log.Errorf("cannot read response body: %v", err)
 					continue
 				}
-				if t.dataHandler != nil {
+				// An empty response means there is no message waiting.
+				if t.dataHandler != nil && len(data) > 0 {
 					metadata := make(map[string]interface{})
 					for k, v := range resp.Header {
 						metadata[k] = v
@@ -110,7 +112,7 @@ func (t *HTTP) Connect() error {
 			if t.disconnected.Load().(bool) {
 				return
 			}
-			resp, err := t.client.Get(t.getUrl("in", "data"))
+			resp, err := t.client.Load().Get(t.getUrl("in", "data"))
 			if err != nil {
 				log.Tracef("cannot get HTTP request: %v", err)
 			}
@@ -121,7 +123,8 @@ func (t *HTTP) Connect() error {
 					log.Errorf("cannot read response body: %v", err)
 					continue
 				}
-				if t.dataHandler != nil {
+				// An empty response means there is no message waiting.
+				if t.dataHandler != nil && len(data) > 0 {
 					metadata := make(map[string]interface{})
 					for k, v := range resp.Header {
 						metadata[k] = v
@@ -142,9 +145,11 @@ func (t *HTTP) Connect() error {
 	return nil
 }
 
-// ReloadTLSConfig creates a new HTTP client with the provided TLS config.
+// ReloadTLSConfig creates a new HTTP client with the provided TLS config. It
+// replaces the existing client, so requests already in flight complete using
+// the previous configuration.
 func (t *HTTP) ReloadTLSConfig(tlsConfig *tls.Config) error {
-	*t.client = *internalhttp.NewHTTPClient(tlsConfig, t.userAgent)
+	t.client.Store(internalhttp.NewHTTPClient(tlsConfig.Clone(), t.userAgent))
 	t.isTLS.Store(tlsConfig != nil)
 	return nil
 }
@@ -167,7 +172,7 @@ func (t *HTTP) Tx(
 	headers := map[string]string{
 		"Content-Type": "application/json",
 	}
-	resp, err := t.client.Post(url, headers, data)
+	resp, err := t.client.Load().Post(url, headers, data)
 	if err != nil && resp == nil {
 		return TxResponseErr, nil, nil, fmt.Errorf("cannot perform HTTP request: %w", err)
 	}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sync/atomic"
 	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
@@ -23,6 +24,7 @@ type MQTT struct {
 	opts           *mqtt.ClientOptions
 	events         chan TransporterEvent
 	eventHandler   EventHandlerFunc
+	disconnected   atomic.Bool
 }
 
 // NewMQTTTransport creates a transport suitable for transmitting data over a
@@ -116,11 +118,12 @@ func NewMQTTTransport(clientID string, brokers []string, tlsConfig *tls.Config)
 		Version:   1,
 		Sent:      time.Now(),
 		Content: struct {
-			CanonicalFacts map[string]interface{}       "json:\"canonical_facts\""
-			Dispatchers    map[string]map[string]string "json:\"dispatchers\""
-			State          yggdrasil.ConnectionState    "json:\"state\""
-			Tags           map[string]string            "json:\"tags,omitempty\""
-			ClientVersion  string                       "json:\"client_version,omitempty\""
+			CanonicalFacts map[string]interface{}              "json:\"canonical_facts\""
+			Dispatchers    map[string]map[string]string        "json:\"dispatchers\""
+			State          yggdrasil.ConnectionState           "json:\"state\""
+			Tags           map[string]string                   "json:\"tags,omitempty\""
+			ClientVersion  string                              "json:\"client_version,omitempty\""
+			Manifests      map[string]yggdrasil.WorkerManifest "json:\"manifests,omitempty\""
 		}{
 			State:         yggdrasil.ConnectionStateOffline,
 			ClientVersion: constants.Version,
@@ -146,6 +149,8 @@ func NewMQTTTransport(clientID string, brokers []string, tlsConfig *tls.Config)
 // Connect connects an MQTT client to the configured broker and waits for the
 // connection to open.
 func (t *MQTT) Connect() error {
+	t.disconnected.Store(false)
+
 	go func() {
 		for event := range t.events {
 			if t.eventHandler == nil {
@@ -185,6 +190,10 @@ func (t *MQTT) ReloadTLSConfig(tlsConfig *tls.Config) error {
 // Disconnect closes the connection to the MQTT broker, waiting for the
 // specified number of milliseconds for work to complete.
 func (t *MQTT) Disconnect(quiesce uint) {
+	// The client disconnects asynchronously once the quiesce period elapses;
+	// mark the transport disconnected first so that Tx fails immediately
+	// rather than blocking on a closing connection.
+	t.disconnected.Store(true)
 	t.client.Disconnect(quiesce)
 }
 
@@ -195,6 +204,9 @@ func (t *MQTT) Tx(
 	metadata map[string]string,
 	data []byte,
 ) (responseCode int, responseMetadata map[string]string, responseData []byte, err error) {
+	if t.disconnected.Load() {
+		return TxResponseErr, nil, nil, fmt.Errorf("cannot perform Tx: transport is disconnected")
+	}
 	opts := t.client.OptionsReader()
 	topic := fmt.Sprintf("%v/%v/%v/out", config.DefaultConfig.PathPrefix, opts.ClientID(), addr)
 
@@ -0,0 +1,251 @@
+package transporttest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redhatinsights/yggdrasil/internal/broker"
+	"github.com/redhatinsights/yggdrasil/internal/config"
+)
+
+// MQTTServer is an MQTT broker serving a single client over TLS.
+type MQTTServer struct {
+	broker    *broker.Broker
+	url       string
+	clientID  string
+	tlsConfig *tls.Config
+	received  chan Message
+}
+
+// NewMQTTServer starts an MQTT broker for the duration of the test, listening
+// for TLS connections on a loopback address. Messages the client with ID
+// clientID publishes are delivered on the Received channel.
+func NewMQTTServer(t testing.TB, clientID string) *MQTTServer {
+	t.Helper()
+
+	serverConfig, clientConfig := newTLSConfigs(t)
+	l, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+
+	s := &MQTTServer{
+		broker:    broker.New(),
+		url:       "ssl://" + l.Addr().String(),
+		clientID:  clientID,
+		tlsConfig: clientConfig,
+		received:  make(chan Message, 256),
+	}
+
+	filter := fmt.Sprintf("%v/%v/+/out", config.DefaultConfig.PathPrefix, clientID)
+	s.broker.Subscribe(filter, func(msg broker.Message) {
+		levels := strings.Split(msg.Topic, "/")
+		s.received <- Message{Addr: levels[len(levels)-2], Data: msg.Payload}
+	})
+
+	go func() {
+		_ = s.broker.Serve(l)
+	}()
+	t.Cleanup(func() {
+		_ = s.broker.Close()
+	})
+
+	return s
+}
+
+// URL returns the URL of the broker.
+func (s *MQTTServer) URL() string {
+	return s.url
+}
+
+// Send publishes data to the inbound topic of channel, once the client has
+// subscribed to it.
+func (s *MQTTServer) Send(channel string, data []byte) error {
+	topic := fmt.Sprintf("%v/%v/%v/in", config.DefaultConfig.PathPrefix, s.clientID, channel)
+
+	deadline := time.Now().Add(DefaultTimeout)
+	for !s.broker.Subscribed(topic) {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("cannot send message: no subscriber to topic %v", topic)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.broker.Publish(topic, data)
+
+	return nil
+}
+
+// Received implements Server.
+func (s *MQTTServer) Received() <-chan Message {
+	return s.received
+}
+
+// Drop closes the connections of all clients, as though the network failed.
+func (s *MQTTServer) Drop() error {
+	s.broker.DisconnectClients()
+	return nil
+}
+
+// TLSConfig implements Server.
+func (s *MQTTServer) TLSConfig() *tls.Config {
+	return s.tlsConfig.Clone()
+}
+
+// HTTPServer is an HTTP server serving a single client over TLS. Messages are
+// queued until the client polls for them.
+type HTTPServer struct {
+	server   *httptest.Server
+	clientID string
+	received chan Message
+
+	mu     sync.Mutex
+	queues map[string][][]byte
+}
+
+// NewHTTPServer starts an HTTP server for the duration of the test. Messages
+// the client with ID clientID posts are delivered on the Received channel.
+func NewHTTPServer(t testing.TB, clientID string) *HTTPServer {
+	t.Helper()
+
+	s := &HTTPServer{
+		clientID: clientID,
+		received: make(chan Message, 256),
+		queues:   map[string][][]byte{},
+	}
+	s.server = httptest.NewTLSServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.server.Close)
+
+	return s
+}
+
+// Addr returns the host and port of the server.
+func (s *HTTPServer) Addr() string {
+	return s.server.Listener.Addr().String()
+}
+
+// serveHTTP handles requests to /{prefix}/{channel}/{clientID}/in by
+// responding with the next message queued for channel, and requests to
+// /{prefix}/{addr}/{clientID}/out by delivering the request body on the
+// Received channel.
+func (s *HTTPServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/"+config.DefaultConfig.PathPrefix+"/")
+	levels := strings.Split(path, "/")
+	if len(levels) != 3 || levels[1] != s.clientID {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case levels[2] == "in" && r.Method == http.MethodGet:
+		s.mu.Lock()
+		var data []byte
+		if queue := s.queues[levels[0]]; len(queue) > 0 {
+			data = queue[0]
+			s.queues[levels[0]] = queue[1:]
+		}
+		s.mu.Unlock()
+
+		if data == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		_, _ = w.Write(data)
+	case levels[2] == "out" && r.Method == http.MethodPost:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.received <- Message{Addr: levels[0], Data: data}
+		w.WriteHeader(http.StatusOK)
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// Send queues data to be returned when the client next polls channel.
+func (s *HTTPServer) Send(channel string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queues[channel] = append(s.queues[channel], data)
+
+	return nil
+}
+
+// Received implements Server.
+func (s *HTTPServer) Received() <-chan Message {
+	return s.received
+}
+
+// Drop returns errors.ErrUnsupported; HTTP clients do not hold a connection
+// the server can drop.
+func (s *HTTPServer) Drop() error {
+	return errors.ErrUnsupported
+}
+
+// TLSConfig implements Server.
+func (s *HTTPServer) TLSConfig() *tls.Config {
+	return s.server.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+}
+
+// newTLSConfigs generates a self-signed certificate for the loopback addresses
+// and returns a server configuration presenting it and a client configuration
+// trusting it.
+func newTLSConfigs(t testing.TB) (*tls.Config, *tls.Config) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("cannot generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "transporttest"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("cannot create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("cannot parse certificate: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	serverConfig := &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		MinVersion:   tls.VersionTLS12,
+	}
+	clientConfig := &tls.Config{
+		RootCAs:    pool,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	return serverConfig, clientConfig
+}
@@ -0,0 +1,350 @@
+// Package transporttest provides a conformance test suite for implementations
+// of transport.Transporter, along with local stand-ins for the servers they
+// connect to.
+package transporttest
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redhatinsights/yggdrasil/internal/transport"
+)
+
+// DefaultTimeout is the duration the suite waits for an event or message
+// before failing a test.
+const DefaultTimeout = 5 * time.Second
+
+// Message is a message sent by a transporter to a server.
+type Message struct {
+	Addr string
+	Data []byte
+}
+
+// Server is the server side of a transport, used by the suite to exchange
+// messages with the transporter under test.
+type Server interface {
+	// Send delivers data to the transporter over channel, either "data" or
+	// "control".
+	Send(channel string, data []byte) error
+
+	// Received returns a channel on which messages sent by the transporter
+	// are delivered.
+	Received() <-chan Message
+
+	// Drop terminates the connection of the transporter unexpectedly. It
+	// returns errors.ErrUnsupported if the server cannot detect or terminate
+	// connections.
+	Drop() error
+
+	// TLSConfig returns a client TLS configuration the transporter can use to
+	// connect to the server.
+	TLSConfig() *tls.Config
+}
+
+// Target describes a transporter under test.
+type Target struct {
+	// New creates a transporter and the server it connects to. Server may be
+	// nil if the transporter does not connect to a server, in which case only
+	// tests that do not exchange messages are run.
+	New func(t *testing.T) (transport.Transporter, Server)
+
+	// Timeout is the duration the suite waits for an event or message. If
+	// zero, DefaultTimeout is used.
+	Timeout time.Duration
+}
+
+// Run runs the conformance suite against target. Each test creates a new
+// transporter and server.
+func Run(t *testing.T, target Target) {
+	if target.Timeout == 0 {
+		target.Timeout = DefaultTimeout
+	}
+
+	tests := []struct {
+		name string
+		f    func(t *testing.T, s *suite)
+	}{
+		{name: "Connect", f: testConnect},
+		{name: "UnexpectedDisconnect", f: testUnexpectedDisconnect},
+		{name: "Rx", f: testRx},
+		{name: "Tx", f: testTx},
+		{name: "TxAfterDisconnect", f: testTxAfterDisconnect},
+		{name: "ReloadTLSConfig", f: testReloadTLSConfig},
+		{name: "Concurrency", f: testConcurrency},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tr, server := target.New(t)
+			s := &suite{
+				transporter: tr,
+				server:      server,
+				timeout:     target.Timeout,
+				events:      make(chan transport.TransporterEvent, 16),
+				rx:          make(chan rxMessage, 64),
+			}
+			if err := tr.SetEventHandler(func(e transport.TransporterEvent) {
+				s.events <- e
+			}); err != nil {
+				t.Fatalf("cannot set event handler: %v", err)
+			}
+			if err := tr.SetRxHandler(func(addr string, metadata map[string]interface{}, data []byte) error {
+				s.rx <- rxMessage{addr: addr, data: data}
+				return nil
+			}); err != nil {
+				t.Fatalf("cannot set RxHandler: %v", err)
+			}
+			test.f(t, s)
+		})
+	}
+}
+
+// rxMessage is a message received by the RxHandler of a transporter.
+type rxMessage struct {
+	addr string
+	data []byte
+}
+
+// suite holds the transporter and server of a single test.
+type suite struct {
+	transporter transport.Transporter
+	server      Server
+	timeout     time.Duration
+	events      chan transport.TransporterEvent
+	rx          chan rxMessage
+}
+
+// requireServer skips the test if the transporter does not connect to a
+// server.
+func (s *suite) requireServer(t *testing.T) {
+	t.Helper()
+	if s.server == nil {
+		t.Skip("transporter does not connect to a server")
+	}
+}
+
+// connect connects the transporter, waiting for the Connected event if the
+// transporter connects to a server. The transporter is disconnected when the
+// test ends.
+func (s *suite) connect(t *testing.T) {
+	t.Helper()
+	if err := s.transporter.Connect(); err != nil {
+		t.Fatalf("cannot connect: %v", err)
+	}
+	t.Cleanup(func() { s.transporter.Disconnect(0) })
+	if s.server != nil {
+		s.waitEvent(t, transport.TransporterEventConnected)
+	}
+}
+
+// waitEvent waits for the transporter to emit event, ignoring other events.
+func (s *suite) waitEvent(t *testing.T, event transport.TransporterEvent) {
+	t.Helper()
+	timeout := time.After(s.timeout)
+	for {
+		select {
+		case e := <-s.events:
+			if e == event {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for event %v", event)
+		}
+	}
+}
+
+// waitRx waits for the RxHandler to receive all messages in want, in any
+// order.
+func (s *suite) waitRx(t *testing.T, want ...rxMessage) {
+	t.Helper()
+	timeout := time.After(s.timeout)
+	for len(want) > 0 {
+		select {
+		case got := <-s.rx:
+			for i, w := range want {
+				if got.addr == w.addr && bytes.Equal(got.data, w.data) {
+					want = append(want[:i], want[i+1:]...)
+					break
+				}
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %v received messages", len(want))
+		}
+	}
+}
+
+// waitReceived waits for the server to receive all messages in want, in any
+// order. Other messages are ignored.
+func (s *suite) waitReceived(t *testing.T, want ...Message) {
+	t.Helper()
+	timeout := time.After(s.timeout)
+	for len(want) > 0 {
+		select {
+		case got := <-s.server.Received():
+			for i, w := range want {
+				if got.Addr == w.Addr && bytes.Equal(got.Data, w.Data) {
+					want = append(want[:i], want[i+1:]...)
+					break
+				}
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for server to receive %v messages", len(want))
+		}
+	}
+}
+
+// tx transmits data to addr, failing the test if the transmission fails.
+func (s *suite) tx(t *testing.T, addr string, data []byte) {
+	t.Helper()
+	if err := s.checkTx(addr, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checkTx transmits data to addr, returning an error if the transmission
+// fails. It is safe to call from any goroutine.
+func (s *suite) checkTx(addr string, data []byte) error {
+	code, _, _, err := s.transporter.Tx(addr, nil, data)
+	if err != nil {
+		return fmt.Errorf("cannot transmit message: %w", err)
+	}
+	if code == transport.TxResponseErr {
+		return fmt.Errorf("unexpected response code %v", code)
+	}
+	return nil
+}
+
+func testConnect(t *testing.T, s *suite) {
+	s.connect(t)
+}
+
+func testUnexpectedDisconnect(t *testing.T, s *suite) {
+	s.requireServer(t)
+	s.connect(t)
+
+	if err := s.server.Drop(); err != nil {
+		if errors.Is(err, errors.ErrUnsupported) {
+			t.Skip("server cannot drop connections")
+		}
+		t.Fatalf("cannot drop connection: %v", err)
+	}
+	s.waitEvent(t, transport.TransporterEventDisconnected)
+}
+
+func testRx(t *testing.T, s *suite) {
+	s.requireServer(t)
+	s.connect(t)
+
+	if err := s.server.Send("data", []byte(`"data"`)); err != nil {
+		t.Fatalf("cannot send message: %v", err)
+	}
+	if err := s.server.Send("control", []byte(`"control"`)); err != nil {
+		t.Fatalf("cannot send message: %v", err)
+	}
+	s.waitRx(t,
+		rxMessage{addr: "data", data: []byte(`"data"`)},
+		rxMessage{addr: "control", data: []byte(`"control"`)},
+	)
+}
+
+func testTx(t *testing.T, s *suite) {
+	s.requireServer(t)
+	s.connect(t)
+
+	s.tx(t, "data", []byte(`"data"`))
+	s.tx(t, "control", []byte(`"control"`))
+	s.waitReceived(t,
+		Message{Addr: "data", Data: []byte(`"data"`)},
+		Message{Addr: "control", Data: []byte(`"control"`)},
+	)
+}
+
+func testTxAfterDisconnect(t *testing.T, s *suite) {
+	s.requireServer(t)
+	s.connect(t)
+
+	s.transporter.Disconnect(0)
+
+	code, _, _, err := s.transporter.Tx("data", nil, []byte(`"data"`))
+	if err == nil {
+		t.Fatal("expected error transmitting while disconnected")
+	}
+	if code != transport.TxResponseErr {
+		t.Errorf("%v != %v", code, transport.TxResponseErr)
+	}
+}
+
+func testReloadTLSConfig(t *testing.T, s *suite) {
+	if s.server == nil {
+		s.connect(t)
+		if err := s.transporter.ReloadTLSConfig(nil); err != nil {
+			t.Fatalf("cannot reload TLS config: %v", err)
+		}
+		return
+	}
+	s.connect(t)
+
+	s.tx(t, "data", []byte(`"before"`))
+	s.waitReceived(t, Message{Addr: "data", Data: []byte(`"before"`)})
+
+	if err := s.transporter.ReloadTLSConfig(s.server.TLSConfig()); err != nil {
+		t.Fatalf("cannot reload TLS config: %v", err)
+	}
+
+	s.tx(t, "data", []byte(`"after"`))
+	s.waitReceived(t, Message{Addr: "data", Data: []byte(`"after"`)})
+
+	if err := s.server.Send("data", []byte(`"after"`)); err != nil {
+		t.Fatalf("cannot send message: %v", err)
+	}
+	s.waitRx(t, rxMessage{addr: "data", data: []byte(`"after"`)})
+}
+
+func testConcurrency(t *testing.T, s *suite) {
+	const n = 16
+
+	s.connect(t)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.checkTx("data", []byte(fmt.Sprintf(`"tx-%v"`, i))); err != nil {
+				errs <- err
+			}
+		}()
+		if s.server != nil {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := s.server.Send("data", []byte(fmt.Sprintf(`"rx-%v"`, i))); err != nil {
+					errs <- err
+				}
+			}()
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if t.Failed() || s.server == nil {
+		return
+	}
+
+	var received []Message
+	var rx []rxMessage
+	for i := 0; i < n; i++ {
+		received = append(received, Message{Addr: "data", Data: []byte(fmt.Sprintf(`"tx-%v"`, i))})
+		rx = append(rx, rxMessage{addr: "data", data: []byte(fmt.Sprintf(`"rx-%v"`, i))})
+	}
+	s.waitReceived(t, received...)
+	s.waitRx(t, rx...)
+}
@@ -0,0 +1,76 @@
+package work
+
+import (
+	"github.com/godbus/dbus/v5"
+	"github.com/google/uuid"
+	"github.com/subpop/go-log"
+)
+
+// TransmitAsync implements the com.redhat.Yggdrasil1.Dispatcher1.TransmitAsync
+// method. It returns a token immediately and transmits the data in the
+// background as Transmit does. When the data has been transmitted, or
+// transmitting it failed, a TransmitComplete signal with the token and the
+// response is sent to the calling worker.
+func (d *Dispatcher) TransmitAsync(
+	sender dbus.Sender,
+	addr string,
+	messageID string,
+	responseTo string,
+	metadata map[string]string,
+	data []byte,
+) (token string, responseError *dbus.Error) {
+	token = uuid.New().String()
+
+	go func() {
+		code, responseMetadata, responseData, transmitErr := d.Transmit(sender, addr, messageID, responseTo, metadata, data)
+		if err := d.emitTransmitComplete(sender, token, code, responseMetadata, responseData, transmitErr); err != nil {
+			log.Errorf("cannot emit TransmitComplete signal for token %v: %v", token, err)
+		}
+	}()
+
+	return token, nil
+}
+
+// emitTransmitComplete sends the com.redhat.Yggdrasil1.Dispatcher1.TransmitComplete
+// signal with the response to the data transmitted for token to the bus
+// connection destination. The signal is unicast, so that the response is only
+// received by the worker that transmitted the data.
+func (d *Dispatcher) emitTransmitComplete(
+	destination dbus.Sender,
+	token string,
+	responseCode int,
+	responseMetadata map[string]string,
+	responseData []byte,
+	responseError *dbus.Error,
+) error {
+	if responseMetadata == nil {
+		responseMetadata = map[string]string{}
+	}
+	var errorName, errorMessage string
+	if responseError != nil {
+		errorName = responseError.Name
+		errorMessage = responseError.Error()
+	}
+	args := []interface{}{
+		token,
+		int32(responseCode),
+		responseMetadata,
+		responseData,
+		errorName,
+		errorMessage,
+	}
+
+	msg := &dbus.Message{
+		Type: dbus.TypeSignal,
+		Headers: map[dbus.HeaderField]dbus.Variant{
+			dbus.FieldPath:        dbus.MakeVariant(dbus.ObjectPath("/com/redhat/Yggdrasil1/Dispatcher1")),
+			dbus.FieldInterface:   dbus.MakeVariant("com.redhat.Yggdrasil1.Dispatcher1"),
+			dbus.FieldMember:      dbus.MakeVariant("TransmitComplete"),
+			dbus.FieldDestination: dbus.MakeVariant(string(destination)),
+			dbus.FieldSignature:   dbus.MakeVariant(dbus.SignatureOf(args...)),
+		},
+		Body: args,
+	}
+	call := d.conn.Send(msg, nil)
+	return call.Err
+}
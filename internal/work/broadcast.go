@@ -0,0 +1,175 @@
+package work
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redhatinsights/yggdrasil"
+	"github.com/subpop/go-log"
+)
+
+// BroadcastDirective is the directive of data messages dispatched to every
+// connected worker matching the message's selector, rather than to a single
+// worker.
+const BroadcastDirective = "*"
+
+// MetadataKeySelector is the data message metadata key that optionally
+// contains the selector of a broadcast message: a comma-separated list of
+// worker features, each either "name", matching workers with the feature, or
+// "name=value", matching workers whose feature has the value. A worker matches
+// the selector if it matches every feature in the list. A broadcast message
+// without a selector matches every worker.
+const MetadataKeySelector = "selector"
+
+// selectorTerm is a feature a worker must have to match a selector.
+type selectorTerm struct {
+	name     string
+	value    string
+	hasValue bool
+}
+
+// parseSelector parses a broadcast selector.
+func parseSelector(value string) ([]selectorTerm, error) {
+	var terms []selectorTerm
+	for _, term := range strings.Split(value, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		name, value, hasValue := strings.Cut(term, "=")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("cannot parse selector '%v': missing feature name", term)
+		}
+		terms = append(terms, selectorTerm{
+			name:     name,
+			value:    strings.TrimSpace(value),
+			hasValue: hasValue,
+		})
+	}
+	return terms, nil
+}
+
+// matchesSelector returns true if features has every feature of terms.
+func matchesSelector(features map[string]string, terms []selectorTerm) bool {
+	for _, term := range terms {
+		value, has := features[term.name]
+		if !has || (term.hasValue && value != term.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// selectWorkers returns the names of the connected workers whose features
+// match terms, in order.
+func (d *Dispatcher) selectWorkers(terms []selectorTerm) []string {
+	var workers []string
+	d.features.Visit(func(worker string, features map[string]string) {
+		if matchesSelector(features, terms) {
+			workers = append(workers, worker)
+		}
+	})
+	sort.Strings(workers)
+	return workers
+}
+
+// newBroadcastData creates the data message dispatched to worker for a
+// broadcast message. The message is in response to the broadcast message.
+func newBroadcastData(broadcast yggdrasil.Data, worker string, messageID string) yggdrasil.Data {
+	metadata := make(map[string]string, len(broadcast.Metadata))
+	for k, v := range broadcast.Metadata {
+		metadata[k] = v
+	}
+
+	return yggdrasil.Data{
+		Type:       yggdrasil.MessageTypeData,
+		MessageID:  messageID,
+		ResponseTo: broadcast.MessageID,
+		Version:    1,
+		Sent:       time.Now(),
+		Directive:  worker,
+		Metadata:   metadata,
+		Content:    broadcast.Content,
+	}
+}
+
+// runBroadcast dispatches data to every connected worker matching its
+// selector, each as a separate message, and waits for every worker to finish.
+// A single event with the result of each worker is then sent to the server in
+// response to data.
+func (d *Dispatcher) runBroadcast(data yggdrasil.Data) {
+	terms, err := parseSelector(data.Metadata[MetadataKeySelector])
+	if err != nil {
+		log.Errorf("cannot broadcast message %v: %v", data.MessageID, err)
+		d.reportDispatchError(data, newDispatchError(ErrorCodeInvalidSelector, err))
+		return
+	}
+
+	workers := d.selectWorkers(terms)
+	if len(workers) == 0 {
+		err := fmt.Errorf("no worker matches selector '%v'", data.Metadata[MetadataKeySelector])
+		log.Errorf("cannot broadcast message %v: %v", data.MessageID, err)
+		d.reportDispatchError(data, newDispatchError(ErrorCodeUnknownDirective, err))
+		return
+	}
+	log.Infof("broadcasting message %v to workers %v", data.MessageID, strings.Join(workers, ", "))
+
+	results := make([]MessageResult, len(workers))
+	var wg sync.WaitGroup
+	for i, worker := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = d.runChild(newBroadcastData(data, worker, uuid.New().String()), false)
+		}()
+	}
+	wg.Wait()
+
+	status := ResultStatusCompleted
+	for _, result := range results {
+		if result.Status != ResultStatusCompleted {
+			status = ResultStatusFailed
+		}
+	}
+
+	log.Infof("broadcast of message %v %v", data.MessageID, status)
+	event, err := newBroadcastCompleteEvent(data, status, results)
+	if err != nil {
+		log.Errorf("cannot create result of broadcast %v: %v", data.MessageID, err)
+		return
+	}
+	d.sendFinalEvent(data.MessageID, event)
+}
+
+// newBroadcastCompleteEvent creates the event reporting the results of a
+// broadcast message.
+func newBroadcastCompleteEvent(
+	broadcast yggdrasil.Data,
+	status ResultStatus,
+	results []MessageResult,
+) (yggdrasil.Event, error) {
+	data, err := json.Marshal(results)
+	if err != nil {
+		return yggdrasil.Event{}, fmt.Errorf("cannot marshal results: %w", err)
+	}
+
+	return yggdrasil.Event{
+		Type:       yggdrasil.MessageTypeEvent,
+		MessageID:  uuid.New().String(),
+		ResponseTo: broadcast.MessageID,
+		Version:    1,
+		Sent:       time.Now(),
+		Content:    string(yggdrasil.EventNameBroadcastComplete),
+		Data: map[string]string{
+			"directive": broadcast.Directive,
+			"status":    string(status),
+			"results":   string(data),
+		},
+	}, nil
+}
@@ -0,0 +1,104 @@
+package work
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		description string
+		input       string
+		want        []selectorTerm
+		wantError   bool
+	}{
+		{
+			description: "empty",
+			input:       "",
+			want:        nil,
+		},
+		{
+			description: "features",
+			input:       "cache, version=2 ,mode=",
+			want: []selectorTerm{
+				{name: "cache"},
+				{name: "version", value: "2", hasValue: true},
+				{name: "mode", value: "", hasValue: true},
+			},
+		},
+		{
+			description: "missing name",
+			input:       "cache,=true",
+			wantError:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			got, err := parseSelector(test.input)
+
+			if test.wantError {
+				if err == nil {
+					t.Errorf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got, test.want, cmp.AllowUnexported(selectorTerm{})) {
+				t.Errorf("%#v != %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSelectWorkers(t *testing.T) {
+	d := &Dispatcher{}
+	d.features.Set("echo", map[string]string{"version": "1"})
+	d.features.Set("facts", map[string]string{"version": "2", "cache": "true"})
+	d.features.Set("packages", map[string]string{"version": "2", "cache": "false"})
+
+	tests := []struct {
+		description string
+		input       string
+		want        []string
+	}{
+		{
+			description: "every worker",
+			input:       "",
+			want:        []string{"echo", "facts", "packages"},
+		},
+		{
+			description: "feature present",
+			input:       "cache",
+			want:        []string{"facts", "packages"},
+		},
+		{
+			description: "feature value",
+			input:       "version=2,cache=true",
+			want:        []string{"facts"},
+		},
+		{
+			description: "no match",
+			input:       "version=3",
+			want:        nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			terms, err := parseSelector(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := d.selectWorkers(terms)
+
+			if !cmp.Equal(got, test.want) {
+				t.Errorf("%v != %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package work
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/redhatinsights/yggdrasil"
+	"github.com/redhatinsights/yggdrasil/ipc"
+	"github.com/subpop/go-log"
+)
+
+// defaultChildTimeout is the duration a child message is given to finish if
+// neither the message nor its worker's configuration sets a deadline.
+var defaultChildTimeout = time.Hour
+
+// childDeadlineGrace is the duration runChild waits past the deadline of a
+// child message for the message to finish before giving up on it.
+var childDeadlineGrace = 10 * time.Second
+
+// ResultStatus describes how a message dispatched on behalf of another
+// message finished, or how the set of such messages finished.
+type ResultStatus string
+
+const (
+	// ResultStatusCompleted indicates that the worker finished working on the
+	// message, or that every message completed.
+	ResultStatusCompleted ResultStatus = "completed"
+
+	// ResultStatusFailed indicates that the message could not be dispatched,
+	// or that the worker failed to finish working on it.
+	ResultStatusFailed ResultStatus = "failed"
+
+	// ResultStatusTimeout indicates that the worker did not finish working on
+	// the message before its deadline.
+	ResultStatusTimeout ResultStatus = "timeout"
+
+	// ResultStatusSkipped indicates that the message was not dispatched, such
+	// as a pipeline step after a step that did not complete.
+	ResultStatusSkipped ResultStatus = "skipped"
+)
+
+// MessageResult is the result of a message dispatched on behalf of another
+// message.
+type MessageResult struct {
+	Directive string          `json:"directive"`
+	MessageID string          `json:"message_id,omitempty"`
+	Status    ResultStatus    `json:"status"`
+	Code      ErrorCode       `json:"code,omitempty"`
+	Reason    string          `json:"reason,omitempty"`
+	Output    json.RawMessage `json:"output,omitempty"`
+}
+
+// childMessage records a message the dispatcher dispatched on behalf of
+// another message, such as a pipeline step or a broadcast to one worker. The
+// outcome of a child message is reported in the result of its parent rather
+// than to the server.
+type childMessage struct {
+	directive string
+	done      chan MessageResult
+
+	// captureOutput is true if data the worker transmits in response to the
+	// message is recorded as its output instead of being sent to the server.
+	captureOutput bool
+
+	mu     sync.Mutex
+	output []byte
+}
+
+// runChild dispatches data as a child message and waits for its worker to
+// finish working on it, or for its deadline to pass. It returns the result of
+// the message and, if captureOutput is true, the data the worker transmitted
+// in response to it.
+func (d *Dispatcher) runChild(data yggdrasil.Data, captureOutput bool) (MessageResult, []byte) {
+	// Give the message a deadline if it has none, so that a worker that never
+	// finishes it does not hold up its parent forever.
+	deadline, err := messageDeadline(data, time.Now())
+	if err != nil || deadline.IsZero() {
+		deadline = time.Now().Add(defaultChildTimeout)
+		metadata := make(map[string]string, len(data.Metadata)+1)
+		for k, v := range data.Metadata {
+			metadata[k] = v
+		}
+		metadata[ipc.MetadataKeyDeadline] = deadline.Format(time.RFC3339)
+		data.Metadata = metadata
+	}
+
+	c := &childMessage{
+		directive:     data.Directive,
+		done:          make(chan MessageResult, 1),
+		captureOutput: captureOutput,
+	}
+	d.children.Set(data.MessageID, c)
+	defer d.children.Del(data.MessageID)
+
+	if err := d.Dispatch(data); err != nil {
+		log.Errorf("cannot dispatch message %v: %v", data.MessageID, err)
+		return MessageResult{
+			Directive: data.Directive,
+			MessageID: data.MessageID,
+			Status:    ResultStatusFailed,
+			Code:      dispatchErrorCode(err),
+			Reason:    err.Error(),
+		}, nil
+	}
+
+	timer := time.NewTimer(time.Until(deadline) + childDeadlineGrace)
+	defer timer.Stop()
+
+	var result MessageResult
+	select {
+	case result = <-c.done:
+	case <-timer.C:
+		// The message was not expired at its deadline, such as when it was
+		// not tracked as in-flight; expire it now.
+		d.expireMessage(data.MessageID)
+		select {
+		case result = <-c.done:
+		default:
+			result = MessageResult{
+				Directive: data.Directive,
+				MessageID: data.MessageID,
+				Status:    ResultStatusTimeout,
+				Reason:    "deadline exceeded",
+			}
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return result, c.output
+}
+
+// finishChild records how the child message with the given ID finished, if
+// the message is a child message. It returns true if the message is a child
+// message, in which case events about it are reported in the result of its
+// parent rather than to the server.
+func (d *Dispatcher) finishChild(messageID string, status ResultStatus, code ErrorCode, reason string) bool {
+	c, has := d.children.Get(messageID)
+	if !has {
+		return false
+	}
+	select {
+	case c.done <- MessageResult{
+		Directive: c.directive,
+		MessageID: messageID,
+		Status:    status,
+		Code:      code,
+		Reason:    reason,
+	}:
+	default:
+		// The message has already finished.
+	}
+	return true
+}
+
+// isChildMessage returns true if the message with the given ID is a child
+// message that has not finished.
+func (d *Dispatcher) isChildMessage(messageID string) bool {
+	_, has := d.children.Get(messageID)
+	return has
+}
+
+// captureChildOutput records data transmitted by worker in response to a child
+// message as the output of the message. It returns false if responseTo is not
+// a child message whose output is captured, or if worker is not working on it.
+func (d *Dispatcher) captureChildOutput(worker string, responseTo string, data []byte) bool {
+	c, has := d.children.Get(responseTo)
+	if !has || !c.captureOutput {
+		return false
+	}
+	// The message may have been routed to a worker other than the one named
+	// by its directive.
+	msg, has := d.inflight.Get(responseTo)
+	if !has || msg.data.Directive != worker {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.output = data
+	return true
+}
@@ -0,0 +1,55 @@
+package work
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/redhatinsights/yggdrasil"
+	"github.com/redhatinsights/yggdrasil/internal/config"
+)
+
+func TestRunChildTimeout(t *testing.T) {
+	defaultChildTimeout = time.Second
+	childDeadlineGrace = time.Second
+	execKillDelay = time.Second
+	defer func() {
+		defaultChildTimeout = time.Hour
+		childDeadlineGrace = 10 * time.Second
+		execKillDelay = 10 * time.Second
+	}()
+
+	d := NewDispatcher(nil)
+	d.StartExecWorkers(map[string]config.ExecWorkerConfig{
+		"test": {Directive: "test", Command: "/bin/sleep", Args: []string{"60"}},
+	})
+	go func() {
+		for range d.WorkerEvents {
+		}
+	}()
+	go func() {
+		for range d.OutboundEvents {
+		}
+	}()
+
+	done := make(chan MessageResult)
+	go func() {
+		result, _ := d.runChild(yggdrasil.Data{Directive: "test", MessageID: "1"}, false)
+		done <- result
+	}()
+
+	select {
+	case got := <-done:
+		want := MessageResult{
+			Directive: "test",
+			MessageID: "1",
+			Status:    ResultStatusTimeout,
+			Reason:    "deadline exceeded",
+		}
+		if !cmp.Equal(got, want) {
+			t.Errorf("%v", cmp.Diff(got, want))
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("child message did not time out")
+	}
+}
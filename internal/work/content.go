@@ -0,0 +1,353 @@
+package work
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/redhatinsights/yggdrasil/internal/cache"
+	"github.com/redhatinsights/yggdrasil/internal/config"
+	"github.com/subpop/go-log"
+)
+
+// MetadataKeyContentDigest is the metadata key of a data message whose value is
+// the digest of its detached content, in the form "<algorithm>:<hex>", where
+// algorithm is either sha256 or sha512.
+const MetadataKeyContentDigest = "content_digest"
+
+// errNotModified is returned by copyContent when the server reports that the
+// content has not changed since it was cached.
+var errNotModified = errors.New("content not modified")
+
+// errRedirectDenied is returned by copyContent when the server redirects the
+// download to a location that is not permitted by the content-sources
+// allow-list.
+var errRedirectDenied = errors.New("not an allowed content source")
+
+// contentDigest is the parsed value of a content_digest metadata value.
+type contentDigest struct {
+	algorithm string
+	sum       []byte
+}
+
+// parseContentDigest parses a content_digest metadata value. It returns nil if
+// value is empty.
+func parseContentDigest(value string) (*contentDigest, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	algorithm, sum, ok := strings.Cut(value, ":")
+	if !ok {
+		return nil, fmt.Errorf("cannot parse content digest '%v': missing algorithm", value)
+	}
+	digest := contentDigest{algorithm: strings.ToLower(algorithm)}
+	h, err := digest.hash()
+	if err != nil {
+		return nil, err
+	}
+
+	digest.sum, err = hex.DecodeString(sum)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse content digest '%v': %w", value, err)
+	}
+	if len(digest.sum) != h.Size() {
+		return nil, fmt.Errorf("cannot parse content digest '%v': invalid length", value)
+	}
+
+	return &digest, nil
+}
+
+// hash returns a new hash computing the digest's algorithm.
+func (digest contentDigest) hash() (hash.Hash, error) {
+	switch digest.algorithm {
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported content digest algorithm '%v'", digest.algorithm)
+	}
+}
+
+// String returns the digest in the form "<algorithm>:<hex>".
+func (digest contentDigest) String() string {
+	return digest.algorithm + ":" + hex.EncodeToString(digest.sum)
+}
+
+// verify returns an error if the content read from r does not match the
+// digest.
+func (digest contentDigest) verify(r io.Reader) error {
+	h, err := digest.hash()
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(h, r); err != nil {
+		return fmt.Errorf("cannot read content: %w", err)
+	}
+	if sum := h.Sum(nil); !bytes.Equal(sum, digest.sum) {
+		return fmt.Errorf(
+			"content digest %v:%v does not match %v",
+			digest.algorithm,
+			hex.EncodeToString(sum),
+			digest,
+		)
+	}
+	return nil
+}
+
+// fetchContent returns a file containing the detached content at URL,
+// positioned at its start. If digest is not nil, the content is checked against
+// it. Content is only fetched from locations permitted by the content-sources
+// allow-list, checked after the host of HTTP(S) URLs is replaced with
+// DataHost, and is taken from ContentCache when possible, and stored in it
+// after it is fetched: content with a digest is cached under its digest, and
+// content downloaded with an entity tag is cached under its URL and revalidated
+// with the server each time it is used. The caller must close the returned
+// file.
+func (d *Dispatcher) fetchContent(URL *url.URL, digest *contentDigest) (*os.File, error) {
+	if isHTTPScheme(URL.Scheme) && config.DefaultConfig.DataHost != "" {
+		override := *URL
+		override.Host = config.DefaultConfig.DataHost
+		URL = &override
+	}
+	if !contentSourceAllowed(URL) {
+		return nil, newDispatchError(
+			ErrorCodeContentDenied,
+			fmt.Errorf("cannot fetch detached content from %v: not an allowed content source", URL.Redacted()),
+		)
+	}
+
+	key := URL.String()
+	if digest != nil {
+		key = digest.String()
+	}
+
+	var cached *os.File
+	var cachedETag string
+	if d.ContentCache != nil {
+		f, entry, err := d.ContentCache.Open(key)
+		switch {
+		case err == nil && digest != nil:
+			log.Debugf("using cached detached content %v for %v", key, URL.Redacted())
+			return f, nil
+		case err == nil:
+			cached = f
+			cachedETag = entry.ETag
+		case !errors.Is(err, os.ErrNotExist):
+			log.Warnf("cannot open cached detached content for %v: %v", URL.Redacted(), err)
+		}
+	}
+
+	f, etag, err := d.downloadContent(URL, cachedETag)
+	if errors.Is(err, errNotModified) {
+		log.Debugf("using cached detached content for %v", URL.Redacted())
+		return cached, nil
+	}
+	if cached != nil {
+		_ = cached.Close()
+	}
+	if errors.Is(err, errRedirectDenied) {
+		return nil, newDispatchError(ErrorCodeContentDenied, err)
+	}
+	if err != nil {
+		return nil, newDispatchError(ErrorCodeContentUnavailable, err)
+	}
+
+	if digest != nil {
+		err := digest.verify(f)
+		if err == nil {
+			_, err = f.Seek(0, io.SeekStart)
+		}
+		if err != nil {
+			_ = f.Close()
+			return nil, newDispatchError(ErrorCodeContentMismatch, err)
+		}
+	}
+
+	if d.ContentCache != nil && (digest != nil || etag != "") {
+		if err := d.cacheContent(f, cache.Entry{Key: key, ETag: etag}); err != nil {
+			_ = f.Close()
+			return nil, newDispatchError(ErrorCodeContentUnavailable, err)
+		}
+	}
+
+	return f, nil
+}
+
+// cacheContent stores the content of f in ContentCache under entry and seeks f
+// back to its start. Failing to store the content is not an error.
+func (d *Dispatcher) cacheContent(f *os.File, entry cache.Entry) error {
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("cannot stat temporary file: %w", err)
+	}
+	entry.Size = info.Size()
+	if err := d.ContentCache.Store(entry, f); err != nil {
+		log.Warnf("cannot cache detached content: %v", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("cannot seek temporary file: %w", err)
+	}
+	return nil
+}
+
+// downloadContent downloads the detached content at URL into an unnamed
+// temporary file in ContentDir, so that content too large to hold in memory can
+// be passed to a worker as a file descriptor. HTTP(S) URLs are downloaded with
+// HTTPClient, and URLs with other schemes with the fetcher registered for their
+// scheme. The returned file is positioned at the start of the content; the
+// caller must close it. The entity tag of the content is returned along with
+// it, if the server provided one. If etag is not empty and the server reports
+// that the content has not changed, the returned error is errNotModified.
+func (d *Dispatcher) downloadContent(URL *url.URL, etag string) (*os.File, string, error) {
+	var fetcher Fetcher
+	if !isHTTPScheme(URL.Scheme) {
+		var has bool
+		fetcher, has = d.fetcher(URL.Scheme)
+		if !has {
+			return nil, "", fmt.Errorf("cannot fetch detached content: unsupported URL scheme '%v'", URL.Scheme)
+		}
+	}
+
+	f, err := os.CreateTemp(d.ContentDir, "content-")
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot create temporary file: %w", err)
+	}
+	// Remove the file's name right away, so that its storage is released once
+	// both yggd and the worker have closed it.
+	if err := os.Remove(f.Name()); err != nil {
+		log.Errorf("cannot remove temporary file %v: %v", f.Name(), err)
+	}
+
+	if fetcher != nil {
+		etag, err = "", fetchContentWith(fetcher, f, URL)
+	} else {
+		etag, err = d.copyContent(f, URL.String(), etag)
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, "", err
+	}
+	return f, etag, nil
+}
+
+// checkContentRedirect is the redirect policy of detached content downloads. It
+// follows up to 10 redirects, like the default policy of http.Client, as long
+// as the content-sources allow-list permits fetching from each of them.
+func checkContentRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if !contentSourceAllowed(req.URL) {
+		return fmt.Errorf("cannot follow redirect to %v: %w", req.URL.Redacted(), errRedirectDenied)
+	}
+	return nil
+}
+
+// fetchContentWith writes the detached content at URL, retrieved with
+// fetcher, to f and seeks back to its start.
+func fetchContentWith(fetcher Fetcher, f *os.File, URL *url.URL) error {
+	r, err := fetcher(URL)
+	if err != nil {
+		return fmt.Errorf("cannot fetch detached content: %w", err)
+	}
+	defer func() {
+		if err := r.Close(); err != nil {
+			log.Errorf("cannot close detached content: %v", err)
+		}
+	}()
+
+	n, err := io.Copy(f, r)
+	if err != nil {
+		return fmt.Errorf("cannot read detached content: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("cannot seek temporary file: %w", err)
+	}
+	log.Debugf("fetched %v bytes of detached content from a %v URL", n, URL.Scheme)
+	return nil
+}
+
+// copyContent writes the detached content at URL to f and seeks back to its
+// start, returning the entity tag of the content. If reading the content fails
+// part way through, the download is resumed from where it stopped, up to the
+// number of retries of HTTPClient. If etag is not empty and the server reports
+// that the content has not changed, errNotModified is returned. Redirects are
+// only followed to locations permitted by the content-sources allow-list;
+// errRedirectDenied is returned otherwise.
+func (d *Dispatcher) copyContent(f *os.File, URL string, etag string) (string, error) {
+	client := *d.HTTPClient
+	client.CheckRedirect = checkContentRedirect
+
+	headers := map[string]string{}
+	if etag != "" {
+		headers["If-None-Match"] = etag
+	}
+
+	var written int64
+	for attempt := 0; ; attempt++ {
+		resp, err := client.GetWithHeaders(URL, headers)
+		if err != nil {
+			return "", fmt.Errorf("cannot get detached message content: %w", err)
+		}
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			// The server sent the entire content, either because none was
+			// downloaded yet, or because it cannot resume the download.
+			if written > 0 {
+				if err := f.Truncate(0); err != nil {
+					_ = resp.Body.Close()
+					return "", fmt.Errorf("cannot truncate temporary file: %w", err)
+				}
+				if _, err := f.Seek(0, io.SeekStart); err != nil {
+					_ = resp.Body.Close()
+					return "", fmt.Errorf("cannot seek temporary file: %w", err)
+				}
+				written = 0
+			}
+			etag = resp.Header.Get("ETag")
+		case http.StatusPartialContent:
+		case http.StatusNotModified:
+			_ = resp.Body.Close()
+			return etag, errNotModified
+		default:
+			_ = resp.Body.Close()
+			return "", fmt.Errorf("cannot get detached message content: unexpected response status: %v", resp.Status)
+		}
+
+		n, err := io.Copy(f, resp.Body)
+		written += n
+		if err := resp.Body.Close(); err != nil {
+			log.Errorf("cannot close response body: %v", err)
+		}
+		if err == nil {
+			break
+		}
+		if attempt >= client.Retries {
+			return "", fmt.Errorf("cannot read response body: %w", err)
+		}
+
+		log.Warnf("cannot read detached message content from %v, resuming at byte %v: %v", URL, written, err)
+		headers = map[string]string{"Range": fmt.Sprintf("bytes=%v-", written)}
+		if etag != "" {
+			headers["If-Range"] = etag
+		}
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("cannot seek temporary file: %w", err)
+	}
+	log.Debugf("downloaded %v bytes of detached content from %v", written, URL)
+	return etag, nil
+}
@@ -0,0 +1,372 @@
+package work
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/redhatinsights/yggdrasil/internal/cache"
+	"github.com/redhatinsights/yggdrasil/internal/config"
+	internalhttp "github.com/redhatinsights/yggdrasil/internal/http"
+)
+
+func mustParseURL(t *testing.T, rawURL string) *url.URL {
+	URL, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return URL
+}
+
+func sha256Digest(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func TestParseContentDigest(t *testing.T) {
+	tests := []struct {
+		description string
+		input       string
+		want        string
+		wantError   bool
+	}{
+		{
+			description: "empty",
+			input:       "",
+			want:        "",
+		},
+		{
+			description: "sha256",
+			input:       sha256Digest("hello"),
+			want:        sha256Digest("hello"),
+		},
+		{
+			description: "sha512",
+			input:       "SHA512:9b71d224bd62f3785d96d46ad3ea3d73319bfbc2890caadae2dff72519673ca72323c3d99ba5c11d7c7acc6e14b8c5da0c4663475c2e5c3adef46f73bcdec043",
+			want:        "sha512:9b71d224bd62f3785d96d46ad3ea3d73319bfbc2890caadae2dff72519673ca72323c3d99ba5c11d7c7acc6e14b8c5da0c4663475c2e5c3adef46f73bcdec043",
+		},
+		{
+			description: "missing algorithm",
+			input:       "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+			wantError:   true,
+		},
+		{
+			description: "unsupported algorithm",
+			input:       "md5:5d41402abc4b2a76b9719d911017c592",
+			wantError:   true,
+		},
+		{
+			description: "invalid length",
+			input:       "sha256:2cf24dba",
+			wantError:   true,
+		},
+		{
+			description: "invalid hex",
+			input:       "sha256:hello",
+			wantError:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			digest, err := parseContentDigest(test.input)
+			if test.wantError {
+				if err == nil {
+					t.Errorf("expected error, got %v", digest)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got string
+			if digest != nil {
+				got = digest.String()
+			}
+			if !cmp.Equal(got, test.want) {
+				t.Errorf("%#v != %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFetchContent(t *testing.T) {
+	tests := []struct {
+		description string
+		content     string
+		status      int
+		digest      string
+		// truncate is the number of bytes of content sent in response to the
+		// first request before the connection is closed. A zero value sends
+		// all of the content.
+		truncate  int
+		retries   int
+		want      string
+		wantError ErrorCode
+	}{
+		{
+			description: "content",
+			content:     "hello world",
+			want:        "hello world",
+		},
+		{
+			description: "matching digest",
+			content:     "hello world",
+			digest:      sha256Digest("hello world"),
+			want:        "hello world",
+		},
+		{
+			description: "mismatched digest",
+			content:     "hello world",
+			digest:      sha256Digest("goodbye world"),
+			wantError:   ErrorCodeContentMismatch,
+		},
+		{
+			description: "not found",
+			status:      http.StatusNotFound,
+			wantError:   ErrorCodeContentUnavailable,
+		},
+		{
+			description: "resumed",
+			content:     "hello world",
+			digest:      sha256Digest("hello world"),
+			truncate:    5,
+			retries:     1,
+			want:        "hello world",
+		},
+		{
+			description: "too many retries",
+			content:     "hello world",
+			truncate:    5,
+			wantError:   ErrorCodeContentUnavailable,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if test.status != 0 {
+					w.WriteHeader(test.status)
+					return
+				}
+				w.Header().Set("ETag", `"1"`)
+				var start int
+				if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &start); err == nil {
+					w.Header().Set("Content-Range", fmt.Sprintf("bytes %v-%v/%v", start, len(test.content)-1, len(test.content)))
+					w.WriteHeader(http.StatusPartialContent)
+					_, _ = io.WriteString(w, test.content[start:])
+					return
+				}
+				w.Header().Set("Content-Length", strconv.Itoa(len(test.content)))
+				if test.truncate > 0 && requests == 1 {
+					_, _ = io.WriteString(w, test.content[:test.truncate])
+					return
+				}
+				_, _ = io.WriteString(w, test.content)
+			}))
+			defer server.Close()
+
+			d := &Dispatcher{
+				HTTPClient: internalhttp.NewHTTPClient(nil, "test"),
+				ContentDir: t.TempDir(),
+			}
+			d.HTTPClient.Retries = test.retries
+			digest, err := parseContentDigest(test.digest)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := d.fetchContent(mustParseURL(t, server.URL), digest)
+			if test.wantError != "" {
+				var dispatchErr *DispatchError
+				if !errors.As(err, &dispatchErr) {
+					t.Fatalf("expected DispatchError, got %v", err)
+				}
+				if dispatchErr.Code != test.wantError {
+					t.Errorf("%v != %v", dispatchErr.Code, test.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			got, err := io.ReadAll(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(string(got), test.want) {
+				t.Errorf("%#v != %#v", string(got), test.want)
+			}
+
+			entries, err := os.ReadDir(d.ContentDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 0 {
+				t.Errorf("content directory is not empty: %v", entries)
+			}
+		})
+	}
+}
+
+func TestFetchContentCache(t *testing.T) {
+	tests := []struct {
+		description  string
+		etag         string
+		digest       string
+		wantRequests int
+	}{
+		{
+			description:  "not cached",
+			wantRequests: 2,
+		},
+		{
+			description:  "revalidated",
+			etag:         `"1"`,
+			wantRequests: 2,
+		},
+		{
+			description:  "digest",
+			digest:       sha256Digest("hello world"),
+			wantRequests: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			requests := 0
+			notModified := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if test.etag != "" {
+					if r.Header.Get("If-None-Match") == test.etag {
+						notModified++
+						w.WriteHeader(http.StatusNotModified)
+						return
+					}
+					w.Header().Set("ETag", test.etag)
+				}
+				_, _ = io.WriteString(w, "hello world")
+			}))
+			defer server.Close()
+
+			contentCache, err := cache.New(t.TempDir(), 1024)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := &Dispatcher{
+				HTTPClient:   internalhttp.NewHTTPClient(nil, "test"),
+				ContentDir:   t.TempDir(),
+				ContentCache: contentCache,
+			}
+			digest, err := parseContentDigest(test.digest)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 0; i < 2; i++ {
+				f, err := d.fetchContent(mustParseURL(t, server.URL), digest)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := io.ReadAll(f)
+				_ = f.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !cmp.Equal(string(got), "hello world") {
+					t.Errorf("%#v != %#v", string(got), "hello world")
+				}
+			}
+
+			if requests != test.wantRequests {
+				t.Errorf("%v != %v", requests, test.wantRequests)
+			}
+			if test.etag != "" && notModified != 1 {
+				t.Errorf("expected content to be revalidated, got %v not modified responses", notModified)
+			}
+		})
+	}
+}
+
+func TestFetchContentRedirect(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "hello world")
+	}))
+	defer target.Close()
+	targetURL := mustParseURL(t, target.URL)
+
+	tests := []struct {
+		description string
+		location    string
+		want        string
+		wantError   ErrorCode
+	}{
+		{
+			description: "allowed",
+			location:    "http://" + targetURL.Host + "/content",
+			want:        "hello world",
+		},
+		{
+			description: "denied",
+			location:    "http://localhost:" + targetURL.Port() + "/content",
+			wantError:   ErrorCodeContentDenied,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			config.DefaultConfig.ContentSources = config.ContentSourceConfig{"http": {"127.0.0.1"}}
+			defer func() {
+				config.DefaultConfig.ContentSources = nil
+			}()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, test.location, http.StatusFound)
+			}))
+			defer server.Close()
+
+			d := &Dispatcher{
+				HTTPClient: internalhttp.NewHTTPClient(nil, "test"),
+				ContentDir: t.TempDir(),
+			}
+
+			f, err := d.fetchContent(mustParseURL(t, server.URL), nil)
+			if test.wantError != "" {
+				var dispatchErr *DispatchError
+				if !errors.As(err, &dispatchErr) {
+					t.Fatalf("expected DispatchError, got %v", err)
+				}
+				if dispatchErr.Code != test.wantError {
+					t.Errorf("%v != %v", dispatchErr.Code, test.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			got, err := io.ReadAll(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(string(got), test.want) {
+				t.Errorf("%#v != %#v", string(got), test.want)
+			}
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package work
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/url"
@@ -12,10 +14,13 @@ import (
 
 	"github.com/godbus/dbus/v5"
 	"github.com/godbus/dbus/v5/introspect"
+	"github.com/google/uuid"
 	"github.com/redhatinsights/yggdrasil"
+	"github.com/redhatinsights/yggdrasil/internal/cache"
 	"github.com/redhatinsights/yggdrasil/internal/config"
 	internalhttp "github.com/redhatinsights/yggdrasil/internal/http"
 	"github.com/redhatinsights/yggdrasil/internal/messagejournal"
+	"github.com/redhatinsights/yggdrasil/internal/schedule"
 	"github.com/redhatinsights/yggdrasil/internal/sync"
 	"github.com/redhatinsights/yggdrasil/ipc"
 	"github.com/subpop/go-log"
@@ -26,17 +31,44 @@ const (
 	TransmitResponseOK  int = 0
 )
 
+const (
+	// unavailableRetryDelay is the duration to wait before dispatching a
+	// message again to a worker that is temporarily unavailable. The delay
+	// doubles after each attempt.
+	unavailableRetryDelay = 2 * time.Second
+
+	// maxUnavailableRetries is the number of times a message is dispatched
+	// again to a worker that is temporarily unavailable.
+	maxUnavailableRetries = 5
+)
+
 // Dispatcher implements the com.redhat.Yggdrasil1.Dispatcher1 D-Bus interface
 // and is suitable to be exported onto a bus.
 //
 // Dispatcher receives values on its 'inbound' channel and sends them via D-Bus
 // to the destination worker. It sends values on the 'outbound' channel to relay
-// data received from workers to a remote address.
+// data received from workers to a remote address. Events that should be
+// reported to the server, such as failures to dispatch inbound data, are sent
+// on the 'outboundEvents' channel. They are queued until they can be sent, so
+// that a slow transport does not hold up the dispatcher.
 type Dispatcher struct {
 	HTTPClient     *internalhttp.Client
 	conn           *dbus.Conn
 	features       sync.RWMutexMap[map[string]string]
+	manifests      sync.RWMutexMap[*compiledManifest]
+	inflight       sync.RWMutexMap[*inflightMessage]
+	children       sync.RWMutexMap[*childMessage]
+	ledger         messageLedger
+	scheduled      sync.RWMutexMap[*time.Timer]
+	jobs           sync.RWMutexMap[*job]
+	usage          sync.RWMutexMap[*transmitUsage]
+	execWorkers    sync.RWMutexMap[config.ExecWorkerConfig]
+	execs          sync.RWMutexMap[context.CancelFunc]
+	fetchers       sync.RWMutexMap[Fetcher]
 	MessageJournal *messagejournal.MessageJournal
+	Schedule       *schedule.Store
+	ContentDir     string
+	ContentCache   *cache.Cache
 	Dispatchers    chan map[string]map[string]string
 	WorkerEvents   chan ipc.WorkerEvent
 	Inbound        chan yggdrasil.Data
@@ -44,13 +76,25 @@ type Dispatcher struct {
 		Data yggdrasil.Data
 		Resp chan yggdrasil.Response
 	}
+	OutboundEvents chan yggdrasil.Event
+	events         eventQueue
 }
 
 func NewDispatcher(client *internalhttp.Client) *Dispatcher {
 	return &Dispatcher{
 		HTTPClient:     client,
 		features:       sync.RWMutexMap[map[string]string]{},
+		manifests:      sync.RWMutexMap[*compiledManifest]{},
+		inflight:       sync.RWMutexMap[*inflightMessage]{},
+		children:       sync.RWMutexMap[*childMessage]{},
+		scheduled:      sync.RWMutexMap[*time.Timer]{},
+		jobs:           sync.RWMutexMap[*job]{},
+		usage:          sync.RWMutexMap[*transmitUsage]{},
+		execWorkers:    sync.RWMutexMap[config.ExecWorkerConfig]{},
+		execs:          sync.RWMutexMap[context.CancelFunc]{},
+		fetchers:       sync.RWMutexMap[Fetcher]{},
 		MessageJournal: nil,
+		Schedule:       schedule.New(),
 		Dispatchers:    make(chan map[string]map[string]string),
 		WorkerEvents:   make(chan ipc.WorkerEvent),
 		Inbound:        make(chan yggdrasil.Data),
@@ -58,6 +102,8 @@ func NewDispatcher(client *internalhttp.Client) *Dispatcher {
 			Data yggdrasil.Data
 			Resp chan yggdrasil.Response
 		}),
+		OutboundEvents: make(chan yggdrasil.Event),
+		events:         eventQueue{ready: make(chan struct{}, 1)},
 	}
 }
 
@@ -153,6 +199,10 @@ func (d *Dispatcher) Connect() error {
 					)
 					d.Dispatchers <- d.FlattenDispatchers()
 				}
+				if _, has := changedProperties["Manifest"]; has {
+					d.setManifest(directive, changedProperties["Manifest"])
+					d.Dispatchers <- d.FlattenDispatchers()
+				}
 			case "com.redhat.Yggdrasil1.Worker1.Event":
 				event, err := workerEventFromSignal(s)
 				if err != nil {
@@ -160,32 +210,7 @@ func (d *Dispatcher) Connect() error {
 					continue
 				}
 				event.Worker = filepath.Base(string(s.Path))
-
-				d.WorkerEvents <- *event
-
-				// Start goroutine to add a new message journal entry.
-				go func() {
-					// Skip adding a new entry if the message journal is disabled.
-					if d.MessageJournal == nil {
-						return
-					}
-					workerMessage := yggdrasil.WorkerMessage{
-						MessageID:  event.MessageID,
-						Sent:       time.Now().UTC(),
-						WorkerName: event.Worker,
-						ResponseTo: event.ResponseTo,
-						WorkerEvent: struct {
-							EventName uint              "json:\"event_name\""
-							EventData map[string]string "json:\"event_data\""
-						}{
-							uint(event.Name),
-							event.Data,
-						},
-					}
-					if err := d.MessageJournal.AddEntry(workerMessage); err != nil {
-						log.Errorf("cannot add journal entry: %v", err)
-					}
-				}()
+				d.handleWorkerEvent(event)
 			case "org.freedesktop.DBus.NameOwnerChanged":
 				name, ok := s.Body[0].(string)
 				if !ok {
@@ -206,13 +231,17 @@ func (d *Dispatcher) Connect() error {
 				workerName := strings.TrimPrefix(name, "com.redhat.Yggdrasil1.Worker1.")
 
 				// If there was an old owner, this signal means the old
-				// owner no longer owns the name; clean up the feature map.
+				// owner no longer owns the name; clean up the feature and
+				// manifest maps and fail any messages it did not finish.
 				if oldOwner != "" {
 					d.features.Del(workerName)
+					d.manifests.Del(workerName)
+					d.abandonMessages(workerName)
 				}
 
 				// If there is a new owner, this signal means a new process
-				// owns the name; add a record to the feature map.
+				// owns the name; add a record to the feature and manifest
+				// maps.
 				if newOwner != "" {
 					obj := d.conn.Object(
 						name,
@@ -234,6 +263,7 @@ func (d *Dispatcher) Connect() error {
 						continue
 					}
 					d.features.Set(workerName, features)
+					d.loadManifest(workerName, obj)
 				}
 				d.Dispatchers <- d.FlattenDispatchers()
 			}
@@ -276,6 +306,7 @@ func (d *Dispatcher) Connect() error {
 					continue
 				}
 				d.features.Set(directive, features)
+				d.loadManifest(directive, obj)
 			} else {
 				d.features.Set(directive, map[string]string{})
 			}
@@ -284,26 +315,89 @@ func (d *Dispatcher) Connect() error {
 	}()
 
 	// start goroutine receiving values from the inbound channel and send them
-	// via the Worker D-Bus interface.
+	// via the Worker D-Bus interface. Messages scheduled for later are added
+	// to the schedule instead. Failures to deliver the data to a worker are
+	// reported to the server.
 	go func() {
 		for data := range d.Inbound {
-			if err := d.Dispatch(data); err != nil {
+			removeJobMetadata(data)
+			scheduled, err := d.scheduleMessage(data)
+			if err != nil {
 				log.Errorf("cannot dispatch data: %v", err)
+				d.reportDispatchError(data, err)
 				continue
 			}
+			if scheduled {
+				continue
+			}
+			d.dispatchInbound(data)
 		}
 	}()
 
+	// Resume dispatching messages scheduled before yggd restarted.
+	d.resumeSchedule()
+
 	return nil
 }
 
+// dispatchInbound dispatches data received from the server, either to the
+// worker identified by its directive, to every worker matching its selector if
+// it is a broadcast or, if it carries a pipeline descriptor, to each step of
+// the pipeline.
+func (d *Dispatcher) dispatchInbound(data yggdrasil.Data) {
+	if data.Directive == BroadcastDirective {
+		go d.runBroadcast(data)
+		return
+	}
+	if _, has := data.Metadata[MetadataKeyPipeline]; has {
+		go d.runPipeline(data)
+		return
+	}
+	if err := d.Dispatch(data); err != nil {
+		if isWorkerUnavailableError(err) {
+			log.Infof("worker %v is unavailable; retrying message %v later", data.Directive, data.MessageID)
+			go d.retryDispatch(data)
+			return
+		}
+		log.Errorf("cannot dispatch data: %v", err)
+		d.reportDispatchError(data, err)
+	}
+}
+
+// Dispatch sends data to the worker that handles the data's directive
+// according to the routing table. If no worker exists for the directive, data
+// is sent to the fallback worker, if one is configured.
 func (d *Dispatcher) Dispatch(data yggdrasil.Data) error {
+	routes := config.DefaultConfig.Routes
+	data = routeMessage(data, resolveDirective(routes, data.Directive))
+
+	err := d.dispatch(data, 0)
+	if routes.Fallback != "" && routes.Fallback != data.Directive && isUnknownDirectiveError(err) {
+		log.Infof(
+			"no worker for directive %v; dispatching message %v to fallback worker %v",
+			data.Directive,
+			data.MessageID,
+			routes.Fallback,
+		)
+		return d.dispatch(routeMessage(data, routes.Fallback), 0)
+	}
+	return err
+}
+
+// dispatch sends data to its worker. attempt is the number of times the
+// message has already been dispatched to a worker that exited before
+// finishing it.
+func (d *Dispatcher) dispatch(data yggdrasil.Data, attempt int) error {
 	var err error
 	data.Directive, err = ScrubName(data.Directive)
 	if err != nil {
 		log.Debug(err)
 	}
 
+	if cfg, has := d.execWorkers.Get(data.Directive); has {
+		return d.dispatchExec(data, cfg, attempt)
+	}
+
 	obj := d.conn.Object(
 		"com.redhat.Yggdrasil1.Worker1."+data.Directive,
 		dbus.ObjectPath(filepath.Join("/com/redhat/Yggdrasil1/Worker1/", data.Directive)),
@@ -311,61 +405,125 @@ func (d *Dispatcher) Dispatch(data yggdrasil.Data) error {
 	propertyName := "com.redhat.Yggdrasil1.Worker1.RemoteContent"
 	r, err := obj.GetProperty(propertyName)
 	if err != nil {
-		return fmt.Errorf(
+		code := ErrorCodeDispatchFailed
+		if isNameUnknownError(err) {
+			code = ErrorCodeUnknownDirective
+		}
+		return newDispatchError(code, fmt.Errorf(
 			"cannot get property '%s' of object: %s: using destination interface: %s: %v",
 			propertyName, obj.Path(), obj.Destination(), err,
-		)
+		))
+	}
+
+	// Data is only dispatched if the worker's manifest accepts it. The
+	// content of workers receiving detached content through a file descriptor
+	// is not validated, as that would require reading it in full.
+	manifest, err := d.dispatchManifest(data.Directive, obj)
+	if err != nil {
+		return newDispatchError(ErrorCodeDispatchFailed, err)
+	}
+	if err := validateMetadata(manifest, data.Metadata); err != nil {
+		return err
 	}
 
-	if r.Value().(bool) {
+	// stream is the file detached content is downloaded into for workers
+	// that receive it through a file descriptor.
+	var stream *os.File
+	if !r.Value().(bool) {
+		if err := validateContent(manifest, data.Metadata[MetadataKeyContentType], data.Content); err != nil {
+			return err
+		}
+	} else {
 		// Because the data.Content field is typed as json.RawMessage, it must first be
 		// unmarshalled into a Go string before parsing as a URL.
 		var urlStr string
 		err = json.Unmarshal(data.Content, &urlStr)
 		if err != nil {
-			return fmt.Errorf("unable to unmarshal JSON string fragment: %v", err)
+			return newDispatchError(
+				ErrorCodeContentUnavailable,
+				fmt.Errorf("unable to unmarshal JSON string fragment: %v", err),
+			)
 		}
 
 		// When string fragment was unmarshalled, then we can try to parse string as URL
 		URL, err := url.Parse(urlStr)
 		if err != nil {
-			return fmt.Errorf("cannot parse content %v as URL: %v", urlStr, err)
-		}
-		if config.DefaultConfig.DataHost != "" {
-			URL.Host = config.DefaultConfig.DataHost
+			return newDispatchError(
+				ErrorCodeContentUnavailable,
+				fmt.Errorf("cannot parse content %v as URL: %v", urlStr, err),
+			)
 		}
 
-		resp, err := d.HTTPClient.Get(URL.String())
+		digest, err := parseContentDigest(data.Metadata[MetadataKeyContentDigest])
 		if err != nil {
-			return fmt.Errorf("cannot get detached message content: %v", err)
+			return newDispatchError(ErrorCodeInvalidDigest, err)
 		}
-		content, err := io.ReadAll(resp.Body)
+		f, err := d.fetchContent(URL, digest)
 		if err != nil {
-			return fmt.Errorf("cannot read response body: %v", err)
+			return err
 		}
-		if err := resp.Body.Close(); err != nil {
-			return fmt.Errorf("cannot close response body: %v", err)
+		defer func() {
+			if err := f.Close(); err != nil {
+				log.Errorf("cannot close detached content file: %v", err)
+			}
+		}()
+
+		if streamsContent(obj) {
+			stream = f
+		} else {
+			content, err := io.ReadAll(f)
+			if err != nil {
+				return newDispatchError(
+					ErrorCodeContentUnavailable,
+					fmt.Errorf("cannot read detached content file: %v", err),
+				)
+			}
+			if err := validateContent(manifest, data.Metadata[MetadataKeyContentType], content); err != nil {
+				return err
+			}
+			data.Content = content
 		}
-		data.Content = content
 	}
 
-	call := obj.Call(
-		"com.redhat.Yggdrasil1.Worker1.Dispatch",
-		0,
-		data.Directive,
-		data.MessageID,
-		data.ResponseTo,
-		data.Metadata,
-		data.Content,
-	)
+	// Track the message before calling the worker, as the worker may finish
+	// working on it before the method call returns.
+	d.trackMessage(data, attempt)
+
+	var call *dbus.Call
+	if stream != nil {
+		call = obj.Call(
+			"com.redhat.Yggdrasil1.Worker1.DispatchStream",
+			0,
+			data.Directive,
+			data.MessageID,
+			data.ResponseTo,
+			data.Metadata,
+			dbus.UnixFD(stream.Fd()),
+		)
+	} else {
+		call = obj.Call(
+			"com.redhat.Yggdrasil1.Worker1.Dispatch",
+			0,
+			data.Directive,
+			data.MessageID,
+			data.ResponseTo,
+			data.Metadata,
+			data.Content,
+		)
+	}
 	if err := call.Store(); err != nil {
-		return fmt.Errorf(
+		d.untrackMessage(data.MessageID)
+		code := ErrorCodeDispatchFailed
+		if isWorkerDrainingError(err) {
+			code = ErrorCodeWorkerUnavailable
+		}
+		return newDispatchError(code, fmt.Errorf(
 			"cannot call 'Dispatch' method on worker: %s of object: %s: using destination interface: %s: %v",
 			data.Directive,
 			obj.Path(),
 			obj.Destination(),
 			err,
-		)
+		))
 	}
 	log.Debugf("send message %v to worker %v", data.MessageID, data.Directive)
 
@@ -389,6 +547,9 @@ func (d *Dispatcher) DisconnectWorkers() {
 	}
 }
 
+// FlattenDispatchers returns the features of each worker, keyed by the
+// directives the worker handles: its name, its "legacy" name and any
+// directives routed to it.
 func (d *Dispatcher) FlattenDispatchers() map[string]map[string]string {
 	dispatchers := make(map[string]map[string]string)
 	d.features.Visit(func(k string, v map[string]string) {
@@ -400,9 +561,97 @@ func (d *Dispatcher) FlattenDispatchers() map[string]map[string]string {
 		dispatchers[strings.ReplaceAll(k, "_", "-")] = v
 	})
 
+	// Advertise the directives routed to connected workers.
+	for directive, worker := range config.DefaultConfig.Routes.Directives {
+		worker, _ = ScrubName(worker)
+		if features, has := d.features.Get(worker); has {
+			dispatchers[directive] = features
+		}
+	}
+
 	return dispatchers
 }
 
+// handleWorkerEvent handles an event emitted by a worker: it records the
+// progress of the message, stops tracking messages the worker has finished,
+// and reports failures to the server.
+func (d *Dispatcher) handleWorkerEvent(event *ipc.WorkerEvent) {
+	if event.Name == ipc.WorkerEventNameWorking {
+		d.handleProgress(event)
+	}
+
+	// The worker has finished working on the message, successfully or not; it
+	// is no longer in-flight.
+	if event.Name == ipc.WorkerEventNameEnd || event.Name == ipc.WorkerEventNameFailed {
+		d.untrackMessage(event.MessageID)
+	}
+
+	d.emitWorkerEvent(*event)
+
+	// Nothing more is reported about a message once it has finished, such as
+	// a failure the worker reports after the message timed out.
+	if event.Name == ipc.WorkerEventNameEnd || event.Name == ipc.WorkerEventNameFailed {
+		if !d.finishMessage(event.MessageID) {
+			log.Debugf("ignoring %v event of message %v: it already finished", event.Name, event.MessageID)
+			return
+		}
+	}
+
+	var isChild bool
+	switch event.Name {
+	case ipc.WorkerEventNameEnd:
+		isChild = d.finishChild(event.MessageID, ResultStatusCompleted, "", "")
+	case ipc.WorkerEventNameFailed:
+		isChild = d.finishChild(
+			event.MessageID,
+			ResultStatusFailed,
+			ErrorCode(event.Data[ipc.WorkerEventDataKeyCode]),
+			event.Data[ipc.WorkerEventDataKeyMessage],
+		)
+	}
+
+	// Report the failure to the server, unless it is reported in the result
+	// of a parent message.
+	if event.Name == ipc.WorkerEventNameFailed && !isChild {
+		d.sendMessageEvent(event.MessageID, newFailedEvent(
+			event.Worker,
+			event.MessageID,
+			ErrorCode(event.Data[ipc.WorkerEventDataKeyCode]),
+			event.Data[ipc.WorkerEventDataKeyMessage],
+		))
+	}
+}
+
+// emitWorkerEvent sends event to the WorkerEvents channel and adds an entry for
+// it to the message journal.
+func (d *Dispatcher) emitWorkerEvent(event ipc.WorkerEvent) {
+	d.WorkerEvents <- event
+
+	// Start goroutine to add a new message journal entry.
+	go func() {
+		// Skip adding a new entry if the message journal is disabled.
+		if d.MessageJournal == nil {
+			return
+		}
+		workerMessage := yggdrasil.WorkerMessage{
+			MessageID:  event.MessageID,
+			Sent:       time.Now().UTC(),
+			WorkerName: event.Worker,
+			ResponseTo: event.ResponseTo,
+			WorkerEvent: struct {
+				EventName uint              "json:\"event_name\""
+				EventData map[string]string "json:\"event_data\""
+			}{
+				uint(event.Name),
+				event.Data,
+			},
+		}
+		if err := d.MessageJournal.AddEntry(workerMessage); err != nil {
+			log.Errorf("cannot add journal entry: %v", err)
+		}
+	}()
+}
+
 func (d *Dispatcher) EmitEvent(event ipc.DispatcherEvent) error {
 	return d.conn.Emit(
 		"/com/redhat/Yggdrasil1/Dispatcher1",
@@ -423,70 +672,76 @@ func (d *Dispatcher) Transmit(
 	name, err := d.senderName(sender)
 	if err != nil {
 		return TransmitResponseErr, nil, nil, NewDBusError(
-			"Transmit",
+			ipc.ErrorNameTransmit,
 			fmt.Sprintf("cannot get name for sender: %v", err),
 		)
 	}
 
 	directive := strings.TrimPrefix(name, "com.redhat.Yggdrasil1.Worker1.")
 
-	obj := d.conn.Object(
-		"com.redhat.Yggdrasil1.Worker1."+directive,
-		dbus.ObjectPath(filepath.Join("/com/redhat/Yggdrasil1/Worker1/", directive)),
-	)
-	r, err := obj.GetProperty("com.redhat.Yggdrasil1.Worker1.RemoteContent")
-	if err != nil {
-		return -1, nil, nil, NewDBusError(
-			"Transmit",
-			"cannot get property 'com.redhat.Yggdrasil1.Worker1.RemoteContent'",
+	return d.transmit(directive, addr, messageID, responseTo, metadata, data)
+}
+
+// transmit sends data transmitted by the worker directive to addr: the input of
+// the next step of a pipeline, a local worker, an HTTP(S) URL if the worker
+// receives remote content, or the server.
+func (d *Dispatcher) transmit(
+	directive string,
+	addr string,
+	messageID string,
+	responseTo string,
+	metadata map[string]string,
+	data []byte,
+) (responseCode int, responseMetadata map[string]string, responseData []byte, responseError *dbus.Error) {
+	// Data transmitted in response to a pipeline step is the input of the
+	// next step rather than a message for the server.
+	if d.captureChildOutput(directive, responseTo, data) {
+		return TransmitResponseOK, map[string]string{}, []byte{}, nil
+	}
+
+	if err := d.throttleTransmit(directive, len(data)); err != nil {
+		log.Warnf("throttled worker %v transmitting message %v to %v: %v", directive, messageID, addr, err)
+		return TransmitResponseErr, nil, nil, NewDBusError(
+			ipc.ErrorNameThrottled,
+			fmt.Sprintf("worker %v cannot transmit: %v", directive, err),
 		)
 	}
 
-	if r.Value().(bool) {
+	if strings.HasPrefix(addr, LocalAddrPrefix) {
+		return d.transmitLocal(directive, addr, messageID, responseTo, metadata, data)
+	}
+
+	remoteContent, dbusErr := d.isRemoteContentWorker(directive)
+	if dbusErr != nil {
+		return -1, nil, nil, dbusErr
+	}
+
+	if remoteContent {
 		URL, err := url.Parse(addr)
 		if err != nil {
 			return TransmitResponseErr, nil, nil, NewDBusError(
-				"Transmit",
+				ipc.ErrorNameTransmit,
 				fmt.Sprintf("cannot parse addr as URL: %v", err),
 			)
 		}
 		if URL.Scheme != "" {
-			if config.DefaultConfig.DataHost != "" {
-				URL.Host = config.DefaultConfig.DataHost
-			}
-			resp, err := d.HTTPClient.Post(URL.String(), metadata, data)
-			if err != nil {
-				return TransmitResponseErr, nil, nil, NewDBusError(
-					"Transmit",
-					fmt.Sprintf("cannot perform HTTP request: %v", err),
-				)
-			}
-			data, err = io.ReadAll(resp.Body)
-			if err != nil {
-				return TransmitResponseErr, nil, nil, NewDBusError(
-					"Transmit",
-					fmt.Sprintf("cannot read HTTP response body: %v", err),
-				)
-			}
-
-			err = resp.Body.Close()
-			if err != nil {
-				return TransmitResponseErr, nil, nil, NewDBusError(
-					"Transmit",
-					fmt.Sprintf("cannot close HTTP response body: %v", err),
-				)
-			}
-			responseCode = resp.StatusCode
-			responseMetadata = make(map[string]string)
-			for header := range resp.Header {
-				responseMetadata[header] = resp.Header.Get(header)
-			}
-			responseData = data
+			return d.transmitHTTP(directive, addr, messageID, responseTo, URL, metadata, bytesBody(data))
 		} else {
-			return TransmitResponseErr, nil, nil, NewDBusError("Transmit", fmt.Sprintf("URL: '%v' has no scheme", addr))
+			return TransmitResponseErr, nil, nil, NewDBusError(ipc.ErrorNameTransmit, fmt.Sprintf("URL: '%v' has no scheme", addr))
 		}
 	} else {
-		ch := make(chan yggdrasil.Response)
+		if !transmitDirectiveAllowed(directive, addr) {
+			return TransmitResponseErr, nil, nil, d.denyTransmit(
+				directive,
+				addr,
+				messageID,
+				responseTo,
+				fmt.Sprintf("worker %v is not allowed to transmit to directive %v", directive, addr),
+			)
+		}
+		// The channel is buffered so that the client does not block sending a
+		// response that arrives after the timeout.
+		ch := make(chan yggdrasil.Response, 1)
 		d.Outbound <- struct {
 			Data yggdrasil.Data
 			Resp chan yggdrasil.Response
@@ -509,13 +764,63 @@ func (d *Dispatcher) Transmit(
 			responseCode = resp.Code
 			responseMetadata = resp.Metadata
 			responseData = resp.Data
-		case <-time.After(1 * time.Second):
-			return TransmitResponseErr, nil, nil, NewDBusError("com.redhat.Yggdrasil1.Dispatcher1.Transmit", "timeout reached waiting for response")
+		case <-time.After(config.DefaultConfig.TransmitTimeout):
+			return TransmitResponseErr, nil, nil, NewDBusError(ipc.ErrorNameTransmit, "timeout reached waiting for response")
 		}
 	}
 	return
 }
 
+// isRemoteContentWorker returns the value of the RemoteContent property of the
+// worker directive. Exec workers do not receive remote content.
+func (d *Dispatcher) isRemoteContentWorker(directive string) (bool, *dbus.Error) {
+	if _, isExec := d.execWorkers.Get(directive); isExec {
+		return false, nil
+	}
+	obj := d.conn.Object(
+		"com.redhat.Yggdrasil1.Worker1."+directive,
+		dbus.ObjectPath(filepath.Join("/com/redhat/Yggdrasil1/Worker1/", directive)),
+	)
+	r, err := obj.GetProperty("com.redhat.Yggdrasil1.Worker1.RemoteContent")
+	if err != nil {
+		return false, NewDBusError(
+			ipc.ErrorNameTransmit,
+			"cannot get property 'com.redhat.Yggdrasil1.Worker1.RemoteContent'",
+		)
+	}
+	remoteContent, _ := r.Value().(bool)
+	return remoteContent, nil
+}
+
+// sendData sends data to the server on behalf of the dispatcher itself, rather
+// than a worker. Like a worker's Transmit call, it waits up to TransmitTimeout
+// for the response of the server; the timeout also applies to handing data to
+// the client, so that a stalled transport cannot block the caller.
+func (d *Dispatcher) sendData(data yggdrasil.Data) (yggdrasil.Response, error) {
+	// The channel is buffered so that the client does not block sending a
+	// response that arrives after the timeout.
+	ch := make(chan yggdrasil.Response, 1)
+	timeout := time.After(config.DefaultConfig.TransmitTimeout)
+	select {
+	case d.Outbound <- struct {
+		Data yggdrasil.Data
+		Resp chan yggdrasil.Response
+	}{
+		Data: data,
+		Resp: ch,
+	}:
+	case <-timeout:
+		return yggdrasil.Response{}, fmt.Errorf("timeout reached waiting to send message %v", data.MessageID)
+	}
+
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-timeout:
+		return yggdrasil.Response{}, fmt.Errorf("timeout reached waiting for response to message %v", data.MessageID)
+	}
+}
+
 // senderName retrieves a list of names from the bus object, iterating over each
 // name, looking for a name owned by sender, returning the name if one is found.
 func (d *Dispatcher) senderName(sender dbus.Sender) (string, error) {
@@ -594,6 +899,93 @@ func reduceNamesPrefix(names []string, prefix string) []string {
 	return reducedNames
 }
 
+// reportDispatchError sends an event to the server informing it that data could
+// not be dispatched, if err is a DispatchError, and data has not finished
+// otherwise.
+func (d *Dispatcher) reportDispatchError(data yggdrasil.Data, err error) {
+	var dispatchErr *DispatchError
+	if errors.As(err, &dispatchErr) {
+		d.sendFinalEvent(data.MessageID, newDispatchErrorEvent(data, dispatchErr))
+	}
+}
+
+// retryDispatch dispatches data again, waiting longer between each attempt,
+// while its worker is temporarily unavailable. Failures are reported to the
+// server once the worker remains unavailable after maxUnavailableRetries
+// attempts.
+func (d *Dispatcher) retryDispatch(data yggdrasil.Data) {
+	var err error
+	delay := unavailableRetryDelay
+	for i := 0; i < maxUnavailableRetries; i++ {
+		time.Sleep(delay)
+		delay *= 2
+
+		err = d.Dispatch(data)
+		if err == nil {
+			return
+		}
+		if !isWorkerUnavailableError(err) {
+			break
+		}
+		log.Debugf("worker %v is still unavailable: %v", data.Directive, err)
+	}
+	log.Errorf("cannot dispatch data: %v", err)
+	d.reportDispatchError(data, err)
+}
+
+// isWorkerUnavailableError returns true if err is a DispatchError indicating
+// that the worker is temporarily unavailable.
+func isWorkerUnavailableError(err error) bool {
+	var dispatchErr *DispatchError
+	return errors.As(err, &dispatchErr) && dispatchErr.Code == ErrorCodeWorkerUnavailable
+}
+
+// isUnknownDirectiveError returns true if err is a DispatchError indicating
+// that no worker exists for the directive of a message.
+func isUnknownDirectiveError(err error) bool {
+	var dispatchErr *DispatchError
+	return errors.As(err, &dispatchErr) && dispatchErr.Code == ErrorCodeUnknownDirective
+}
+
+// isWorkerDrainingError returns true if err is the D-Bus error returned by a
+// worker that is shutting down.
+func isWorkerDrainingError(err error) bool {
+	var dbusErr dbus.Error
+	return errors.As(err, &dbusErr) && dbusErr.Name == ipc.ErrorNameWorkerDraining
+}
+
+// isNameUnknownError returns true if err is a D-Bus error indicating that the
+// destination name of a method call has no owner and cannot be activated.
+func isNameUnknownError(err error) bool {
+	var dbusErr dbus.Error
+	if !errors.As(err, &dbusErr) {
+		return false
+	}
+	switch dbusErr.Name {
+	case "org.freedesktop.DBus.Error.ServiceUnknown", "org.freedesktop.DBus.Error.NameHasNoOwner":
+		return true
+	}
+	return false
+}
+
+// newDispatchErrorEvent creates an event message informing the server that
+// data could not be dispatched to a worker.
+func newDispatchErrorEvent(data yggdrasil.Data, err *DispatchError) yggdrasil.Event {
+	return yggdrasil.Event{
+		Type:       yggdrasil.MessageTypeEvent,
+		MessageID:  uuid.New().String(),
+		ResponseTo: data.MessageID,
+		Version:    1,
+		Sent:       time.Now(),
+		Content:    string(yggdrasil.EventNameDispatchError),
+		Data: map[string]string{
+			"code":      string(err.Code),
+			"reason":    err.Error(),
+			"directive": data.Directive,
+		},
+	}
+}
+
 // workerEventFromSignal creates an ipc.WorkerEvent from a DBus signal.
 func workerEventFromSignal(s *dbus.Signal) (*ipc.WorkerEvent, error) {
 	event := ipc.WorkerEvent{}
@@ -631,7 +1023,16 @@ func workerEventFromSignal(s *dbus.Signal) (*ipc.WorkerEvent, error) {
 }
 
 // CancelMessage implements the dispatching of a cancel message to the worker.
+// The message is sent to the worker working on the message cancel_id if it is
+// in-flight. Otherwise, directive is resolved to a worker the same way Dispatch
+// resolves the directive of a message.
 func (d *Dispatcher) CancelMessage(directive, message_id, cancel_id string) error {
+	directive = d.cancelWorker(directive, cancel_id)
+
+	if _, has := d.execWorkers.Get(directive); has {
+		return d.cancelExec(directive, cancel_id)
+	}
+
 	// Send the message through the cancel interface
 	obj := d.conn.Object("com.redhat.Yggdrasil1.Worker1."+directive,
 		dbus.ObjectPath(filepath.Join("/com/redhat/Yggdrasil1/Worker1/", directive)))
@@ -651,3 +1052,18 @@ func (d *Dispatcher) CancelMessage(directive, message_id, cancel_id string) erro
 	d.Dispatchers <- d.FlattenDispatchers()
 	return nil
 }
+
+// cancelWorker returns the worker to send a message cancelling the message
+// cancelID to: the worker the message was dispatched to if it is in-flight,
+// otherwise the worker that handles directive.
+func (d *Dispatcher) cancelWorker(directive string, cancelID string) string {
+	if msg, has := d.inflight.Get(cancelID); has {
+		return msg.data.Directive
+	}
+
+	worker, err := ScrubName(resolveDirective(config.DefaultConfig.Routes, directive))
+	if err != nil {
+		log.Debug(err)
+	}
+	return worker
+}
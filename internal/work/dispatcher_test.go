@@ -1,11 +1,15 @@
 package work
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/godbus/dbus/v5"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/redhatinsights/yggdrasil"
+	"github.com/redhatinsights/yggdrasil/internal/config"
 	"github.com/redhatinsights/yggdrasil/ipc"
 )
 
@@ -136,3 +140,188 @@ func TestWorkerEventFromSignal(t *testing.T) {
 		})
 	}
 }
+
+func TestIsNameUnknownError(t *testing.T) {
+	tests := []struct {
+		description string
+		input       error
+		want        bool
+	}{
+		{
+			description: "service unknown",
+			input: *dbus.NewError(
+				"org.freedesktop.DBus.Error.ServiceUnknown",
+				[]interface{}{"The name is not activatable"},
+			),
+			want: true,
+		},
+		{
+			description: "name has no owner",
+			input: fmt.Errorf(
+				"cannot get property: %w",
+				*dbus.NewError("org.freedesktop.DBus.Error.NameHasNoOwner", nil),
+			),
+			want: true,
+		},
+		{
+			description: "other D-Bus error",
+			input:       *dbus.NewError("org.freedesktop.DBus.Error.AccessDenied", nil),
+			want:        false,
+		},
+		{
+			description: "not a D-Bus error",
+			input:       fmt.Errorf("cannot get property"),
+			want:        false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			got := isNameUnknownError(test.input)
+
+			if !cmp.Equal(got, test.want) {
+				t.Errorf("%v", cmp.Diff(got, test.want))
+			}
+		})
+	}
+}
+
+func TestNewDispatchErrorEvent(t *testing.T) {
+	tests := []struct {
+		description string
+		data        yggdrasil.Data
+		err         *DispatchError
+		want        yggdrasil.Event
+	}{
+		{
+			description: "unknown directive",
+			data: yggdrasil.Data{
+				MessageID: "6925055f-167a-45cc-9869-1789ee37883f",
+				Directive: "echo",
+			},
+			err: newDispatchError(ErrorCodeUnknownDirective, fmt.Errorf("no such worker")),
+			want: yggdrasil.Event{
+				Type:       yggdrasil.MessageTypeEvent,
+				ResponseTo: "6925055f-167a-45cc-9869-1789ee37883f",
+				Version:    1,
+				Content:    string(yggdrasil.EventNameDispatchError),
+				Data: map[string]string{
+					"code":      "unknown-directive",
+					"reason":    "no such worker",
+					"directive": "echo",
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			got := newDispatchErrorEvent(test.data, test.err)
+
+			if !cmp.Equal(got, test.want, cmpopts.IgnoreFields(yggdrasil.Event{}, "MessageID", "Sent")) {
+				t.Errorf(
+					"%v",
+					cmp.Diff(got, test.want, cmpopts.IgnoreFields(yggdrasil.Event{}, "MessageID", "Sent")),
+				)
+			}
+		})
+	}
+}
+
+func TestIsWorkerDrainingError(t *testing.T) {
+	tests := []struct {
+		description string
+		input       error
+		want        bool
+	}{
+		{
+			description: "draining",
+			input: fmt.Errorf(
+				"cannot call method: %w",
+				*dbus.NewError("com.redhat.Yggdrasil1.Worker1.Draining", nil),
+			),
+			want: true,
+		},
+		{
+			description: "other D-Bus error",
+			input:       *dbus.NewError("org.freedesktop.DBus.Error.AccessDenied", nil),
+			want:        false,
+		},
+		{
+			description: "not a D-Bus error",
+			input:       fmt.Errorf("cannot call method"),
+			want:        false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			got := isWorkerDrainingError(test.input)
+
+			if !cmp.Equal(got, test.want) {
+				t.Errorf("%v", cmp.Diff(got, test.want))
+			}
+		})
+	}
+}
+
+func TestSendData(t *testing.T) {
+	config.DefaultConfig.TransmitTimeout = 50 * time.Millisecond
+	defer func() {
+		config.DefaultConfig.TransmitTimeout = 0
+	}()
+
+	tests := []struct {
+		description string
+		// receive is true if the client receives the message, and respond
+		// is true if it responds to it.
+		receive   bool
+		respond   bool
+		want      yggdrasil.Response
+		wantError bool
+	}{
+		{
+			description: "response",
+			receive:     true,
+			respond:     true,
+			want:        yggdrasil.Response{Code: 202},
+		},
+		{
+			description: "stalled transport",
+			wantError:   true,
+		},
+		{
+			description: "no response",
+			receive:     true,
+			wantError:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			d := NewDispatcher(nil)
+			if test.receive {
+				go func() {
+					msg := <-d.Outbound
+					if test.respond {
+						msg.Resp <- yggdrasil.Response{Code: 202}
+					}
+				}()
+			}
+
+			got, err := d.sendData(yggdrasil.Data{MessageID: "1"})
+			if test.wantError {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got, test.want) {
+				t.Errorf("%v", cmp.Diff(got, test.want))
+			}
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package work
 
-import "reflect"
+import (
+	"errors"
+	"reflect"
+)
 
 // typeConversionError represents a conversion error when converting one type
 // to  another.
@@ -33,3 +36,111 @@ func newStringMapTypeConversionError(parameter interface{}) typeConversionError
 		t: reflect.TypeOf(map[string]string{}),
 	}
 }
+
+// ErrorCode is a machine-readable value describing why the dispatcher could not
+// handle a message. Error codes are reported to the server in event messages.
+type ErrorCode string
+
+const (
+	// ErrorCodeUnknownDirective indicates that no worker exists for the
+	// directive of a message.
+	ErrorCodeUnknownDirective ErrorCode = "unknown-directive"
+
+	// ErrorCodeContentUnavailable indicates that the detached content of a
+	// message could not be retrieved.
+	ErrorCodeContentUnavailable ErrorCode = "content-unavailable"
+
+	// ErrorCodeDispatchFailed indicates that calling the worker's Dispatch
+	// method failed.
+	ErrorCodeDispatchFailed ErrorCode = "dispatch-failed"
+
+	// ErrorCodeWorkerExited indicates that the worker exited before it
+	// finished working on a message.
+	ErrorCodeWorkerExited ErrorCode = "worker-exited"
+
+	// ErrorCodeWorkerUnavailable indicates that the worker is temporarily
+	// unable to accept messages, such as when it is shutting down.
+	ErrorCodeWorkerUnavailable ErrorCode = "worker-unavailable"
+
+	// ErrorCodeInvalidPipeline indicates that the pipeline descriptor of a
+	// message could not be parsed.
+	ErrorCodeInvalidPipeline ErrorCode = "invalid-pipeline"
+
+	// ErrorCodeInvalidSchedule indicates that the not_before or not_after
+	// metadata value of a message could not be parsed.
+	ErrorCodeInvalidSchedule ErrorCode = "invalid-schedule"
+
+	// ErrorCodeExpired indicates that a message was not dispatched because
+	// its not_after time passed.
+	ErrorCodeExpired ErrorCode = "expired"
+
+	// ErrorCodeCancelled indicates that a scheduled message was cancelled
+	// before it was dispatched, or that the command of an exec worker was
+	// terminated because its message was cancelled.
+	ErrorCodeCancelled ErrorCode = "cancelled"
+
+	// ErrorCodeInvalidSelector indicates that the selector of a broadcast
+	// message could not be parsed.
+	ErrorCodeInvalidSelector ErrorCode = "invalid-selector"
+
+	// ErrorCodeInvalidDigest indicates that the content_digest metadata value
+	// of a message could not be parsed.
+	ErrorCodeInvalidDigest ErrorCode = "invalid-digest"
+
+	// ErrorCodeContentMismatch indicates that the detached content of a
+	// message did not match its content_digest metadata value.
+	ErrorCodeContentMismatch ErrorCode = "content-mismatch"
+
+	// ErrorCodeContentDenied indicates that the detached content of a message
+	// is located somewhere the content-sources allow-list does not permit
+	// fetching content from.
+	ErrorCodeContentDenied ErrorCode = "content-denied"
+
+	// ErrorCodeInvalidContent indicates that the content or metadata of a
+	// message is not accepted by the manifest of its worker.
+	ErrorCodeInvalidContent ErrorCode = "invalid-content"
+
+	// ErrorCodeExitStatus indicates that the command of an exec worker exited
+	// with a non-zero status.
+	ErrorCodeExitStatus ErrorCode = "exit-status"
+
+	// ErrorCodeTransmitFailed indicates that the output of an exec worker's
+	// command could not be transmitted.
+	ErrorCodeTransmitFailed ErrorCode = "transmit-failed"
+
+	// ErrorCodeOutputTooLarge indicates that the command of an exec worker
+	// wrote more than the maximum number of bytes to its standard output.
+	ErrorCodeOutputTooLarge ErrorCode = "output-too-large"
+)
+
+// DispatchError is returned by Dispatcher.Dispatch when a message cannot be
+// delivered to a worker.
+type DispatchError struct {
+	Code ErrorCode
+	err  error
+}
+
+func (e *DispatchError) Error() string {
+	return e.err.Error()
+}
+
+func (e *DispatchError) Unwrap() error {
+	return e.err
+}
+
+func newDispatchError(code ErrorCode, err error) *DispatchError {
+	return &DispatchError{
+		Code: code,
+		err:  err,
+	}
+}
+
+// dispatchErrorCode returns the code of err if it is a DispatchError, or
+// ErrorCodeDispatchFailed otherwise.
+func dispatchErrorCode(err error) ErrorCode {
+	var dispatchErr *DispatchError
+	if errors.As(err, &dispatchErr) {
+		return dispatchErr.Code
+	}
+	return ErrorCodeDispatchFailed
+}
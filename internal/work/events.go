@@ -0,0 +1,72 @@
+package work
+
+import (
+	"sync"
+
+	"github.com/redhatinsights/yggdrasil"
+)
+
+// eventQueue holds event messages waiting to be sent on the dispatcher's
+// OutboundEvents channel. Events are queued rather than sent on the channel
+// directly so that a slow transport does not block the dispatcher, such as
+// its handling of worker signals.
+type eventQueue struct {
+	mu     sync.Mutex
+	events []yggdrasil.Event
+	ready  chan struct{}
+	start  sync.Once
+}
+
+// sendEvent queues event to be sent to the server. A progress event replaces
+// a progress event of the same message that has not been sent yet, so that
+// only the latest progress of a message is sent once the transport catches
+// up.
+func (d *Dispatcher) sendEvent(event yggdrasil.Event) {
+	q := &d.events
+	q.start.Do(func() {
+		go d.forwardEvents()
+	})
+
+	q.mu.Lock()
+	queued := false
+	if event.Content == string(yggdrasil.EventNameProgress) {
+		for i, e := range q.events {
+			if e.Content == event.Content && e.ResponseTo == event.ResponseTo {
+				q.events[i] = event
+				queued = true
+				break
+			}
+		}
+	}
+	if !queued {
+		q.events = append(q.events, event)
+	}
+	q.mu.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+		// The forwarding goroutine has not yet picked up earlier events; it
+		// picks up this event along with them.
+	}
+}
+
+// forwardEvents sends queued events on the OutboundEvents channel, in the order
+// they were queued.
+func (d *Dispatcher) forwardEvents() {
+	q := &d.events
+	for range q.ready {
+		for {
+			q.mu.Lock()
+			if len(q.events) == 0 {
+				q.mu.Unlock()
+				break
+			}
+			event := q.events[0]
+			q.events = q.events[1:]
+			q.mu.Unlock()
+
+			d.OutboundEvents <- event
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package work
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/redhatinsights/yggdrasil"
+)
+
+func TestSendEvent(t *testing.T) {
+	d := NewDispatcher(nil)
+	// Queue the events before the forwarding goroutine runs.
+	d.events.start.Do(func() {})
+
+	events := []yggdrasil.Event{
+		{MessageID: "1", ResponseTo: "a", Content: string(yggdrasil.EventNameProgress)},
+		{MessageID: "2", ResponseTo: "b", Content: string(yggdrasil.EventNameFailed)},
+		{MessageID: "3", ResponseTo: "b", Content: string(yggdrasil.EventNameProgress)},
+		{MessageID: "4", ResponseTo: "a", Content: string(yggdrasil.EventNameProgress)},
+		{MessageID: "5", ResponseTo: "a", Content: string(yggdrasil.EventNameFailed)},
+	}
+	for _, event := range events {
+		d.sendEvent(event)
+	}
+	go d.forwardEvents()
+
+	want := []string{"4", "2", "3", "5"}
+	var got []string
+	for len(got) < len(want) {
+		select {
+		case event := <-d.OutboundEvents:
+			got = append(got, event.MessageID)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("missing events after %v", got)
+		}
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("%v", cmp.Diff(got, want))
+	}
+}
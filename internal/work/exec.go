@@ -0,0 +1,329 @@
+package work
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redhatinsights/yggdrasil"
+	"github.com/redhatinsights/yggdrasil/internal/config"
+	"github.com/redhatinsights/yggdrasil/ipc"
+	"github.com/subpop/go-log"
+)
+
+// Environment variables describing the message an exec worker's command is
+// run for. Each metadata value of the message is set in a variable named
+// after its key, prefixed with ExecEnvMetadataPrefix, upper-cased, and with
+// characters other than letters, digits and underscores replaced by
+// underscores.
+const (
+	ExecEnvDirective      = "YGG_DIRECTIVE"
+	ExecEnvMessageID      = "YGG_MESSAGE_ID"
+	ExecEnvResponseTo     = "YGG_RESPONSE_TO"
+	ExecEnvMetadataPrefix = "YGG_METADATA_"
+)
+
+// execDefaultPath is the PATH an exec worker's command is run with, unless its
+// environment sets one.
+const execDefaultPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// execKillDelay is the duration a cancelled exec worker's command is given to
+// exit after receiving SIGTERM before it is sent SIGKILL.
+var execKillDelay = 10 * time.Second
+
+// maxExecOutput is the number of bytes an exec worker's command may write to
+// its standard output. The output is held in memory until the command exits,
+// and a command that writes more fails.
+var maxExecOutput = 16 * 1024 * 1024
+
+// maxExecStderr is the number of bytes at the end of the standard error of an
+// exec worker's command included in the FAILED event emitted when it fails.
+const maxExecStderr = 1024
+
+// StartExecWorkers registers the given exec workers, keyed by directive.
+// Messages dispatched to an exec worker are handled by running its command
+// rather than by calling a worker on the bus.
+func (d *Dispatcher) StartExecWorkers(workers map[string]config.ExecWorkerConfig) {
+	for directive, cfg := range workers {
+		d.execWorkers.Set(directive, cfg)
+		d.features.Set(directive, map[string]string{})
+		d.manifests.Set(directive, compileManifest(ipc.WorkerManifest{Cancellable: true}))
+		log.Infof("registered exec worker %v running %v", directive, cfg.Command)
+	}
+}
+
+// dispatchExec runs the command of the exec worker cfg for data. The content
+// of data is written to the standard input of the command, and its metadata
+// set in the environment. The command runs in the background: if it exits with
+// status 0, its standard output is transmitted in response to data and an END
+// event is emitted on behalf of the worker, otherwise a FAILED event is
+// emitted.
+func (d *Dispatcher) dispatchExec(data yggdrasil.Data, cfg config.ExecWorkerConfig, attempt int) error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cmd := exec.CommandContext(ctx, cfg.Command, cfg.Args...)
+	cmd.Dir = "/"
+	cmd.Env = execEnvironment(cfg, data)
+	cmd.Stdin = bytes.NewReader(data.Content)
+	stdout := &limitBuffer{max: maxExecOutput}
+	stderr := &tailBuffer{max: maxExecStderr}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	// Run the command in its own process group so that cancelling the message
+	// signals any processes the command started as well.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if cfg.User != "" {
+		credential, err := lookupCredential(cfg.User)
+		if err != nil {
+			cancel()
+			return newDispatchError(ErrorCodeDispatchFailed, err)
+		}
+		cmd.SysProcAttr.Credential = credential
+	}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+	}
+	cmd.WaitDelay = execKillDelay
+
+	d.execs.Set(data.MessageID, cancel)
+
+	// Track the message before starting the command, as the command may exit
+	// before Start returns.
+	d.trackMessage(data, attempt)
+
+	if err := cmd.Start(); err != nil {
+		d.execs.Del(data.MessageID)
+		d.untrackMessage(data.MessageID)
+		cancel()
+		return newDispatchError(ErrorCodeDispatchFailed, fmt.Errorf(
+			"cannot start command %v of worker %v: %w",
+			cfg.Command,
+			data.Directive,
+			err,
+		))
+	}
+	log.Debugf("started command %v of worker %v for message %v", cfg.Command, data.Directive, data.MessageID)
+
+	go func() {
+		d.emitWorkerEvent(ipc.WorkerEvent{
+			Worker:     data.Directive,
+			Name:       ipc.WorkerEventNameBegin,
+			MessageID:  data.MessageID,
+			ResponseTo: data.ResponseTo,
+		})
+
+		err := cmd.Wait()
+		// The context is only cancelled before the command exits if the
+		// message is cancelled.
+		cancelled := ctx.Err() != nil
+		d.execs.Del(data.MessageID)
+		cancel()
+
+		event := ipc.WorkerEvent{
+			Worker:     data.Directive,
+			Name:       ipc.WorkerEventNameEnd,
+			MessageID:  data.MessageID,
+			ResponseTo: data.ResponseTo,
+		}
+		code, reason := execResult(err, cancelled, stderr.String())
+		if stdout.exceeded && !cancelled {
+			code = ErrorCodeOutputTooLarge
+			reason = fmt.Sprintf("command wrote more than %v bytes to its standard output", stdout.max)
+		}
+		if code != "" {
+			log.Warnf("command %v of worker %v failed for message %v: %v", cfg.Command, data.Directive, data.MessageID, reason)
+			event.Name = ipc.WorkerEventNameFailed
+			event.Data = map[string]string{
+				ipc.WorkerEventDataKeyCode:    string(code),
+				ipc.WorkerEventDataKeyMessage: reason,
+			}
+		} else if len(stdout.data) > 0 {
+			if err := d.transmitExecOutput(data, stdout.data); err != nil {
+				log.Errorf("cannot transmit output of worker %v for message %v: %v", data.Directive, data.MessageID, err)
+				event.Name = ipc.WorkerEventNameFailed
+				event.Data = map[string]string{
+					ipc.WorkerEventDataKeyCode:    string(ErrorCodeTransmitFailed),
+					ipc.WorkerEventDataKeyMessage: err.Error(),
+				}
+			}
+		}
+		d.handleWorkerEvent(&event)
+	}()
+
+	return nil
+}
+
+// execResult returns the error code and reason of the failure of an exec
+// worker's command, which exited with err after writing stderr to its standard
+// error, or an empty code if it succeeded.
+func execResult(err error, cancelled bool, stderr string) (ErrorCode, string) {
+	if err == nil {
+		return "", ""
+	}
+	if cancelled {
+		return ErrorCodeCancelled, "command was cancelled"
+	}
+
+	reason := err.Error()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		reason = fmt.Sprintf("command %v", exitErr.ProcessState)
+	}
+	if stderr = strings.TrimSpace(stderr); stderr != "" {
+		reason += ": " + stderr
+	}
+	return ErrorCodeExitStatus, reason
+}
+
+// transmitExecOutput transmits the standard output of an exec worker's command
+// in response to data, as a D-Bus worker would by calling Transmit with the
+// directive of data as its address.
+func (d *Dispatcher) transmitExecOutput(data yggdrasil.Data, output []byte) error {
+	code, _, _, dbusErr := d.transmit(
+		data.Directive,
+		data.Directive,
+		uuid.New().String(),
+		data.MessageID,
+		map[string]string{},
+		output,
+	)
+	if dbusErr != nil {
+		return dbusErr
+	}
+	if code != TransmitResponseOK {
+		log.Warnf("unexpected response code %v transmitting output of worker %v", code, data.Directive)
+	}
+	return nil
+}
+
+// cancelExec terminates the command run by an exec worker for the message with
+// the given ID.
+func (d *Dispatcher) cancelExec(directive string, cancelID string) error {
+	cancel, has := d.execs.Get(cancelID)
+	if !has {
+		return fmt.Errorf("worker %v is not working on message %v", directive, cancelID)
+	}
+	cancel()
+	log.Debugf("cancelled command of worker %v for message %v", directive, cancelID)
+	return nil
+}
+
+// execEnvironment returns the environment the command of the exec worker cfg
+// is run with for data.
+func execEnvironment(cfg config.ExecWorkerConfig, data yggdrasil.Data) []string {
+	env := map[string]string{"PATH": execDefaultPath}
+	for k, v := range cfg.Environment {
+		env[k] = v
+	}
+	for k, v := range data.Metadata {
+		env[execMetadataVariable(k)] = v
+	}
+	env[ExecEnvDirective] = data.Directive
+	env[ExecEnvMessageID] = data.MessageID
+	env[ExecEnvResponseTo] = data.ResponseTo
+
+	environ := make([]string, 0, len(env))
+	for k, v := range env {
+		environ = append(environ, k+"="+v)
+	}
+	return environ
+}
+
+// execMetadataVariable returns the name of the environment variable the
+// metadata value with the given key is set in.
+func execMetadataVariable(key string) string {
+	return ExecEnvMetadataPrefix + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+}
+
+// lookupCredential returns the credential of the user with the given name,
+// including the groups the user is a member of.
+func lookupCredential(name string) (*syscall.Credential, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		return nil, fmt.Errorf("cannot look up user %v: %w", name, err)
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse UID of user %v: %w", name, err)
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse GID of user %v: %w", name, err)
+	}
+
+	credential := &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}
+	groups, err := u.GroupIds()
+	if err != nil {
+		return nil, fmt.Errorf("cannot look up groups of user %v: %w", name, err)
+	}
+	for _, group := range groups {
+		id, err := strconv.ParseUint(group, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse group ID of user %v: %w", name, err)
+		}
+		credential.Groups = append(credential.Groups, uint32(id))
+	}
+	// Only change the supplementary groups when running as root, as changing
+	// them requires privileges even if they would not change.
+	if os.Geteuid() != 0 {
+		credential.NoSetGroups = true
+	}
+	return credential, nil
+}
+
+// errOutputTooLarge is returned by limitBuffer when a write exceeds its size.
+var errOutputTooLarge = errors.New("output too large")
+
+// limitBuffer is an io.Writer that holds at most max bytes written to it. A
+// write that would exceed max fails with errOutputTooLarge and sets exceeded.
+type limitBuffer struct {
+	max      int
+	data     []byte
+	exceeded bool
+}
+
+func (b *limitBuffer) Write(p []byte) (int, error) {
+	if len(b.data)+len(p) > b.max {
+		b.exceeded = true
+		return 0, errOutputTooLarge
+	}
+	b.data = append(b.data, p...)
+	return len(p), nil
+}
+
+// tailBuffer is an io.Writer that keeps the last max bytes written to it.
+type tailBuffer struct {
+	max  int
+	data []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.data = append(b.data, p...)
+	if len(b.data) > b.max {
+		b.data = b.data[len(b.data)-b.max:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	return string(b.data)
+}
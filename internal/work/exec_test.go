@@ -0,0 +1,198 @@
+package work
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/redhatinsights/yggdrasil"
+	"github.com/redhatinsights/yggdrasil/internal/config"
+	"github.com/redhatinsights/yggdrasil/ipc"
+)
+
+func TestExecEnvironment(t *testing.T) {
+	cfg := config.ExecWorkerConfig{
+		Environment: map[string]string{"LANG": "C.UTF-8", "YGG_MESSAGE_ID": "overridden"},
+	}
+	data := yggdrasil.Data{
+		Directive:  "echo",
+		MessageID:  "1",
+		ResponseTo: "0",
+		Metadata:   map[string]string{"content_type": "text/plain", "x-retries": "3"},
+	}
+
+	got := execEnvironment(cfg, data)
+	sort.Strings(got)
+	want := []string{
+		"LANG=C.UTF-8",
+		"PATH=" + execDefaultPath,
+		"YGG_DIRECTIVE=echo",
+		"YGG_MESSAGE_ID=1",
+		"YGG_METADATA_CONTENT_TYPE=text/plain",
+		"YGG_METADATA_X_RETRIES=3",
+		"YGG_RESPONSE_TO=0",
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("%v", cmp.Diff(got, want))
+	}
+}
+
+func TestDispatchExec(t *testing.T) {
+	execKillDelay = time.Second
+	maxExecOutput = 1024
+	config.DefaultConfig.TransmitTimeout = time.Second
+	defer func() {
+		maxExecOutput = 16 * 1024 * 1024
+		config.DefaultConfig.TransmitTimeout = 0
+	}()
+
+	tests := []struct {
+		description string
+		script      string
+		content     string
+		cancel      bool
+		want        ipc.WorkerEvent
+		wantOutput  string
+		wantError   ErrorCode
+	}{
+		{
+			description: "output",
+			script:      `echo "$YGG_MESSAGE_ID: $(cat)"`,
+			content:     "hello",
+			want:        ipc.WorkerEvent{Worker: "test", Name: ipc.WorkerEventNameEnd, MessageID: "1"},
+			wantOutput:  "1: hello\n",
+		},
+		{
+			description: "no output",
+			script:      `true`,
+			want:        ipc.WorkerEvent{Worker: "test", Name: ipc.WorkerEventNameEnd, MessageID: "1"},
+		},
+		{
+			description: "exit status",
+			script:      `echo ignored; echo failure >&2; exit 3`,
+			want: ipc.WorkerEvent{
+				Worker:    "test",
+				Name:      ipc.WorkerEventNameFailed,
+				MessageID: "1",
+				Data: map[string]string{
+					ipc.WorkerEventDataKeyCode:    string(ErrorCodeExitStatus),
+					ipc.WorkerEventDataKeyMessage: "command exit status 3: failure",
+				},
+			},
+		},
+		{
+			description: "output too large",
+			script:      `head -c 2048 /dev/zero`,
+			want: ipc.WorkerEvent{
+				Worker:    "test",
+				Name:      ipc.WorkerEventNameFailed,
+				MessageID: "1",
+				Data: map[string]string{
+					ipc.WorkerEventDataKeyCode:    string(ErrorCodeOutputTooLarge),
+					ipc.WorkerEventDataKeyMessage: "command wrote more than 1024 bytes to its standard output",
+				},
+			},
+		},
+		{
+			description: "cancelled",
+			script:      `sleep 60`,
+			cancel:      true,
+			want: ipc.WorkerEvent{
+				Worker:    "test",
+				Name:      ipc.WorkerEventNameFailed,
+				MessageID: "1",
+				Data: map[string]string{
+					ipc.WorkerEventDataKeyCode:    string(ErrorCodeCancelled),
+					ipc.WorkerEventDataKeyMessage: "command was cancelled",
+				},
+			},
+		},
+		{
+			description: "missing command",
+			wantError:   ErrorCodeDispatchFailed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			d := NewDispatcher(nil)
+			cfg := config.ExecWorkerConfig{
+				Directive: "test",
+				Command:   "/bin/sh",
+				Args:      []string{"-c", test.script},
+			}
+			if test.wantError != "" {
+				cfg = config.ExecWorkerConfig{Directive: "test", Command: "/nonexistent"}
+			}
+			d.StartExecWorkers(map[string]config.ExecWorkerConfig{"test": cfg})
+
+			outputs := make(chan string, 1)
+			go func() {
+				for msg := range d.Outbound {
+					outputs <- string(msg.Data.Content)
+					msg.Resp <- yggdrasil.Response{}
+				}
+			}()
+
+			err := d.dispatch(yggdrasil.Data{
+				Directive: "test",
+				MessageID: "1",
+				Content:   []byte(test.content),
+			}, 0)
+			if test.wantError != "" {
+				var dispatchErr *DispatchError
+				if !errors.As(err, &dispatchErr) {
+					t.Fatalf("expected DispatchError, got %v", err)
+				}
+				if dispatchErr.Code != test.wantError {
+					t.Errorf("%v != %v", dispatchErr.Code, test.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if begin := <-d.WorkerEvents; begin.Name != ipc.WorkerEventNameBegin {
+				t.Fatalf("expected BEGIN event, got %v", begin.Name)
+			}
+			if test.cancel {
+				if err := d.CancelMessage("test", "2", "1"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			select {
+			case got := <-d.WorkerEvents:
+				if !cmp.Equal(got, test.want) {
+					t.Errorf("%v", cmp.Diff(got, test.want))
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("command did not finish")
+			}
+			if test.want.Name == ipc.WorkerEventNameFailed {
+				select {
+				case event := <-d.OutboundEvents:
+					if event.Data["code"] != test.want.Data[ipc.WorkerEventDataKeyCode] {
+						t.Errorf("%v != %v", event.Data["code"], test.want.Data[ipc.WorkerEventDataKeyCode])
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatal("failure was not reported to the server")
+				}
+			}
+			var output string
+			select {
+			case output = <-outputs:
+			default:
+			}
+			if output != test.wantOutput {
+				t.Errorf("%#v != %#v", output, test.wantOutput)
+			}
+			if _, has := d.inflight.Get("1"); has {
+				t.Errorf("message is still in-flight")
+			}
+		})
+	}
+}
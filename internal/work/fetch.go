@@ -0,0 +1,111 @@
+package work
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/redhatinsights/yggdrasil/internal/config"
+)
+
+// Fetcher retrieves the detached content located at URL. The caller must close
+// the returned reader.
+type Fetcher func(URL *url.URL) (io.ReadCloser, error)
+
+// defaultFetchers are the fetchers of the URL schemes other than http and https
+// that detached content can be fetched from without registering a fetcher.
+var defaultFetchers = map[string]Fetcher{
+	"file": fetchFile,
+	"data": fetchData,
+}
+
+// RegisterFetcher registers fetcher to retrieve detached content from URLs
+// with the given scheme, replacing any fetcher already registered for it.
+// Content is only fetched from locations permitted by the content-sources
+// allow-list. The http and https schemes are always fetched with the
+// dispatcher's HTTP client, and cannot be registered.
+func (d *Dispatcher) RegisterFetcher(scheme string, fetcher Fetcher) error {
+	scheme = strings.ToLower(scheme)
+	if isHTTPScheme(scheme) {
+		return fmt.Errorf("cannot register fetcher: scheme '%v' is reserved", scheme)
+	}
+	d.fetchers.Set(scheme, fetcher)
+	return nil
+}
+
+// fetcher returns the fetcher registered for scheme, or the default fetcher of
+// the scheme if none is registered.
+func (d *Dispatcher) fetcher(scheme string) (Fetcher, bool) {
+	if fetcher, has := d.fetchers.Get(scheme); has {
+		return fetcher, true
+	}
+	fetcher, has := defaultFetchers[scheme]
+	return fetcher, has
+}
+
+// isHTTPScheme returns true if scheme is fetched with the HTTP client.
+func isHTTPScheme(scheme string) bool {
+	return scheme == "http" || scheme == "https"
+}
+
+// contentSourceAllowed returns true if the content-sources allow-list permits
+// fetching detached content from URL. The host of the URL is matched against
+// the patterns of its scheme as by path.Match, except for file URLs, whose
+// cleaned path is matched instead.
+func contentSourceAllowed(URL *url.URL) bool {
+	sources := config.DefaultConfig.ContentSources
+	if sources == nil {
+		sources = config.DefaultContentSources
+	}
+	patterns, has := sources[URL.Scheme]
+	if !has {
+		return false
+	}
+
+	value := URL.Hostname()
+	if URL.Scheme == "file" {
+		value = path.Clean(URL.Path)
+	}
+	return policyAllows(patterns, value)
+}
+
+// fetchFile opens the local file at URL, such as content pre-staged on local
+// media.
+func fetchFile(URL *url.URL) (io.ReadCloser, error) {
+	if URL.Host != "" && URL.Host != "localhost" {
+		return nil, fmt.Errorf("cannot open file URL '%v': remote host", URL)
+	}
+	f, err := os.Open(path.Clean(URL.Path))
+	if err != nil {
+		return nil, fmt.Errorf("cannot open file: %w", err)
+	}
+	return f, nil
+}
+
+// fetchData decodes the content inlined in the data URL URL, as defined by
+// RFC 2397.
+func fetchData(URL *url.URL) (io.ReadCloser, error) {
+	mediaType, data, ok := strings.Cut(URL.Opaque, ",")
+	if !ok {
+		return nil, fmt.Errorf("cannot parse data URL: missing ','")
+	}
+
+	content, err := url.PathUnescape(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse data URL: %w", err)
+	}
+	if strings.HasSuffix(mediaType, ";base64") {
+		decoded, err := base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode data URL: %w", err)
+		}
+		content = string(decoded)
+	}
+
+	return io.NopCloser(bytes.NewReader([]byte(content))), nil
+}
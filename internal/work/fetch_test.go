@@ -0,0 +1,300 @@
+package work
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/redhatinsights/yggdrasil/internal/config"
+)
+
+func TestContentSourceAllowed(t *testing.T) {
+	tests := []struct {
+		description string
+		sources     config.ContentSourceConfig
+		input       string
+		want        bool
+	}{
+		{
+			description: "default - https",
+			input:       "https://example.com/content",
+			want:        true,
+		},
+		{
+			description: "default - data",
+			input:       "data:,hello",
+			want:        true,
+		},
+		{
+			description: "default - file",
+			input:       "file:///etc/shadow",
+			want:        false,
+		},
+		{
+			description: "allowed host",
+			sources:     config.ContentSourceConfig{"https": {"*.example.com"}},
+			input:       "https://cdn.example.com:8443/content",
+			want:        true,
+		},
+		{
+			description: "denied host",
+			sources:     config.ContentSourceConfig{"https": {"*.example.com"}},
+			input:       "https://169.254.169.254/latest/meta-data",
+			want:        false,
+		},
+		{
+			description: "denied scheme",
+			sources:     config.ContentSourceConfig{"https": {"*.example.com"}},
+			input:       "http://cdn.example.com/content",
+			want:        false,
+		},
+		{
+			description: "allowed path",
+			sources:     config.ContentSourceConfig{"file": {"/run/media/*/*"}},
+			input:       "file:///run/media/usb/content.json",
+			want:        true,
+		},
+		{
+			description: "denied path",
+			sources:     config.ContentSourceConfig{"file": {"/run/media/*/*"}},
+			input:       "file:///run/media/usb/../../../etc/shadow",
+			want:        false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			config.DefaultConfig.ContentSources = test.sources
+			defer func() {
+				config.DefaultConfig.ContentSources = nil
+			}()
+
+			got := contentSourceAllowed(mustParseURL(t, test.input))
+			if got != test.want {
+				t.Errorf("%v != %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFetchData(t *testing.T) {
+	tests := []struct {
+		description string
+		input       string
+		want        string
+		wantError   bool
+	}{
+		{
+			description: "plain",
+			input:       "data:,hello%20world",
+			want:        "hello world",
+		},
+		{
+			description: "media type",
+			input:       "data:text/plain;charset=utf-8,hello",
+			want:        "hello",
+		},
+		{
+			description: "base64",
+			input:       "data:application/json;base64,eyJoZWxsbyI6IndvcmxkIn0=",
+			want:        `{"hello":"world"}`,
+		},
+		{
+			description: "invalid base64",
+			input:       "data:;base64,!!!",
+			wantError:   true,
+		},
+		{
+			description: "missing data",
+			input:       "data:text/plain",
+			wantError:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			r, err := fetchData(mustParseURL(t, test.input))
+			if test.wantError {
+				if err == nil {
+					t.Errorf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(string(got), test.want) {
+				t.Errorf("%#v != %#v", string(got), test.want)
+			}
+		})
+	}
+}
+
+func TestFetchContentFetchers(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "content"), []byte("from file"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		description string
+		sources     config.ContentSourceConfig
+		dataHost    string
+		input       string
+		want        string
+		wantError   ErrorCode
+	}{
+		{
+			description: "file",
+			sources:     config.ContentSourceConfig{"file": {dir + "/*"}},
+			input:       "file://" + dir + "/content",
+			want:        "from file",
+		},
+		{
+			description: "data",
+			sources:     config.ContentSourceConfig{"data": {"*"}},
+			input:       "data:,from%20data",
+			want:        "from data",
+		},
+		{
+			description: "registered",
+			sources:     config.ContentSourceConfig{"test": {"host"}},
+			input:       "test://host/content",
+			want:        "from test",
+		},
+		{
+			description: "denied",
+			sources:     config.ContentSourceConfig{"data": {"*"}},
+			input:       "file://" + dir + "/content",
+			wantError:   ErrorCodeContentDenied,
+		},
+		{
+			description: "denied data host",
+			sources:     config.ContentSourceConfig{"https": {"example.com"}},
+			dataHost:    "internal.example.net",
+			input:       "https://example.com/content",
+			wantError:   ErrorCodeContentDenied,
+		},
+		{
+			description: "unsupported scheme",
+			sources:     config.ContentSourceConfig{"ftp": {"*"}},
+			input:       "ftp://example.com/content",
+			wantError:   ErrorCodeContentUnavailable,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			config.DefaultConfig.ContentSources = test.sources
+			config.DefaultConfig.DataHost = test.dataHost
+			defer func() {
+				config.DefaultConfig.ContentSources = nil
+				config.DefaultConfig.DataHost = ""
+			}()
+
+			d := &Dispatcher{ContentDir: t.TempDir()}
+			err := d.RegisterFetcher("test", func(URL *url.URL) (io.ReadCloser, error) {
+				return io.NopCloser(strings.NewReader("from test")), nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := d.fetchContent(mustParseURL(t, test.input), nil)
+			if test.wantError != "" {
+				var dispatchErr *DispatchError
+				if !errors.As(err, &dispatchErr) {
+					t.Fatalf("expected DispatchError, got %v", err)
+				}
+				if dispatchErr.Code != test.wantError {
+					t.Errorf("%v != %v", dispatchErr.Code, test.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			got, err := io.ReadAll(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(string(got), test.want) {
+				t.Errorf("%#v != %#v", string(got), test.want)
+			}
+		})
+	}
+}
+
+func TestRegisterFetcher(t *testing.T) {
+	tests := []struct {
+		description string
+		scheme      string
+		wantError   bool
+	}{
+		{
+			description: "new scheme",
+			scheme:      "s3",
+		},
+		{
+			description: "default scheme",
+			scheme:      "data",
+		},
+		{
+			description: "reserved scheme",
+			scheme:      "https",
+			wantError:   true,
+		},
+		{
+			description: "reserved scheme in upper case",
+			scheme:      "HTTP",
+			wantError:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			d := &Dispatcher{}
+			fetcher := func(URL *url.URL) (io.ReadCloser, error) {
+				return io.NopCloser(strings.NewReader("registered")), nil
+			}
+
+			err := d.RegisterFetcher(test.scheme, fetcher)
+			if test.wantError {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, has := d.fetcher(strings.ToLower(test.scheme))
+			if !has {
+				t.Fatalf("no fetcher for scheme %v", test.scheme)
+			}
+			r, err := got(&url.URL{Scheme: test.scheme})
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != "registered" {
+				t.Errorf("%#v != %#v", string(content), "registered")
+			}
+		})
+	}
+}
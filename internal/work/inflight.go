@@ -0,0 +1,252 @@
+package work
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redhatinsights/yggdrasil"
+	"github.com/redhatinsights/yggdrasil/internal/config"
+	"github.com/redhatinsights/yggdrasil/ipc"
+	"github.com/subpop/go-log"
+)
+
+// MetadataKeyIdempotent is the data message metadata key that optionally marks
+// a message as safe to dispatch more than once. If set to "true", and the
+// worker is configured to allow it, the message is dispatched again when the
+// worker exits before finishing it.
+const MetadataKeyIdempotent = "idempotent"
+
+// maxRedispatchAttempts is the number of times a message is dispatched again
+// after its worker exits before finishing it.
+const maxRedispatchAttempts = 3
+
+// inflightMessage records a message that has been dispatched to a worker that
+// has not yet finished working on it.
+type inflightMessage struct {
+	data     yggdrasil.Data
+	deadline time.Time
+	timer    *time.Timer
+	attempt  int
+
+	// lastProgress is the time the last progress event for the message was
+	// sent to the server.
+	lastProgress time.Time
+}
+
+// messageDeadline returns the time by which the worker must finish working on
+// data. The deadline is read from the message metadata, falling back to the
+// worker's configured timeout. A zero time is returned if the message has no
+// deadline.
+func messageDeadline(data yggdrasil.Data, now time.Time) (time.Time, error) {
+	if value, has := data.Metadata[ipc.MetadataKeyDeadline]; has {
+		deadline, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("cannot parse deadline '%v': %w", value, err)
+		}
+		return deadline, nil
+	}
+
+	if workerConfig, has := config.DefaultConfig.Workers[data.Directive]; has {
+		if workerConfig.Timeout > 0 {
+			return now.Add(workerConfig.Timeout), nil
+		}
+	}
+
+	return time.Time{}, nil
+}
+
+// trackMessage records data as in-flight, starting a timer that cancels the
+// message if it has a deadline.
+func (d *Dispatcher) trackMessage(data yggdrasil.Data, attempt int) {
+	deadline, err := messageDeadline(data, time.Now())
+	if err != nil {
+		log.Warnf("ignoring deadline of message %v: %v", data.MessageID, err)
+	}
+
+	msg := &inflightMessage{
+		data:     data,
+		deadline: deadline,
+		attempt:  attempt,
+	}
+	d.inflight.Set(data.MessageID, msg)
+
+	if !deadline.IsZero() {
+		msg.timer = time.AfterFunc(time.Until(deadline), func() {
+			d.expireMessage(data.MessageID)
+		})
+		log.Debugf("message %v must finish by %v", data.MessageID, deadline)
+	}
+}
+
+// untrackMessage removes the message with the given ID from the set of
+// in-flight messages, stopping its deadline timer. It returns the message and
+// whether the message was in-flight.
+func (d *Dispatcher) untrackMessage(messageID string) (*inflightMessage, bool) {
+	msg, has := d.inflight.Pop(messageID)
+	if !has {
+		return nil, false
+	}
+	if msg.timer != nil {
+		msg.timer.Stop()
+	}
+	return msg, true
+}
+
+// expireMessage cancels an in-flight message whose deadline has passed and
+// reports the timeout to D-Bus subscribers, the message journal and the
+// server.
+func (d *Dispatcher) expireMessage(messageID string) {
+	msg, has := d.inflight.Pop(messageID)
+	if !has {
+		return
+	}
+
+	log.Warnf(
+		"worker %v did not finish message %v by %v; cancelling",
+		msg.data.Directive,
+		messageID,
+		msg.deadline,
+	)
+
+	if err := d.CancelMessage(msg.data.Directive, uuid.New().String(), messageID); err != nil {
+		log.Errorf("cannot cancel message %v: %v", messageID, err)
+	}
+
+	d.emitWorkerEvent(ipc.WorkerEvent{
+		Worker:     msg.data.Directive,
+		Name:       ipc.WorkerEventNameTimeout,
+		MessageID:  messageID,
+		ResponseTo: msg.data.ResponseTo,
+		Data: map[string]string{
+			ipc.MetadataKeyDeadline: msg.deadline.Format(time.RFC3339),
+		},
+	})
+
+	if !d.finishMessage(messageID) {
+		return
+	}
+	if d.finishChild(messageID, ResultStatusTimeout, "", "deadline exceeded") {
+		return
+	}
+	d.sendMessageEvent(messageID, newTimeoutEvent(msg))
+}
+
+// newTimeoutEvent creates an event message informing the server that a worker
+// did not finish working on msg before its deadline.
+func newTimeoutEvent(msg *inflightMessage) yggdrasil.Event {
+	return yggdrasil.Event{
+		Type:       yggdrasil.MessageTypeEvent,
+		MessageID:  uuid.New().String(),
+		ResponseTo: msg.data.MessageID,
+		Version:    1,
+		Sent:       time.Now(),
+		Content:    string(yggdrasil.EventNameTimeout),
+		Data: map[string]string{
+			"directive":             msg.data.Directive,
+			ipc.MetadataKeyDeadline: msg.deadline.Format(time.RFC3339),
+		},
+	}
+}
+
+// abandonMessages fails every in-flight message dispatched to worker, reporting
+// the failure to D-Bus subscribers, the message journal and the server.
+// Messages marked as idempotent are dispatched again instead of being reported
+// to the server if the worker is configured to allow it.
+func (d *Dispatcher) abandonMessages(worker string) {
+	var orphaned []string
+	d.inflight.Visit(func(k string, v *inflightMessage) {
+		if v.data.Directive == worker {
+			orphaned = append(orphaned, k)
+		}
+	})
+
+	for _, messageID := range orphaned {
+		msg, has := d.untrackMessage(messageID)
+		if !has {
+			continue
+		}
+
+		log.Warnf("worker %v exited before finishing message %v", worker, messageID)
+
+		reason := "worker exited before finishing the message"
+		d.emitWorkerEvent(ipc.WorkerEvent{
+			Worker:     worker,
+			Name:       ipc.WorkerEventNameFailed,
+			MessageID:  messageID,
+			ResponseTo: msg.data.ResponseTo,
+			Data: map[string]string{
+				ipc.WorkerEventDataKeyCode:    string(ErrorCodeWorkerExited),
+				ipc.WorkerEventDataKeyMessage: reason,
+			},
+		})
+
+		if canRedispatch(msg) {
+			log.Infof(
+				"dispatching message %v again (attempt %v of %v)",
+				messageID,
+				msg.attempt+1,
+				maxRedispatchAttempts,
+			)
+			// Dispatch in a goroutine; the worker may need to be activated
+			// on the bus before the call completes.
+			go func() {
+				if err := d.dispatch(msg.data, msg.attempt+1); err != nil {
+					log.Errorf("cannot dispatch message %v again: %v", messageID, err)
+					if d.finishChild(messageID, ResultStatusFailed, dispatchErrorCode(err), err.Error()) {
+						d.finishMessage(messageID)
+						return
+					}
+					d.reportDispatchError(msg.data, err)
+				}
+			}()
+			continue
+		}
+
+		if !d.finishMessage(messageID) {
+			continue
+		}
+		if d.finishChild(messageID, ResultStatusFailed, ErrorCodeWorkerExited, reason) {
+			continue
+		}
+		d.sendMessageEvent(messageID, newFailedEvent(
+			msg.data.Directive,
+			messageID,
+			ErrorCodeWorkerExited,
+			reason,
+		))
+	}
+}
+
+// canRedispatch returns true if msg is marked as idempotent, its worker is
+// configured to allow redispatching messages, and it has not been dispatched
+// too many times already.
+func canRedispatch(msg *inflightMessage) bool {
+	if !config.DefaultConfig.Workers[msg.data.Directive].Redispatch {
+		return false
+	}
+	idempotent, err := strconv.ParseBool(msg.data.Metadata[MetadataKeyIdempotent])
+	if err != nil || !idempotent {
+		return false
+	}
+	return msg.attempt < maxRedispatchAttempts
+}
+
+// newFailedEvent creates an event message informing the server that worker
+// failed to finish working on the message with the given ID.
+func newFailedEvent(worker, messageID string, code ErrorCode, reason string) yggdrasil.Event {
+	return yggdrasil.Event{
+		Type:       yggdrasil.MessageTypeEvent,
+		MessageID:  uuid.New().String(),
+		ResponseTo: messageID,
+		Version:    1,
+		Sent:       time.Now(),
+		Content:    string(yggdrasil.EventNameFailed),
+		Data: map[string]string{
+			"code":      string(code),
+			"reason":    reason,
+			"directive": worker,
+		},
+	}
+}
@@ -0,0 +1,233 @@
+package work
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/redhatinsights/yggdrasil"
+	"github.com/redhatinsights/yggdrasil/internal/config"
+	"github.com/redhatinsights/yggdrasil/ipc"
+)
+
+func TestMessageDeadline(t *testing.T) {
+	now := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	config.DefaultConfig.Workers = map[string]config.WorkerConfig{
+		"echo": {Timeout: 5 * time.Minute},
+	}
+	defer func() {
+		config.DefaultConfig.Workers = nil
+	}()
+
+	tests := []struct {
+		description string
+		input       yggdrasil.Data
+		want        time.Time
+		wantError   error
+	}{
+		{
+			description: "metadata deadline",
+			input: yggdrasil.Data{
+				Directive: "echo",
+				Metadata:  map[string]string{"deadline": "2000-01-01T01:00:00Z"},
+			},
+			want: time.Date(2000, time.January, 1, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			description: "worker default",
+			input: yggdrasil.Data{
+				Directive: "echo",
+			},
+			want: time.Date(2000, time.January, 1, 0, 5, 0, 0, time.UTC),
+		},
+		{
+			description: "no deadline",
+			input: yggdrasil.Data{
+				Directive: "other",
+			},
+			want: time.Time{},
+		},
+		{
+			description: "invalid deadline",
+			input: yggdrasil.Data{
+				Directive: "echo",
+				Metadata:  map[string]string{"deadline": "tomorrow"},
+			},
+			wantError: cmpopts.AnyError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			got, err := messageDeadline(test.input, now)
+
+			if test.wantError != nil {
+				if !cmp.Equal(err, test.wantError, cmpopts.EquateErrors()) {
+					t.Errorf("%#v != %#v", err, test.wantError)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !cmp.Equal(got, test.want) {
+					t.Errorf("%v != %v", got, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCanRedispatch(t *testing.T) {
+	config.DefaultConfig.Workers = map[string]config.WorkerConfig{
+		"echo": {Redispatch: true},
+		"sh":   {},
+	}
+	defer func() {
+		config.DefaultConfig.Workers = nil
+	}()
+
+	tests := []struct {
+		description string
+		input       *inflightMessage
+		want        bool
+	}{
+		{
+			description: "idempotent",
+			input: &inflightMessage{
+				data: yggdrasil.Data{
+					Directive: "echo",
+					Metadata:  map[string]string{"idempotent": "true"},
+				},
+			},
+			want: true,
+		},
+		{
+			description: "not idempotent",
+			input: &inflightMessage{
+				data: yggdrasil.Data{
+					Directive: "echo",
+				},
+			},
+			want: false,
+		},
+		{
+			description: "redispatch disabled",
+			input: &inflightMessage{
+				data: yggdrasil.Data{
+					Directive: "sh",
+					Metadata:  map[string]string{"idempotent": "true"},
+				},
+			},
+			want: false,
+		},
+		{
+			description: "too many attempts",
+			input: &inflightMessage{
+				data: yggdrasil.Data{
+					Directive: "echo",
+					Metadata:  map[string]string{"idempotent": "true"},
+				},
+				attempt: maxRedispatchAttempts,
+			},
+			want: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			got := canRedispatch(test.input)
+
+			if !cmp.Equal(got, test.want) {
+				t.Errorf("%v != %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAbandonChildMessage(t *testing.T) {
+	config.DefaultConfig.Workers = map[string]config.WorkerConfig{
+		"test": {Redispatch: true},
+	}
+	defer func() {
+		config.DefaultConfig.Workers = nil
+	}()
+
+	d := NewDispatcher(nil)
+	d.StartExecWorkers(map[string]config.ExecWorkerConfig{
+		"test": {Directive: "test", Command: "/nonexistent"},
+	})
+	go func() {
+		for range d.WorkerEvents {
+		}
+	}()
+
+	c := &childMessage{directive: "test", done: make(chan MessageResult, 1)}
+	d.children.Set("1", c)
+	d.inflight.Set("1", &inflightMessage{
+		data: yggdrasil.Data{
+			Directive: "test",
+			MessageID: "1",
+			Metadata:  map[string]string{"idempotent": "true"},
+		},
+	})
+
+	d.abandonMessages("test")
+
+	select {
+	case got := <-c.done:
+		want := MessageResult{
+			Directive: "test",
+			MessageID: "1",
+			Status:    ResultStatusFailed,
+			Code:      ErrorCodeDispatchFailed,
+		}
+		if !cmp.Equal(got, want, cmpopts.IgnoreFields(MessageResult{}, "Reason")) {
+			t.Errorf("%v", cmp.Diff(got, want, cmpopts.IgnoreFields(MessageResult{}, "Reason")))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("child message did not finish")
+	}
+}
+
+func TestFailureAfterTimeout(t *testing.T) {
+	d := NewDispatcher(nil)
+	d.StartExecWorkers(map[string]config.ExecWorkerConfig{
+		"test": {Directive: "test", Command: "/bin/true"},
+	})
+	go func() {
+		for range d.WorkerEvents {
+		}
+	}()
+	d.inflight.Set("1", &inflightMessage{
+		data:     yggdrasil.Data{Directive: "test", MessageID: "1"},
+		deadline: time.Now(),
+	})
+
+	d.expireMessage("1")
+
+	select {
+	case event := <-d.OutboundEvents:
+		if event.Content != string(yggdrasil.EventNameTimeout) {
+			t.Fatalf("%v != %v", event.Content, yggdrasil.EventNameTimeout)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout was not reported")
+	}
+
+	// The worker reports a failure once it is cancelled; the server has
+	// already been told the message timed out.
+	d.handleWorkerEvent(&ipc.WorkerEvent{
+		Worker:    "test",
+		Name:      ipc.WorkerEventNameFailed,
+		MessageID: "1",
+		Data:      map[string]string{ipc.WorkerEventDataKeyCode: string(ErrorCodeCancelled)},
+	})
+
+	select {
+	case event := <-d.OutboundEvents:
+		t.Errorf("unexpected event sent to the server: %v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,170 @@
+package work
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redhatinsights/yggdrasil"
+	"github.com/redhatinsights/yggdrasil/internal/config"
+	"github.com/redhatinsights/yggdrasil/internal/cron"
+	"github.com/subpop/go-log"
+)
+
+// MetadataKeyJob is the data message metadata key set on messages dispatched
+// by a locally configured job. It contains the name of the job. The key is
+// reserved: it is removed from the metadata of messages received from the
+// server.
+const MetadataKeyJob = "yggdrasil_job"
+
+// Results of the most recent run of a job.
+const (
+	JobResultDispatched = "dispatched"
+	JobResultSkipped    = "skipped"
+)
+
+// JobStatus describes a locally configured job and its most recent run.
+type JobStatus struct {
+	Name          string
+	Directive     string
+	Schedule      string
+	NextRun       time.Time
+	LastRun       time.Time
+	LastMessageID string
+
+	// LastResult is JobResultDispatched if the most recent run dispatched a
+	// message, JobResultSkipped if it was skipped because the message of the
+	// run before it was still in-flight, or the reason dispatching failed.
+	LastResult string
+}
+
+// job is a locally configured job scheduled by the dispatcher.
+type job struct {
+	name     string
+	config   config.JobConfig
+	schedule *cron.Schedule
+
+	mu     sync.Mutex
+	status JobStatus
+}
+
+// StartJobs schedules the given jobs, keyed by name, to be dispatched at the
+// times defined by their schedules. Jobs with an invalid schedule are logged
+// and skipped.
+func (d *Dispatcher) StartJobs(jobs map[string]config.JobConfig) {
+	for name, cfg := range jobs {
+		schedule, err := cron.Parse(cfg.Schedule)
+		if err != nil {
+			log.Errorf("cannot schedule job %v: %v", name, err)
+			continue
+		}
+		j := &job{
+			name:     name,
+			config:   cfg,
+			schedule: schedule,
+			status: JobStatus{
+				Name:      name,
+				Directive: cfg.Directive,
+				Schedule:  cfg.Schedule,
+			},
+		}
+		d.jobs.Set(name, j)
+		d.armJob(j)
+	}
+}
+
+// armJob starts a timer that runs j at the next time matching its schedule.
+func (d *Dispatcher) armJob(j *job) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	// Never compute the next run from before the current one, in case the
+	// timer fires early.
+	from := time.Now()
+	if from.Before(j.status.NextRun) {
+		from = j.status.NextRun
+	}
+	j.status.NextRun = j.schedule.Next(from)
+	if j.status.NextRun.IsZero() {
+		log.Warnf("job %v is not scheduled to run again", j.name)
+		return
+	}
+	log.Debugf("job %v runs next at %v", j.name, j.status.NextRun)
+
+	time.AfterFunc(time.Until(j.status.NextRun), func() {
+		d.runJob(j)
+		d.armJob(j)
+	})
+}
+
+// runJob dispatches the message defined by j to its worker, unless the message
+// of its previous run is still in-flight.
+func (d *Dispatcher) runJob(j *job) {
+	j.mu.Lock()
+	previous := j.status.LastMessageID
+	j.mu.Unlock()
+
+	if _, running := d.inflight.Get(previous); running {
+		log.Warnf("skipping run of job %v: message %v has not finished", j.name, previous)
+		j.mu.Lock()
+		j.status.LastResult = JobResultSkipped
+		j.mu.Unlock()
+		return
+	}
+
+	metadata := make(map[string]string, len(j.config.Metadata)+1)
+	for k, v := range j.config.Metadata {
+		metadata[k] = v
+	}
+	metadata[MetadataKeyJob] = j.name
+
+	data := yggdrasil.Data{
+		Type:      yggdrasil.MessageTypeData,
+		MessageID: uuid.New().String(),
+		Version:   1,
+		Sent:      time.Now(),
+		Directive: j.config.Directive,
+		Metadata:  metadata,
+		Content:   []byte(j.config.Content),
+	}
+
+	log.Infof("running job %v: dispatching message %v to worker %v", j.name, data.MessageID, data.Directive)
+	d.markLocal(data.MessageID)
+	result := JobResultDispatched
+	if err := d.Dispatch(data); err != nil {
+		log.Errorf("cannot run job %v: %v", j.name, err)
+		d.finishMessage(data.MessageID)
+		result = err.Error()
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.LastRun = data.Sent
+	j.status.LastMessageID = data.MessageID
+	j.status.LastResult = result
+}
+
+// removeJobMetadata removes the reserved MetadataKeyJob key from the metadata
+// of data, which was received from the server. Only yggd marks the messages of
+// locally configured jobs.
+func removeJobMetadata(data yggdrasil.Data) {
+	if _, has := data.Metadata[MetadataKeyJob]; has {
+		log.Warnf("removing reserved metadata key %v from message %v", MetadataKeyJob, data.MessageID)
+		delete(data.Metadata, MetadataKeyJob)
+	}
+}
+
+// ListJobs returns the status of the locally configured jobs, ordered by name.
+func (d *Dispatcher) ListJobs() []JobStatus {
+	var jobs []JobStatus
+	d.jobs.Visit(func(_ string, j *job) {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		jobs = append(jobs, j.status)
+	})
+	sort.Slice(jobs, func(i, k int) bool {
+		return jobs[i].Name < jobs[k].Name
+	})
+	return jobs
+}
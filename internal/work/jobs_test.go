@@ -0,0 +1,131 @@
+package work
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/redhatinsights/yggdrasil"
+	"github.com/redhatinsights/yggdrasil/internal/config"
+	"github.com/redhatinsights/yggdrasil/ipc"
+)
+
+func TestRunJobSkipsOverlappingRun(t *testing.T) {
+	d := &Dispatcher{}
+	d.inflight.Set("1", &inflightMessage{
+		data: yggdrasil.Data{MessageID: "1", Directive: "compliance"},
+	})
+	j := &job{
+		name:   "scan",
+		config: config.JobConfig{Directive: "compliance"},
+		status: JobStatus{
+			Name:          "scan",
+			LastMessageID: "1",
+			LastResult:    JobResultDispatched,
+		},
+	}
+
+	d.runJob(j)
+
+	want := JobStatus{
+		Name:          "scan",
+		LastMessageID: "1",
+		LastResult:    JobResultSkipped,
+	}
+	if !cmp.Equal(j.status, want) {
+		t.Errorf("%v", cmp.Diff(j.status, want))
+	}
+}
+
+func TestStartJobs(t *testing.T) {
+	d := &Dispatcher{}
+	d.StartJobs(map[string]config.JobConfig{
+		"scan":    {Schedule: "0 2 * * *", Directive: "compliance"},
+		"invalid": {Schedule: "every night", Directive: "compliance"},
+		"facts":   {Schedule: "@hourly", Directive: "facts"},
+	})
+
+	got := d.ListJobs()
+
+	if len(got) != 2 || got[0].Name != "facts" || got[1].Name != "scan" {
+		t.Fatalf("unexpected jobs: %+v", got)
+	}
+	for _, status := range got {
+		if status.NextRun.IsZero() {
+			t.Errorf("job %v has no next run", status.Name)
+		}
+		if status.NextRun.Minute() != 0 {
+			t.Errorf("job %v runs at unexpected time %v", status.Name, status.NextRun)
+		}
+	}
+}
+
+func TestRunJobKeepsEventsLocal(t *testing.T) {
+	d := NewDispatcher(nil)
+	d.StartExecWorkers(map[string]config.ExecWorkerConfig{
+		"compliance": {Directive: "compliance", Command: "/bin/false"},
+	})
+	j := &job{
+		name:   "scan",
+		config: config.JobConfig{Directive: "compliance"},
+	}
+
+	d.runJob(j)
+
+	for _, name := range []ipc.WorkerEventName{ipc.WorkerEventNameBegin, ipc.WorkerEventNameFailed} {
+		if got := <-d.WorkerEvents; got.Name != name {
+			t.Fatalf("%v != %v", got.Name, name)
+		}
+	}
+	select {
+	case event := <-d.OutboundEvents:
+		t.Errorf("unexpected event sent to the server: %v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestJobMessageTimeoutKeptLocal(t *testing.T) {
+	d := NewDispatcher(nil)
+	d.StartExecWorkers(map[string]config.ExecWorkerConfig{
+		"compliance": {Directive: "compliance", Command: "/bin/true"},
+	})
+	go func() {
+		for range d.WorkerEvents {
+		}
+	}()
+	d.markLocal("1")
+	d.inflight.Set("1", &inflightMessage{
+		data:     yggdrasil.Data{Directive: "compliance", MessageID: "1"},
+		deadline: time.Now(),
+	})
+
+	d.expireMessage("1")
+	d.handleWorkerEvent(&ipc.WorkerEvent{
+		Worker:    "compliance",
+		Name:      ipc.WorkerEventNameFailed,
+		MessageID: "1",
+	})
+
+	select {
+	case event := <-d.OutboundEvents:
+		t.Errorf("unexpected event sent to the server: %v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestRemoveJobMetadata(t *testing.T) {
+	data := yggdrasil.Data{
+		MessageID: "1",
+		Metadata: map[string]string{
+			MetadataKeyJob: "scan",
+			"report":       "true",
+		},
+	}
+
+	removeJobMetadata(data)
+
+	want := map[string]string{"report": "true"}
+	if !cmp.Equal(data.Metadata, want) {
+		t.Errorf("%v", cmp.Diff(data.Metadata, want))
+	}
+}
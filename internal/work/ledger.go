@@ -0,0 +1,148 @@
+package work
+
+import (
+	"sync"
+	"time"
+
+	"github.com/redhatinsights/yggdrasil"
+	"github.com/subpop/go-log"
+)
+
+// ledgerRetention is how long the ledger remembers a message after it
+// finished, so that events a worker emits late, such as a FAILED event after
+// the message timed out, are recognized.
+var ledgerRetention = time.Hour
+
+// messageLedger records what the dispatcher knows about a message beyond the
+// time it is in-flight: whether the message was created locally rather than
+// sent by the server, and whether it has finished.
+type messageLedger struct {
+	mu      sync.Mutex
+	entries map[string]*ledgerEntry
+}
+
+// ledgerEntry is the record of a message in the ledger.
+type ledgerEntry struct {
+	// local is true if the message was created on the host, such as by a
+	// locally configured job, rather than sent by the server.
+	local bool
+
+	// sender is the directive of the worker that transmitted the message,
+	// if it was transmitted from one worker to another. target is the
+	// directive of the worker it was transmitted to.
+	sender string
+	target string
+
+	// finished is the time the message finished. It is zero while the
+	// message has not finished.
+	finished time.Time
+}
+
+// markLocal records that the message with the given ID was created on the
+// host, so that no events about it are sent to the server.
+func (d *Dispatcher) markLocal(messageID string) {
+	l := &d.ledger
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.entries == nil {
+		l.entries = map[string]*ledgerEntry{}
+	}
+	if entry, has := l.entries[messageID]; has {
+		entry.local = true
+		return
+	}
+	l.entries[messageID] = &ledgerEntry{local: true}
+}
+
+// markTransmitted records that the message with the given ID was transmitted by
+// the worker sender to the worker target, so that events about it are returned
+// to sender instead of being sent to the server.
+func (d *Dispatcher) markTransmitted(messageID string, sender string, target string) {
+	d.markLocal(messageID)
+
+	l := &d.ledger
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry := l.entries[messageID]
+	entry.sender = sender
+	entry.target = target
+}
+
+// isLocal returns true if the message with the given ID was created on the
+// host.
+func (d *Dispatcher) isLocal(messageID string) bool {
+	l := &d.ledger
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, has := l.entries[messageID]
+	return has && entry.local
+}
+
+// finishMessage records that the message with the given ID finished, by
+// completing, failing, timing out or failing to be dispatched. It returns
+// false if the message had already finished, in which case nothing more
+// should be reported about it.
+func (d *Dispatcher) finishMessage(messageID string) bool {
+	l := &d.ledger
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for id, entry := range l.entries {
+		if !entry.finished.IsZero() && now.Sub(entry.finished) > ledgerRetention {
+			delete(l.entries, id)
+		}
+	}
+
+	if l.entries == nil {
+		l.entries = map[string]*ledgerEntry{}
+	}
+	entry, has := l.entries[messageID]
+	if !has {
+		entry = &ledgerEntry{}
+		l.entries[messageID] = entry
+	}
+	if !entry.finished.IsZero() {
+		return false
+	}
+	entry.finished = now
+	return true
+}
+
+// sendMessageEvent queues event about the message with the given ID to be sent
+// to the server, unless the message was created on the host. The server did not
+// send such messages, so events about them are only recorded in the message
+// journal and emitted as D-Bus signals. Failures of messages transmitted from
+// one worker to another are returned to the sending worker.
+func (d *Dispatcher) sendMessageEvent(messageID string, event yggdrasil.Event) {
+	l := &d.ledger
+	l.mu.Lock()
+	var entry ledgerEntry
+	if e, has := l.entries[messageID]; has {
+		entry = *e
+	}
+	l.mu.Unlock()
+
+	if !entry.local {
+		d.sendEvent(event)
+		return
+	}
+	log.Debugf("not sending %v event about local message %v", event.Content, messageID)
+	if entry.sender != "" && event.Content != string(yggdrasil.EventNameProgress) {
+		go d.returnLocalEvent(messageID, entry.sender, entry.target, event)
+	}
+}
+
+// sendFinalEvent finishes the message with the given ID and queues event, which
+// reports how it finished, to be sent to the server as by sendMessageEvent.
+// The event is not sent if the message had already finished.
+func (d *Dispatcher) sendFinalEvent(messageID string, event yggdrasil.Event) {
+	if !d.finishMessage(messageID) {
+		log.Debugf("not sending %v event about message %v: it already finished", event.Content, messageID)
+		return
+	}
+	d.sendMessageEvent(messageID, event)
+}
@@ -0,0 +1,45 @@
+package work
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFinishMessage(t *testing.T) {
+	d := &Dispatcher{}
+
+	if !d.finishMessage("1") {
+		t.Fatal("first finish of message 1 returned false")
+	}
+	if d.finishMessage("1") {
+		t.Error("second finish of message 1 returned true")
+	}
+	if !d.finishMessage("2") {
+		t.Error("first finish of message 2 returned false")
+	}
+}
+
+func TestFinishMessageForgetsOldMessages(t *testing.T) {
+	d := &Dispatcher{}
+	d.finishMessage("1")
+	d.ledger.entries["1"].finished = time.Now().Add(-2 * ledgerRetention)
+
+	d.finishMessage("2")
+
+	if _, has := d.ledger.entries["1"]; has {
+		t.Error("message 1 was not forgotten")
+	}
+}
+
+func TestMarkLocal(t *testing.T) {
+	d := &Dispatcher{}
+	d.markLocal("1")
+	d.finishMessage("1")
+
+	if !d.isLocal("1") {
+		t.Error("message 1 is not local")
+	}
+	if d.isLocal("2") {
+		t.Error("message 2 is local")
+	}
+}
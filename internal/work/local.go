@@ -0,0 +1,153 @@
+package work
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/google/uuid"
+	"github.com/redhatinsights/yggdrasil"
+	"github.com/redhatinsights/yggdrasil/internal/config"
+	"github.com/redhatinsights/yggdrasil/ipc"
+	"github.com/subpop/go-log"
+)
+
+// LocalAddrPrefix is the prefix of Transmit addresses that route a message to
+// another worker on the same host instead of the server. The remainder of the
+// address is the directive of the receiving worker, e.g. "local:echo".
+const LocalAddrPrefix = "local:"
+
+// MetadataKeySender is the data message metadata key set on messages
+// transmitted from one worker to another. It contains the directive of the
+// sending worker, so that the receiving worker can reply to it.
+const MetadataKeySender = "sender"
+
+// MetadataKeyLocalEvent is the data message metadata key set on messages that
+// return an event about a message transmitted from one worker to another to
+// the sending worker. It contains the name of the event, such as "failed" or
+// "timeout", and the content of the message is the event.
+const MetadataKeyLocalEvent = "local_event"
+
+// localTargetAllowed returns true if targets, the "local-targets" allow-list of
+// a worker, permits transmitting to target. The value "*" permits any target.
+func localTargetAllowed(targets []string, target string) bool {
+	return slices.Contains(targets, "*") || slices.Contains(targets, target)
+}
+
+// isLocalReply returns true if msg is a message sender received from target
+// through a local address, making a message from sender to target a reply.
+func isLocalReply(msg *inflightMessage, sender string, target string) bool {
+	return msg.data.Directive == sender && msg.data.Metadata[MetadataKeySender] == target
+}
+
+// canTransmitLocal returns true if the worker sender may transmit a message to
+// the worker target. A worker may transmit to the targets in its allow-list,
+// and may reply to the sender of any local message it is working on.
+func (d *Dispatcher) canTransmitLocal(sender string, target string, responseTo string) bool {
+	if localTargetAllowed(config.DefaultConfig.Workers[sender].LocalTargets, target) {
+		return true
+	}
+	if responseTo == "" {
+		return false
+	}
+	msg, has := d.inflight.Get(responseTo)
+	return has && isLocalReply(msg, sender, target)
+}
+
+// transmitLocal dispatches a message transmitted by the worker sender to the
+// local worker addressed by addr.
+func (d *Dispatcher) transmitLocal(
+	sender string,
+	addr string,
+	messageID string,
+	responseTo string,
+	metadata map[string]string,
+	data []byte,
+) (responseCode int, responseMetadata map[string]string, responseData []byte, responseError *dbus.Error) {
+	target := strings.TrimPrefix(addr, LocalAddrPrefix)
+	if target == "" {
+		return TransmitResponseErr, nil, nil, NewDBusError(
+			ipc.ErrorNameTransmit,
+			fmt.Sprintf("cannot parse local address '%v': missing directive", addr),
+		)
+	}
+
+	if !d.canTransmitLocal(sender, target, responseTo) {
+		return TransmitResponseErr, nil, nil, d.denyTransmit(
+			sender,
+			addr,
+			messageID,
+			responseTo,
+			fmt.Sprintf("worker %v is not allowed to transmit to local worker %v", sender, target),
+		)
+	}
+
+	if messageID == "" {
+		messageID = uuid.New().String()
+	}
+	msgMetadata := make(map[string]string, len(metadata)+1)
+	for k, v := range metadata {
+		msgMetadata[k] = v
+	}
+	msgMetadata[MetadataKeySender] = sender
+
+	d.markTransmitted(messageID, sender, target)
+	err := d.Dispatch(yggdrasil.Data{
+		Type:       yggdrasil.MessageTypeData,
+		MessageID:  messageID,
+		ResponseTo: responseTo,
+		Version:    1,
+		Sent:       time.Now(),
+		Directive:  target,
+		Metadata:   msgMetadata,
+		Content:    data,
+	})
+	if err != nil {
+		d.finishMessage(messageID)
+		return TransmitResponseErr, nil, nil, NewDBusError(
+			ipc.ErrorNameTransmit,
+			fmt.Sprintf("cannot dispatch message to local worker %v: %v", target, err),
+		)
+	}
+	log.Debugf("routed message %v from worker %v to local worker %v", messageID, sender, target)
+
+	return TransmitResponseOK, map[string]string{}, []byte{}, nil
+}
+
+// returnLocalEvent dispatches event, which reports that the message with the
+// given ID transmitted by the worker sender to the worker target failed, to
+// sender in response to the message.
+func (d *Dispatcher) returnLocalEvent(messageID string, sender string, target string, event yggdrasil.Event) {
+	content, err := json.Marshal(event)
+	if err != nil {
+		log.Errorf("cannot marshal %v event of local message %v: %v", event.Content, messageID, err)
+		return
+	}
+
+	data := yggdrasil.Data{
+		Type:       yggdrasil.MessageTypeData,
+		MessageID:  uuid.New().String(),
+		ResponseTo: messageID,
+		Version:    1,
+		Sent:       time.Now(),
+		Directive:  sender,
+		Metadata: map[string]string{
+			MetadataKeySender:     target,
+			MetadataKeyLocalEvent: event.Content,
+		},
+		Content: content,
+	}
+
+	// The returned event is itself a local message; if it cannot be
+	// delivered, nothing is returned about it in turn.
+	d.markLocal(data.MessageID)
+	if err := d.Dispatch(data); err != nil {
+		d.finishMessage(data.MessageID)
+		log.Errorf("cannot return %v event of local message %v to worker %v: %v", event.Content, messageID, sender, err)
+		return
+	}
+	log.Debugf("returned %v event of local message %v to worker %v", event.Content, messageID, sender)
+}
@@ -0,0 +1,148 @@
+package work
+
+import (
+	"testing"
+	"time"
+
+	"github.com/redhatinsights/yggdrasil"
+	"github.com/redhatinsights/yggdrasil/internal/config"
+	"github.com/redhatinsights/yggdrasil/ipc"
+)
+
+func TestCanTransmitLocal(t *testing.T) {
+	config.DefaultConfig.Workers = map[string]config.WorkerConfig{
+		"remediation": {LocalTargets: []string{"facts"}},
+		"admin":       {LocalTargets: []string{"*"}},
+	}
+	defer func() {
+		config.DefaultConfig.Workers = nil
+	}()
+
+	d := &Dispatcher{}
+	d.inflight.Set("1", &inflightMessage{
+		data: yggdrasil.Data{
+			MessageID: "1",
+			Directive: "facts",
+			Metadata:  map[string]string{MetadataKeySender: "remediation"},
+		},
+	})
+	d.inflight.Set("2", &inflightMessage{
+		data: yggdrasil.Data{
+			MessageID: "2",
+			Directive: "facts",
+		},
+	})
+
+	tests := []struct {
+		description string
+		sender      string
+		target      string
+		responseTo  string
+		want        bool
+	}{
+		{
+			description: "allowed target",
+			sender:      "remediation",
+			target:      "facts",
+			want:        true,
+		},
+		{
+			description: "target not allowed",
+			sender:      "remediation",
+			target:      "echo",
+			want:        false,
+		},
+		{
+			description: "wildcard",
+			sender:      "admin",
+			target:      "echo",
+			want:        true,
+		},
+		{
+			description: "unconfigured worker",
+			sender:      "facts",
+			target:      "remediation",
+			want:        false,
+		},
+		{
+			description: "reply to local message",
+			sender:      "facts",
+			target:      "remediation",
+			responseTo:  "1",
+			want:        true,
+		},
+		{
+			description: "reply to another worker",
+			sender:      "facts",
+			target:      "echo",
+			responseTo:  "1",
+			want:        false,
+		},
+		{
+			description: "reply to message from server",
+			sender:      "facts",
+			target:      "remediation",
+			responseTo:  "2",
+			want:        false,
+		},
+		{
+			description: "reply to unknown message",
+			sender:      "facts",
+			target:      "remediation",
+			responseTo:  "3",
+			want:        false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			got := d.canTransmitLocal(test.sender, test.target, test.responseTo)
+
+			if got != test.want {
+				t.Errorf("%v != %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTransmitLocalReturnsFailure(t *testing.T) {
+	config.DefaultConfig.Workers = map[string]config.WorkerConfig{
+		"remediation": {LocalTargets: []string{"facts"}},
+	}
+	defer func() {
+		config.DefaultConfig.Workers = nil
+	}()
+
+	d := NewDispatcher(nil)
+	d.StartExecWorkers(map[string]config.ExecWorkerConfig{
+		"remediation": {Directive: "remediation", Command: "/bin/true"},
+		"facts":       {Directive: "facts", Command: "/bin/false"},
+	})
+
+	code, _, _, err := d.transmitLocal("remediation", "local:facts", "1", "", nil, []byte{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != TransmitResponseOK {
+		t.Fatalf("%v != %v", code, TransmitResponseOK)
+	}
+
+	// The failure of the message is returned to the sending worker in
+	// response to it, instead of being sent to the server.
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-d.WorkerEvents:
+			if event.Worker == "remediation" && event.Name == ipc.WorkerEventNameBegin {
+				if event.ResponseTo != "1" {
+					t.Fatalf("%v != %v", event.ResponseTo, "1")
+				}
+				return
+			}
+		case event := <-d.OutboundEvents:
+			t.Fatalf("unexpected event sent to the server: %v", event)
+		case <-timeout:
+			t.Fatal("failure was not returned to the sending worker")
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package work
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/redhatinsights/yggdrasil/internal/schema"
+	"github.com/redhatinsights/yggdrasil/ipc"
+	"github.com/subpop/go-log"
+)
+
+// MetadataKeyContentType is the metadata key of a data message whose value is
+// the media type of its content. A message is only dispatched to a worker
+// whose manifest lists content types if the value is one of them.
+const MetadataKeyContentType = "content_type"
+
+// compiledManifest is the manifest of a connected worker, with its schemas
+// parsed so that they are not parsed again for each message dispatched to the
+// worker.
+type compiledManifest struct {
+	ipc.WorkerManifest
+
+	contentSchema  *schema.Schema
+	metadataSchema *schema.Schema
+
+	// schemaErr is the error parsing the schemas of the manifest, if any.
+	// Messages are not dispatched to a worker whose schemas cannot be parsed.
+	schemaErr error
+}
+
+// compileManifest parses the schemas of m.
+func compileManifest(m ipc.WorkerManifest) *compiledManifest {
+	compiled := &compiledManifest{WorkerManifest: m}
+	if len(m.ContentSchema) > 0 {
+		s, err := schema.Parse(m.ContentSchema)
+		if err != nil {
+			compiled.schemaErr = fmt.Errorf("cannot parse content schema of worker: %w", err)
+			return compiled
+		}
+		compiled.contentSchema = s
+	}
+	if len(m.MetadataSchema) > 0 {
+		s, err := schema.Parse(m.MetadataSchema)
+		if err != nil {
+			compiled.schemaErr = fmt.Errorf("cannot parse metadata schema of worker: %w", err)
+			return compiled
+		}
+		compiled.metadataSchema = s
+	}
+	return compiled
+}
+
+// parseManifest unmarshals the JSON value of a Manifest property.
+func parseManifest(v dbus.Variant) (*compiledManifest, error) {
+	value, ok := v.Value().(string)
+	if !ok {
+		return nil, fmt.Errorf("cannot convert %T to string", v.Value())
+	}
+	var m ipc.WorkerManifest
+	if err := json.Unmarshal([]byte(value), &m); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal manifest: %w", err)
+	}
+	return compileManifest(m), nil
+}
+
+// workerManifest returns the manifest of the worker exported as obj. Workers
+// that do not export the Manifest property have an empty manifest.
+func workerManifest(obj dbus.BusObject) (*compiledManifest, error) {
+	v, err := obj.GetProperty("com.redhat.Yggdrasil1.Worker1.Manifest")
+	if err != nil {
+		if isUnknownPropertyError(err) {
+			return compileManifest(ipc.WorkerManifest{}), nil
+		}
+		return nil, fmt.Errorf("cannot get property 'com.redhat.Yggdrasil1.Worker1.Manifest': %w", err)
+	}
+	return parseManifest(v)
+}
+
+// isUnknownPropertyError returns true if err is a D-Bus error indicating that
+// the object does not export the requested property. Bus libraries name this
+// error differently: godbus returns PropertyNotFound, sd-bus UnknownProperty
+// and GDBus InvalidArgs.
+func isUnknownPropertyError(err error) bool {
+	var dbusErr dbus.Error
+	if !errors.As(err, &dbusErr) {
+		return false
+	}
+	switch dbusErr.Name {
+	case "org.freedesktop.DBus.Properties.Error.PropertyNotFound",
+		"org.freedesktop.DBus.Error.UnknownProperty",
+		"org.freedesktop.DBus.Error.InvalidArgs":
+		return true
+	}
+	return false
+}
+
+// loadManifest records the manifest of the worker directive, exported as obj.
+func (d *Dispatcher) loadManifest(directive string, obj dbus.BusObject) {
+	m, err := workerManifest(obj)
+	if err != nil {
+		log.Errorf("cannot get manifest of worker %v: %v", directive, err)
+		return
+	}
+	d.manifests.Set(directive, m)
+}
+
+// setManifest records the manifest of the worker directive from the JSON value
+// of a changed Manifest property.
+func (d *Dispatcher) setManifest(directive string, v dbus.Variant) {
+	m, err := parseManifest(v)
+	if err != nil {
+		log.Errorf("cannot get manifest of worker %v: %v", directive, err)
+		return
+	}
+	d.manifests.Set(directive, m)
+}
+
+// dispatchManifest returns the manifest of the worker directive, exported as
+// obj. The manifest recorded when the worker connected is used if there is
+// one; otherwise, such as for a worker activated by the message being
+// dispatched, it is read from the worker and recorded.
+func (d *Dispatcher) dispatchManifest(directive string, obj dbus.BusObject) (*compiledManifest, error) {
+	if m, has := d.manifests.Get(directive); has {
+		return m, nil
+	}
+	m, err := workerManifest(obj)
+	if err != nil {
+		return nil, err
+	}
+	d.manifests.Set(directive, m)
+	return m, nil
+}
+
+// ListManifests returns the manifest of each connected worker, keyed by the
+// worker name.
+func (d *Dispatcher) ListManifests() map[string]ipc.WorkerManifest {
+	manifests := make(map[string]ipc.WorkerManifest)
+	d.manifests.Visit(func(k string, v *compiledManifest) {
+		manifests[k] = v.WorkerManifest
+	})
+	return manifests
+}
+
+// validateMetadata returns an error if the content type or metadata of data
+// are not accepted by the worker with manifest m.
+func validateMetadata(m *compiledManifest, metadata map[string]string) error {
+	if m.schemaErr != nil {
+		return newDispatchError(ErrorCodeDispatchFailed, m.schemaErr)
+	}
+
+	if contentType := metadata[MetadataKeyContentType]; contentType != "" && len(m.ContentTypes) > 0 {
+		accepted := false
+		for _, t := range m.ContentTypes {
+			if t == contentType {
+				accepted = true
+				break
+			}
+		}
+		if !accepted {
+			return newDispatchError(
+				ErrorCodeInvalidContent,
+				fmt.Errorf("content type %v is not accepted by the worker", contentType),
+			)
+		}
+	}
+
+	if m.metadataSchema == nil {
+		return nil
+	}
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	if err := m.metadataSchema.ValidateValue(metadata); err != nil {
+		return newDispatchError(ErrorCodeInvalidContent, fmt.Errorf("invalid metadata: %w", err))
+	}
+	return nil
+}
+
+// isJSONMediaType returns true if contentType is application/json or a media
+// type with the +json structured syntax suffix, such as
+// application/merge-patch+json.
+func isJSONMediaType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// validateContent returns an error if content is not valid against the
+// content schema of the worker with manifest m. The schema only applies to JSON
+// content: content whose content type is not a JSON media type is not
+// validated, and content without a content type must be JSON.
+func validateContent(m *compiledManifest, contentType string, content []byte) error {
+	if m.contentSchema == nil {
+		return nil
+	}
+	if contentType != "" && !isJSONMediaType(contentType) {
+		log.Debugf("not validating content of type %v against the content schema of the worker", contentType)
+		return nil
+	}
+	if !json.Valid(content) {
+		return newDispatchError(
+			ErrorCodeInvalidContent,
+			fmt.Errorf("cannot validate content against the content schema of the worker: content is not JSON and has no content type"),
+		)
+	}
+	if err := m.contentSchema.Validate(content); err != nil {
+		return newDispatchError(ErrorCodeInvalidContent, fmt.Errorf("invalid content: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package work
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/google/go-cmp/cmp"
+	"github.com/redhatinsights/yggdrasil/ipc"
+)
+
+func TestValidateManifest(t *testing.T) {
+	manifest := ipc.WorkerManifest{
+		ContentTypes:   []string{"application/json"},
+		ContentSchema:  []byte(`{"type":"object","required":["command"],"properties":{"command":{"enum":["start","stop"]}}}`),
+		MetadataSchema: []byte(`{"properties":{"retries":{"pattern":"^[0-9]+$"}}}`),
+	}
+
+	tests := []struct {
+		description string
+		manifest    ipc.WorkerManifest
+		metadata    map[string]string
+		content     string
+		wantError   ErrorCode
+	}{
+		{
+			description: "empty manifest",
+			metadata:    map[string]string{MetadataKeyContentType: "text/plain"},
+			content:     "not JSON",
+		},
+		{
+			description: "valid",
+			manifest:    manifest,
+			metadata:    map[string]string{MetadataKeyContentType: "application/json", "retries": "3"},
+			content:     `{"command":"start"}`,
+		},
+		{
+			description: "no content type",
+			manifest:    manifest,
+			content:     `{"command":"stop"}`,
+		},
+		{
+			description: "unaccepted content type",
+			manifest:    manifest,
+			metadata:    map[string]string{MetadataKeyContentType: "text/plain"},
+			content:     `{"command":"start"}`,
+			wantError:   ErrorCodeInvalidContent,
+		},
+		{
+			description: "invalid metadata",
+			manifest:    manifest,
+			metadata:    map[string]string{"retries": "many"},
+			content:     `{"command":"start"}`,
+			wantError:   ErrorCodeInvalidContent,
+		},
+		{
+			description: "invalid content",
+			manifest:    manifest,
+			content:     `{"command":"restart"}`,
+			wantError:   ErrorCodeInvalidContent,
+		},
+		{
+			description: "content type with parameters",
+			manifest:    manifest,
+			metadata:    map[string]string{MetadataKeyContentType: "application/json; charset=utf-8"},
+			content:     `{"command":"restart"}`,
+			wantError:   ErrorCodeInvalidContent,
+		},
+		{
+			description: "not JSON",
+			manifest:    ipc.WorkerManifest{ContentSchema: manifest.ContentSchema},
+			metadata:    map[string]string{MetadataKeyContentType: "text/x-shellscript"},
+			content:     "#!/bin/sh",
+		},
+		{
+			description: "not JSON without content type",
+			manifest:    manifest,
+			content:     "#!/bin/sh",
+			wantError:   ErrorCodeInvalidContent,
+		},
+		{
+			description: "invalid schema",
+			manifest:    ipc.WorkerManifest{ContentSchema: []byte(`{"type":"map"}`)},
+			content:     `{}`,
+			wantError:   ErrorCodeDispatchFailed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			m := compileManifest(test.manifest)
+			err := validateMetadata(m, test.metadata)
+			if err == nil {
+				err = validateContent(m, test.metadata[MetadataKeyContentType], []byte(test.content))
+			}
+			if test.wantError == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var dispatchErr *DispatchError
+			if !errors.As(err, &dispatchErr) {
+				t.Fatalf("expected DispatchError, got %v", err)
+			}
+			if dispatchErr.Code != test.wantError {
+				t.Errorf("%v != %v", dispatchErr.Code, test.wantError)
+			}
+		})
+	}
+}
+
+// propertyObject is a bus object whose GetProperty method returns value and
+// err.
+type propertyObject struct {
+	dbus.BusObject
+	value dbus.Variant
+	err   error
+}
+
+func (o propertyObject) GetProperty(p string) (dbus.Variant, error) {
+	return o.value, o.err
+}
+
+func TestWorkerManifest(t *testing.T) {
+	tests := []struct {
+		description string
+		obj         propertyObject
+		want        ipc.WorkerManifest
+		wantError   bool
+	}{
+		{
+			description: "manifest",
+			obj:         propertyObject{value: dbus.MakeVariant(`{"version":"1.0","cancellable":true}`)},
+			want:        ipc.WorkerManifest{Version: "1.0", Cancellable: true},
+		},
+		{
+			description: "no manifest",
+			obj: propertyObject{err: dbus.Error{
+				Name: "org.freedesktop.DBus.Properties.Error.PropertyNotFound",
+			}},
+			want: ipc.WorkerManifest{},
+		},
+		{
+			description: "no reply",
+			obj: propertyObject{err: dbus.Error{
+				Name: "org.freedesktop.DBus.Error.NoReply",
+			}},
+			wantError: true,
+		},
+		{
+			description: "invalid manifest",
+			obj:         propertyObject{value: dbus.MakeVariant(`{"version":`)},
+			wantError:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			got, err := workerManifest(test.obj)
+			if test.wantError {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got.WorkerManifest, test.want) {
+				t.Errorf("%v", cmp.Diff(got.WorkerManifest, test.want))
+			}
+		})
+	}
+}
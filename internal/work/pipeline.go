@@ -0,0 +1,181 @@
+package work
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redhatinsights/yggdrasil"
+	"github.com/subpop/go-log"
+)
+
+// MetadataKeyPipeline is the data message metadata key that optionally
+// contains a pipeline descriptor: a JSON array of steps, each with a
+// "directive" and optional "metadata". A message with a pipeline descriptor is
+// dispatched to each step's worker in turn instead of to its own directive.
+const MetadataKeyPipeline = "pipeline"
+
+// PipelineStep is a step of a pipeline descriptor.
+type PipelineStep struct {
+	Directive string            `json:"directive"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// PipelineResult is the content of the data message sent to the server when a
+// pipeline finishes.
+type PipelineResult struct {
+	Status ResultStatus    `json:"status"`
+	Steps  []MessageResult `json:"steps"`
+}
+
+// parsePipeline parses a pipeline descriptor.
+func parsePipeline(value string) ([]PipelineStep, error) {
+	var steps []PipelineStep
+	if err := json.Unmarshal([]byte(value), &steps); err != nil {
+		return nil, fmt.Errorf("cannot parse pipeline: %w", err)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("cannot parse pipeline: no steps")
+	}
+	for i, step := range steps {
+		if step.Directive == "" {
+			return nil, fmt.Errorf("cannot parse pipeline: step %v has no directive", i)
+		}
+	}
+	return steps, nil
+}
+
+// newStepData creates the data message dispatched to the worker of step. The
+// message is in response to the pipeline's message, and its metadata combines
+// the pipeline's metadata (without the descriptor) with the step's metadata.
+func newStepData(pipeline yggdrasil.Data, step PipelineStep, messageID string, content []byte) yggdrasil.Data {
+	metadata := make(map[string]string, len(pipeline.Metadata)+len(step.Metadata))
+	for k, v := range pipeline.Metadata {
+		if k != MetadataKeyPipeline {
+			metadata[k] = v
+		}
+	}
+	for k, v := range step.Metadata {
+		metadata[k] = v
+	}
+
+	return yggdrasil.Data{
+		Type:       yggdrasil.MessageTypeData,
+		MessageID:  messageID,
+		ResponseTo: pipeline.MessageID,
+		Version:    1,
+		Sent:       time.Now(),
+		Directive:  step.Directive,
+		Metadata:   metadata,
+		Content:    content,
+	}
+}
+
+// stepOutput returns output as a JSON value: output itself if it is valid
+// JSON, otherwise a JSON string. Empty output is omitted.
+func stepOutput(output []byte) json.RawMessage {
+	if len(output) == 0 {
+		return nil
+	}
+	if json.Valid(output) {
+		return output
+	}
+	data, err := json.Marshal(string(output))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// runPipeline dispatches each step of the pipeline carried by data in turn,
+// using the data a step's worker transmits as the content of the next step.
+// The pipeline stops at the first step that does not complete. The result of
+// every step is sent to the server in a single data message in response to
+// data.
+func (d *Dispatcher) runPipeline(data yggdrasil.Data) {
+	steps, err := parsePipeline(data.Metadata[MetadataKeyPipeline])
+	if err != nil {
+		log.Errorf("cannot run pipeline of message %v: %v", data.MessageID, err)
+		d.reportDispatchError(data, newDispatchError(ErrorCodeInvalidPipeline, err))
+		return
+	}
+
+	result := PipelineResult{Status: ResultStatusCompleted}
+	content := []byte(data.Content)
+	for _, step := range steps {
+		if result.Status != ResultStatusCompleted {
+			result.Steps = append(result.Steps, MessageResult{
+				Directive: step.Directive,
+				Status:    ResultStatusSkipped,
+			})
+			continue
+		}
+
+		var stepResult MessageResult
+		stepResult, content = d.runPipelineStep(data, step, content)
+		if stepResult.Status != ResultStatusCompleted {
+			result.Status = ResultStatusFailed
+		}
+		result.Steps = append(result.Steps, stepResult)
+	}
+
+	log.Infof("pipeline of message %v %v", data.MessageID, result.Status)
+	d.sendPipelineResult(data, result)
+}
+
+// runPipelineStep dispatches step to its worker with content, waiting for the
+// worker to finish. It returns the result of the step and the data the worker
+// transmitted.
+func (d *Dispatcher) runPipelineStep(
+	pipeline yggdrasil.Data,
+	step PipelineStep,
+	content []byte,
+) (MessageResult, []byte) {
+	messageID := uuid.New().String()
+	log.Debugf("dispatching step %v of pipeline %v to worker %v", messageID, pipeline.MessageID, step.Directive)
+
+	result, output := d.runChild(newStepData(pipeline, step, messageID, content), true)
+	if result.Status == ResultStatusCompleted {
+		result.Output = stepOutput(output)
+	}
+
+	return result, output
+}
+
+// sendPipelineResult sends result to the server in a data message in response
+// to the pipeline's message, unless the pipeline was created on the host or
+// has already finished.
+func (d *Dispatcher) sendPipelineResult(pipeline yggdrasil.Data, result PipelineResult) {
+	content, err := json.Marshal(result)
+	if err != nil {
+		log.Errorf("cannot marshal result of pipeline %v: %v", pipeline.MessageID, err)
+		return
+	}
+	if !d.finishMessage(pipeline.MessageID) {
+		log.Debugf("not sending result of pipeline %v: it already finished", pipeline.MessageID)
+		return
+	}
+	if d.isLocal(pipeline.MessageID) {
+		log.Debugf("not sending result of local pipeline %v", pipeline.MessageID)
+		return
+	}
+
+	resp, err := d.sendData(yggdrasil.Data{
+		Type:       yggdrasil.MessageTypeData,
+		MessageID:  uuid.New().String(),
+		ResponseTo: pipeline.MessageID,
+		Version:    1,
+		Sent:       time.Now(),
+		Directive:  pipeline.Directive,
+		Metadata:   map[string]string{},
+		Content:    content,
+	})
+	if err != nil {
+		log.Errorf("cannot send result of pipeline %v: %v", pipeline.MessageID, err)
+		return
+	}
+	if resp.Code != TransmitResponseOK {
+		log.Warnf("unexpected response code %v sending result of pipeline %v", resp.Code, pipeline.MessageID)
+	}
+}
@@ -0,0 +1,141 @@
+package work
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/redhatinsights/yggdrasil"
+)
+
+func TestParsePipeline(t *testing.T) {
+	tests := []struct {
+		description string
+		input       string
+		want        []PipelineStep
+		wantError   bool
+	}{
+		{
+			description: "steps",
+			input:       `[{"directive":"facts"},{"directive":"echo","metadata":{"a":"b"}}]`,
+			want: []PipelineStep{
+				{Directive: "facts"},
+				{Directive: "echo", Metadata: map[string]string{"a": "b"}},
+			},
+		},
+		{
+			description: "invalid JSON",
+			input:       `{"directive":"facts"}`,
+			wantError:   true,
+		},
+		{
+			description: "no steps",
+			input:       `[]`,
+			wantError:   true,
+		},
+		{
+			description: "missing directive",
+			input:       `[{"directive":"facts"},{"metadata":{"a":"b"}}]`,
+			wantError:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			got, err := parsePipeline(test.input)
+
+			if test.wantError {
+				if err == nil {
+					t.Errorf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got, test.want) {
+				t.Errorf("%#v != %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewStepData(t *testing.T) {
+	tests := []struct {
+		description string
+		pipeline    yggdrasil.Data
+		step        PipelineStep
+		want        yggdrasil.Data
+	}{
+		{
+			description: "metadata merged",
+			pipeline: yggdrasil.Data{
+				MessageID: "1",
+				Directive: "pipeline",
+				Metadata: map[string]string{
+					MetadataKeyPipeline: `[{"directive":"echo"}]`,
+					"a":                 "pipeline",
+					"b":                 "pipeline",
+				},
+			},
+			step: PipelineStep{
+				Directive: "echo",
+				Metadata:  map[string]string{"b": "step"},
+			},
+			want: yggdrasil.Data{
+				Type:       yggdrasil.MessageTypeData,
+				MessageID:  "2",
+				ResponseTo: "1",
+				Version:    1,
+				Directive:  "echo",
+				Metadata:   map[string]string{"a": "pipeline", "b": "step"},
+				Content:    []byte("content"),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			got := newStepData(test.pipeline, test.step, "2", []byte("content"))
+
+			if !cmp.Equal(got, test.want, cmpopts.IgnoreFields(yggdrasil.Data{}, "Sent")) {
+				t.Errorf("%#v != %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestStepOutput(t *testing.T) {
+	tests := []struct {
+		description string
+		input       []byte
+		want        json.RawMessage
+	}{
+		{
+			description: "JSON",
+			input:       []byte(`{"a":1}`),
+			want:        json.RawMessage(`{"a":1}`),
+		},
+		{
+			description: "text",
+			input:       []byte("hello"),
+			want:        json.RawMessage(`"hello"`),
+		},
+		{
+			description: "empty",
+			input:       []byte{},
+			want:        nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			got := stepOutput(test.input)
+
+			if !cmp.Equal(got, test.want) {
+				t.Errorf("%s != %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package work
+
+import (
+	"path"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/redhatinsights/yggdrasil/internal/config"
+	"github.com/redhatinsights/yggdrasil/ipc"
+	"github.com/subpop/go-log"
+)
+
+// policyAllows returns true if patterns, an allow-list of a worker's transmit
+// policy, permits value. Each pattern is matched as by path.Match. A nil list
+// permits any value.
+func policyAllows(patterns []string, value string) bool {
+	if patterns == nil {
+		return true
+	}
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// transmitDirectiveAllowed returns true if the transmit policy of worker
+// permits transmitting data upstream to directive.
+func transmitDirectiveAllowed(worker string, directive string) bool {
+	return policyAllows(config.DefaultConfig.Workers[worker].TransmitDirectives, directive)
+}
+
+// transmitHostAllowed returns true if the transmit policy of worker permits
+// transmitting data to host through an HTTP URL.
+func transmitHostAllowed(worker string, host string) bool {
+	return policyAllows(config.DefaultConfig.Workers[worker].TransmitHosts, host)
+}
+
+// denyTransmit records that worker was denied transmitting data to addr by
+// emitting a DENIED event on its behalf, and returns the error returned to the
+// worker.
+func (d *Dispatcher) denyTransmit(
+	worker string,
+	addr string,
+	messageID string,
+	responseTo string,
+	reason string,
+) *dbus.Error {
+	log.Warnf("denied worker %v transmitting message %v to %v: %v", worker, messageID, addr, reason)
+	d.emitWorkerEvent(ipc.WorkerEvent{
+		Worker:     worker,
+		Name:       ipc.WorkerEventNameDenied,
+		MessageID:  messageID,
+		ResponseTo: responseTo,
+		Data: map[string]string{
+			ipc.WorkerEventDataKeyAddr:    addr,
+			ipc.WorkerEventDataKeyMessage: reason,
+		},
+	})
+	return NewDBusError(ipc.ErrorNameTransmit, reason)
+}
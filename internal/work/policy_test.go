@@ -0,0 +1,101 @@
+package work
+
+import (
+	"testing"
+
+	"github.com/redhatinsights/yggdrasil/internal/config"
+)
+
+func TestTransmitPolicy(t *testing.T) {
+	config.DefaultConfig.Workers = map[string]config.WorkerConfig{
+		"insights": {
+			TransmitDirectives: []string{"insights", "insights@*"},
+			TransmitHosts:      []string{"*.example.com"},
+		},
+		"echo": {
+			TransmitDirectives: []string{},
+			TransmitHosts:      []string{},
+		},
+	}
+	defer func() {
+		config.DefaultConfig.Workers = nil
+	}()
+
+	tests := []struct {
+		description string
+		worker      string
+		directive   string
+		host        string
+		want        bool
+	}{
+		{
+			description: "directive allowed",
+			worker:      "insights",
+			directive:   "insights",
+			want:        true,
+		},
+		{
+			description: "directive pattern allowed",
+			worker:      "insights",
+			directive:   "insights@v2",
+			want:        true,
+		},
+		{
+			description: "directive denied",
+			worker:      "insights",
+			directive:   "remediation",
+			want:        false,
+		},
+		{
+			description: "directive denied by empty list",
+			worker:      "echo",
+			directive:   "echo",
+			want:        false,
+		},
+		{
+			description: "directive without policy",
+			worker:      "facts",
+			directive:   "remediation",
+			want:        true,
+		},
+		{
+			description: "host pattern allowed",
+			worker:      "insights",
+			host:        "cert.console.example.com",
+			want:        true,
+		},
+		{
+			description: "host denied",
+			worker:      "insights",
+			host:        "example.org",
+			want:        false,
+		},
+		{
+			description: "host denied by empty list",
+			worker:      "echo",
+			host:        "api.example.com",
+			want:        false,
+		},
+		{
+			description: "host without policy",
+			worker:      "facts",
+			host:        "example.org",
+			want:        true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			var got bool
+			if test.host != "" {
+				got = transmitHostAllowed(test.worker, test.host)
+			} else {
+				got = transmitDirectiveAllowed(test.worker, test.directive)
+			}
+
+			if got != test.want {
+				t.Errorf("%v != %v", got, test.want)
+			}
+		})
+	}
+}
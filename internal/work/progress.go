@@ -0,0 +1,82 @@
+package work
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redhatinsights/yggdrasil"
+	"github.com/redhatinsights/yggdrasil/internal/config"
+	"github.com/redhatinsights/yggdrasil/ipc"
+	"github.com/subpop/go-log"
+)
+
+// handleProgress validates the progress reported in the data of a WORKING
+// event, removing the progress values from the data if they are invalid. Valid
+// progress is sent to the server, at most once every ProgressEventInterval for
+// each message.
+func (d *Dispatcher) handleProgress(event *ipc.WorkerEvent) {
+	progress, err := ipc.ParseProgress(event.Data)
+	if err != nil {
+		log.Warnf(
+			"ignoring invalid progress of message %v from worker %v: %v",
+			event.MessageID,
+			event.Worker,
+			err,
+		)
+		delete(event.Data, ipc.WorkerEventDataKeyPercent)
+		delete(event.Data, ipc.WorkerEventDataKeyStep)
+		delete(event.Data, ipc.WorkerEventDataKeyTotalSteps)
+		return
+	}
+	if progress == nil {
+		return
+	}
+
+	msg, has := d.inflight.Get(event.MessageID)
+	if !has || d.isChildMessage(event.MessageID) {
+		return
+	}
+
+	now := time.Now()
+	if !shouldReportProgress(
+		*progress,
+		msg.lastProgress,
+		now,
+		config.DefaultConfig.ProgressEventInterval,
+	) {
+		return
+	}
+	msg.lastProgress = now
+
+	d.sendMessageEvent(msg.data.MessageID, newProgressEvent(msg.data, *progress))
+}
+
+// shouldReportProgress returns true if progress reported at now should be sent
+// to the server, given the time the last progress was sent. Progress is never
+// sent if interval is zero. Completed progress is always sent.
+func shouldReportProgress(progress ipc.Progress, last, now time.Time, interval time.Duration) bool {
+	if interval <= 0 {
+		return false
+	}
+	if progress.Percent == 100 || last.IsZero() {
+		return true
+	}
+	return now.Sub(last) >= interval
+}
+
+// newProgressEvent creates an event message informing the server of the
+// progress of a worker working on data.
+func newProgressEvent(data yggdrasil.Data, progress ipc.Progress) yggdrasil.Event {
+	eventData := progress.Data()
+	eventData["directive"] = data.Directive
+
+	return yggdrasil.Event{
+		Type:       yggdrasil.MessageTypeEvent,
+		MessageID:  uuid.New().String(),
+		ResponseTo: data.MessageID,
+		Version:    1,
+		Sent:       time.Now(),
+		Content:    string(yggdrasil.EventNameProgress),
+		Data:       eventData,
+	}
+}
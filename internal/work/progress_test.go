@@ -0,0 +1,101 @@
+package work
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/redhatinsights/yggdrasil/ipc"
+)
+
+func TestShouldReportProgress(t *testing.T) {
+	now := time.Date(2000, time.January, 1, 0, 0, 10, 0, time.UTC)
+
+	tests := []struct {
+		description string
+		input       struct {
+			progress ipc.Progress
+			last     time.Time
+			interval time.Duration
+		}
+		want bool
+	}{
+		{
+			description: "disabled",
+			input: struct {
+				progress ipc.Progress
+				last     time.Time
+				interval time.Duration
+			}{
+				progress: ipc.Progress{Percent: 50},
+			},
+			want: false,
+		},
+		{
+			description: "first",
+			input: struct {
+				progress ipc.Progress
+				last     time.Time
+				interval time.Duration
+			}{
+				progress: ipc.Progress{Percent: 50},
+				interval: 5 * time.Second,
+			},
+			want: true,
+		},
+		{
+			description: "throttled",
+			input: struct {
+				progress ipc.Progress
+				last     time.Time
+				interval time.Duration
+			}{
+				progress: ipc.Progress{Percent: 50},
+				last:     now.Add(-2 * time.Second),
+				interval: 5 * time.Second,
+			},
+			want: false,
+		},
+		{
+			description: "interval elapsed",
+			input: struct {
+				progress ipc.Progress
+				last     time.Time
+				interval time.Duration
+			}{
+				progress: ipc.Progress{Percent: 50},
+				last:     now.Add(-5 * time.Second),
+				interval: 5 * time.Second,
+			},
+			want: true,
+		},
+		{
+			description: "complete",
+			input: struct {
+				progress ipc.Progress
+				last     time.Time
+				interval time.Duration
+			}{
+				progress: ipc.Progress{Percent: 100},
+				last:     now.Add(-2 * time.Second),
+				interval: 5 * time.Second,
+			},
+			want: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			got := shouldReportProgress(
+				test.input.progress,
+				test.input.last,
+				now,
+				test.input.interval,
+			)
+
+			if !cmp.Equal(got, test.want) {
+				t.Errorf("%v != %v", got, test.want)
+			}
+		})
+	}
+}
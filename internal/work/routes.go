@@ -0,0 +1,56 @@
+package work
+
+import (
+	"strings"
+
+	"github.com/redhatinsights/yggdrasil"
+	"github.com/redhatinsights/yggdrasil/internal/config"
+	"github.com/subpop/go-log"
+)
+
+// MetadataKeyRequestedDirective is the data message metadata key set when a
+// message is routed to a worker other than the one named by its directive. It
+// contains the directive of the message as received, such as "foo@v2".
+const MetadataKeyRequestedDirective = "requested_directive"
+
+// DirectiveVersionSeparator separates the name of a versioned directive from
+// its version, as in "foo@v2".
+const DirectiveVersionSeparator = "@"
+
+// resolveDirective returns the worker that handles directive according to
+// routes. A directive with a route is handled by the route's worker. A
+// versioned directive without a route of its own is routed like its name.
+// Any other directive is handled by the worker of the same name.
+func resolveDirective(routes config.RouteConfig, directive string) string {
+	if worker, has := routes.Directives[directive]; has {
+		return worker
+	}
+	if name, _, versioned := strings.Cut(directive, DirectiveVersionSeparator); versioned {
+		if worker, has := routes.Directives[name]; has {
+			return worker
+		}
+		return name
+	}
+	return directive
+}
+
+// routeMessage returns data addressed to worker instead of its directive,
+// recording the original directive in its metadata.
+func routeMessage(data yggdrasil.Data, worker string) yggdrasil.Data {
+	if worker == data.Directive {
+		return data
+	}
+
+	metadata := make(map[string]string, len(data.Metadata)+1)
+	for k, v := range data.Metadata {
+		metadata[k] = v
+	}
+	if _, has := metadata[MetadataKeyRequestedDirective]; !has {
+		metadata[MetadataKeyRequestedDirective] = data.Directive
+	}
+
+	log.Debugf("routing message %v for directive %v to worker %v", data.MessageID, data.Directive, worker)
+	data.Metadata = metadata
+	data.Directive = worker
+	return data
+}
@@ -0,0 +1,199 @@
+package work
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/redhatinsights/yggdrasil"
+	"github.com/redhatinsights/yggdrasil/internal/config"
+)
+
+func TestResolveDirective(t *testing.T) {
+	routes := config.RouteConfig{
+		Directives: map[string]string{
+			"rhc-worker-playbook": "rhc_worker_playbook",
+			"package_manager":     "pkg",
+			"package_manager@v2":  "pkg_v2",
+		},
+		Fallback: "catchall",
+	}
+
+	tests := []struct {
+		description string
+		input       string
+		want        string
+	}{
+		{
+			description: "unrouted",
+			input:       "echo",
+			want:        "echo",
+		},
+		{
+			description: "alias",
+			input:       "rhc-worker-playbook",
+			want:        "rhc_worker_playbook",
+		},
+		{
+			description: "versioned route",
+			input:       "package_manager@v2",
+			want:        "pkg_v2",
+		},
+		{
+			description: "versioned without route",
+			input:       "package_manager@v3",
+			want:        "pkg",
+		},
+		{
+			description: "versioned unrouted",
+			input:       "echo@v2",
+			want:        "echo",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			got := resolveDirective(routes, test.input)
+
+			if got != test.want {
+				t.Errorf("%v != %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRouteMessage(t *testing.T) {
+	tests := []struct {
+		description string
+		input       yggdrasil.Data
+		worker      string
+		want        yggdrasil.Data
+	}{
+		{
+			description: "same worker",
+			input:       yggdrasil.Data{Directive: "echo", Metadata: map[string]string{"a": "b"}},
+			worker:      "echo",
+			want:        yggdrasil.Data{Directive: "echo", Metadata: map[string]string{"a": "b"}},
+		},
+		{
+			description: "routed",
+			input:       yggdrasil.Data{Directive: "echo@v2", Metadata: map[string]string{"a": "b"}},
+			worker:      "echo",
+			want: yggdrasil.Data{
+				Directive: "echo",
+				Metadata:  map[string]string{"a": "b", MetadataKeyRequestedDirective: "echo@v2"},
+			},
+		},
+		{
+			description: "routed twice",
+			input: yggdrasil.Data{
+				Directive: "echo",
+				Metadata:  map[string]string{MetadataKeyRequestedDirective: "echo@v2"},
+			},
+			worker: "catchall",
+			want: yggdrasil.Data{
+				Directive: "catchall",
+				Metadata:  map[string]string{MetadataKeyRequestedDirective: "echo@v2"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			got := routeMessage(test.input, test.worker)
+
+			if !cmp.Equal(got, test.want) {
+				t.Errorf("%v", cmp.Diff(got, test.want))
+			}
+		})
+	}
+}
+
+func TestFlattenDispatchers(t *testing.T) {
+	config.DefaultConfig.Routes = config.RouteConfig{
+		Directives: map[string]string{
+			"playbook":            "rhc_worker_playbook",
+			"package_manager@v2":  "pkg-v2",
+			"disconnected_worker": "missing",
+		},
+	}
+	defer func() {
+		config.DefaultConfig.Routes = config.RouteConfig{}
+	}()
+
+	d := &Dispatcher{}
+	d.features.Set("rhc_worker_playbook", map[string]string{"version": "1"})
+	d.features.Set("pkg_v2", map[string]string{"version": "2"})
+
+	got := d.FlattenDispatchers()
+
+	want := map[string]map[string]string{
+		"rhc_worker_playbook": {"version": "1"},
+		"rhc-worker-playbook": {"version": "1"},
+		"playbook":            {"version": "1"},
+		"pkg_v2":              {"version": "2"},
+		"pkg-v2":              {"version": "2"},
+		"package_manager@v2":  {"version": "2"},
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("%v", cmp.Diff(got, want))
+	}
+}
+
+func TestCancelWorker(t *testing.T) {
+	config.DefaultConfig.Routes = config.RouteConfig{
+		Directives: map[string]string{
+			"package_manager": "pkg",
+		},
+		Fallback: "catchall",
+	}
+	defer func() {
+		config.DefaultConfig.Routes = config.RouteConfig{}
+	}()
+
+	d := &Dispatcher{}
+	d.inflight.Set("1", &inflightMessage{
+		data: yggdrasil.Data{MessageID: "1", Directive: "catchall"},
+	})
+
+	tests := []struct {
+		description string
+		directive   string
+		cancelID    string
+		want        string
+	}{
+		{
+			description: "in-flight",
+			directive:   "unknown",
+			cancelID:    "1",
+			want:        "catchall",
+		},
+		{
+			description: "alias",
+			directive:   "package_manager",
+			cancelID:    "2",
+			want:        "pkg",
+		},
+		{
+			description: "versioned",
+			directive:   "package_manager@v2",
+			cancelID:    "2",
+			want:        "pkg",
+		},
+		{
+			description: "scrubbed",
+			directive:   "rhc-worker-playbook",
+			cancelID:    "2",
+			want:        "rhc_worker_playbook",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			got := d.cancelWorker(test.directive, test.cancelID)
+
+			if got != test.want {
+				t.Errorf("%v != %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package work
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/redhatinsights/yggdrasil"
+	"github.com/redhatinsights/yggdrasil/internal/schedule"
+	"github.com/subpop/go-log"
+)
+
+// Data message metadata keys that schedule the message to be dispatched later.
+// Each value is either an RFC 3339 timestamp or a duration (such as "90m")
+// relative to when the message is received.
+const (
+	// MetadataKeyNotBefore is the metadata key containing the earliest time
+	// the message may be dispatched.
+	MetadataKeyNotBefore = "not_before"
+
+	// MetadataKeyNotAfter is the metadata key containing the latest time the
+	// message may be dispatched. A message that is not dispatched by then is
+	// reported as expired.
+	MetadataKeyNotAfter = "not_after"
+)
+
+// parseSchedule parses the not_before and not_after metadata values of data,
+// relative to now. Either value is zero if it is absent.
+func parseSchedule(data yggdrasil.Data, now time.Time) (time.Time, time.Time, error) {
+	notBefore, err := schedule.ParseTime(data.Metadata[MetadataKeyNotBefore], now)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("cannot parse %v: %w", MetadataKeyNotBefore, err)
+	}
+	// A not_after time of day is the first one after not_before, so that a
+	// window such as 02:00 to 04:00 that has already opened today is the
+	// window of the next day.
+	notAfterFrom := now
+	if value := data.Metadata[MetadataKeyNotAfter]; schedule.IsClockTime(value) && !notBefore.IsZero() {
+		notAfterFrom = notBefore
+	}
+	notAfter, err := schedule.ParseTime(data.Metadata[MetadataKeyNotAfter], notAfterFrom)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("cannot parse %v: %w", MetadataKeyNotAfter, err)
+	}
+	if !notBefore.IsZero() && !notAfter.IsZero() && notAfter.Before(notBefore) {
+		return time.Time{}, time.Time{}, fmt.Errorf(
+			"%v %v is before %v %v",
+			MetadataKeyNotAfter,
+			notAfter.Format(time.RFC3339),
+			MetadataKeyNotBefore,
+			notBefore.Format(time.RFC3339),
+		)
+	}
+	return notBefore, notAfter, nil
+}
+
+// scheduleMessage adds data to the dispatcher's schedule if its not_before
+// value is in the future. It returns false if data should be dispatched now.
+// A message whose not_after value has passed is not dispatched; an error is
+// returned instead.
+func (d *Dispatcher) scheduleMessage(data yggdrasil.Data) (bool, error) {
+	now := time.Now()
+	notBefore, notAfter, err := parseSchedule(data, now)
+	if err != nil {
+		return false, newDispatchError(ErrorCodeInvalidSchedule, err)
+	}
+	if !notAfter.IsZero() && now.After(notAfter) {
+		return false, newDispatchError(
+			ErrorCodeExpired,
+			fmt.Errorf("message expired at %v", notAfter.Format(time.RFC3339)),
+		)
+	}
+	if !notBefore.After(now) {
+		return false, nil
+	}
+
+	entry := schedule.Entry{
+		Data:      data,
+		NotBefore: notBefore,
+		NotAfter:  notAfter,
+	}
+	if err := d.Schedule.Add(entry); err != nil {
+		return false, newDispatchError(
+			ErrorCodeDispatchFailed,
+			fmt.Errorf("cannot schedule message: %w", err),
+		)
+	}
+	d.armSchedule(entry)
+	log.Infof(
+		"scheduled message %v for worker %v at %v",
+		data.MessageID,
+		data.Directive,
+		notBefore.Format(time.RFC3339),
+	)
+
+	return true, nil
+}
+
+// armSchedule starts a timer that dispatches the scheduled entry at its
+// NotBefore time.
+func (d *Dispatcher) armSchedule(entry schedule.Entry) {
+	messageID := entry.Data.MessageID
+	d.scheduled.Set(messageID, time.AfterFunc(time.Until(entry.NotBefore), func() {
+		d.runScheduled(messageID)
+	}))
+}
+
+// resumeSchedule starts timers for the entries of the dispatcher's schedule,
+// such as those saved before yggd restarted. Entries whose time has passed are
+// dispatched immediately.
+func (d *Dispatcher) resumeSchedule() {
+	for _, entry := range d.Schedule.List() {
+		d.armSchedule(entry)
+	}
+}
+
+// runScheduled removes the message with the given ID from the schedule and
+// dispatches it, unless its not_after time has passed.
+func (d *Dispatcher) runScheduled(messageID string) {
+	d.scheduled.Del(messageID)
+	entry, has, err := d.Schedule.Remove(messageID)
+	if err != nil {
+		log.Errorf("cannot remove message %v from schedule: %v", messageID, err)
+	}
+	if !has {
+		// The message was cancelled.
+		return
+	}
+
+	if !entry.NotAfter.IsZero() && time.Now().After(entry.NotAfter) {
+		log.Errorf("scheduled message %v expired at %v", messageID, entry.NotAfter)
+		d.reportDispatchError(entry.Data, newDispatchError(
+			ErrorCodeExpired,
+			fmt.Errorf("message expired at %v", entry.NotAfter.Format(time.RFC3339)),
+		))
+		return
+	}
+
+	log.Debugf("dispatching scheduled message %v to worker %v", messageID, entry.Data.Directive)
+	d.dispatchInbound(entry.Data)
+}
+
+// CancelScheduled removes the message with the given ID from the schedule so
+// that it is never dispatched, reporting the cancellation to the server. It
+// returns false if the message is not scheduled.
+func (d *Dispatcher) CancelScheduled(messageID string) (bool, error) {
+	if timer, has := d.scheduled.Pop(messageID); has {
+		timer.Stop()
+	}
+	entry, has, err := d.Schedule.Remove(messageID)
+	if err != nil {
+		return has, fmt.Errorf("cannot remove message %v from schedule: %w", messageID, err)
+	}
+	if !has {
+		return false, nil
+	}
+
+	log.Infof("cancelled scheduled message %v", messageID)
+	d.reportDispatchError(entry.Data, newDispatchError(
+		ErrorCodeCancelled,
+		fmt.Errorf("scheduled message cancelled"),
+	))
+
+	return true, nil
+}
+
+// ListScheduled returns the messages in the schedule, ordered by the time they
+// are to be dispatched.
+func (d *Dispatcher) ListScheduled() []schedule.Entry {
+	return d.Schedule.List()
+}
@@ -0,0 +1,166 @@
+package work
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redhatinsights/yggdrasil"
+	"github.com/redhatinsights/yggdrasil/internal/schedule"
+)
+
+func TestParseSchedule(t *testing.T) {
+	now := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		description   string
+		input         map[string]string
+		wantNotBefore time.Time
+		wantNotAfter  time.Time
+		wantError     bool
+	}{
+		{
+			description: "unscheduled",
+			input:       map[string]string{},
+		},
+		{
+			description: "window",
+			input: map[string]string{
+				MetadataKeyNotBefore: "2000-01-01T02:00:00Z",
+				MetadataKeyNotAfter:  "2000-01-01T04:00:00Z",
+			},
+			wantNotBefore: time.Date(2000, time.January, 1, 2, 0, 0, 0, time.UTC),
+			wantNotAfter:  time.Date(2000, time.January, 1, 4, 0, 0, 0, time.UTC),
+		},
+		{
+			description: "delay",
+			input: map[string]string{
+				MetadataKeyNotBefore: "10m",
+			},
+			wantNotBefore: time.Date(2000, time.January, 1, 0, 10, 0, 0, time.UTC),
+		},
+		{
+			description: "time of day window",
+			input: map[string]string{
+				MetadataKeyNotBefore: "02:00",
+				MetadataKeyNotAfter:  "04:00",
+			},
+			wantNotBefore: time.Date(2000, time.January, 1, 2, 0, 0, 0, time.UTC),
+			wantNotAfter:  time.Date(2000, time.January, 1, 4, 0, 0, 0, time.UTC),
+		},
+		{
+			description: "time of day window across midnight",
+			input: map[string]string{
+				MetadataKeyNotBefore: "23:00",
+				MetadataKeyNotAfter:  "01:00",
+			},
+			wantNotBefore: time.Date(2000, time.January, 1, 23, 0, 0, 0, time.UTC),
+			wantNotAfter:  time.Date(2000, time.January, 2, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			description: "not_after before not_before",
+			input: map[string]string{
+				MetadataKeyNotBefore: "2h",
+				MetadataKeyNotAfter:  "1h",
+			},
+			wantError: true,
+		},
+		{
+			description: "invalid not_before",
+			input: map[string]string{
+				MetadataKeyNotBefore: "later",
+			},
+			wantError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			notBefore, notAfter, err := parseSchedule(yggdrasil.Data{Metadata: test.input}, now)
+
+			if test.wantError {
+				if err == nil {
+					t.Errorf("expected error, got %v, %v", notBefore, notAfter)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !notBefore.Equal(test.wantNotBefore) {
+				t.Errorf("%v != %v", notBefore, test.wantNotBefore)
+			}
+			if !notAfter.Equal(test.wantNotAfter) {
+				t.Errorf("%v != %v", notAfter, test.wantNotAfter)
+			}
+		})
+	}
+}
+
+func TestScheduleMessage(t *testing.T) {
+	tests := []struct {
+		description   string
+		input         map[string]string
+		want          bool
+		wantErrorCode ErrorCode
+	}{
+		{
+			description: "unscheduled",
+			input:       map[string]string{},
+			want:        false,
+		},
+		{
+			description: "future",
+			input:       map[string]string{MetadataKeyNotBefore: "1h"},
+			want:        true,
+		},
+		{
+			description: "past",
+			input:       map[string]string{MetadataKeyNotBefore: "-1h"},
+			want:        false,
+		},
+		{
+			description:   "expired",
+			input:         map[string]string{MetadataKeyNotAfter: "-1h"},
+			wantErrorCode: ErrorCodeExpired,
+		},
+		{
+			description:   "invalid",
+			input:         map[string]string{MetadataKeyNotAfter: "never"},
+			wantErrorCode: ErrorCodeInvalidSchedule,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			d := &Dispatcher{Schedule: schedule.New()}
+			data := yggdrasil.Data{
+				MessageID: "1",
+				Directive: "echo",
+				Metadata:  test.input,
+			}
+
+			got, err := d.scheduleMessage(data)
+			if timer, has := d.scheduled.Pop("1"); has {
+				timer.Stop()
+			}
+
+			if test.wantErrorCode != "" {
+				var dispatchErr *DispatchError
+				if !errors.As(err, &dispatchErr) || dispatchErr.Code != test.wantErrorCode {
+					t.Errorf("expected error with code %v, got %v", test.wantErrorCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("%v != %v", got, test.want)
+			}
+			if n := len(d.Schedule.List()); (n == 1) != test.want {
+				t.Errorf("unexpected number of scheduled messages: %v", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package work
+
+import "github.com/godbus/dbus/v5"
+
+// streamsContent returns true if the worker exported as obj receives detached
+// content through a file descriptor passed to its DispatchStream method rather
+// than as a byte array. Workers that do not export the StreamContent property
+// receive content as a byte array.
+func streamsContent(obj dbus.BusObject) bool {
+	v, err := obj.GetProperty("com.redhat.Yggdrasil1.Worker1.StreamContent")
+	if err != nil {
+		return false
+	}
+	stream, ok := v.Value().(bool)
+	return ok && stream
+}
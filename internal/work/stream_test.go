@@ -0,0 +1,74 @@
+package work
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	internalhttp "github.com/redhatinsights/yggdrasil/internal/http"
+)
+
+func TestDownloadContent(t *testing.T) {
+	tests := []struct {
+		description string
+		content     string
+		want        string
+	}{
+		{
+			description: "empty",
+			content:     "",
+			want:        "",
+		},
+		{
+			description: "content",
+			content:     "hello world",
+			want:        "hello world",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, test.content)
+			}))
+			defer server.Close()
+
+			dir := t.TempDir()
+			d := &Dispatcher{
+				HTTPClient: internalhttp.NewHTTPClient(nil, "test"),
+				ContentDir: dir,
+			}
+
+			URL, err := url.Parse(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f, _, err := d.downloadContent(URL, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			got, err := io.ReadAll(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(string(got), test.want) {
+				t.Errorf("%#v != %#v", string(got), test.want)
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 0 {
+				t.Errorf("content directory is not empty: %v", entries)
+			}
+		})
+	}
+}
@@ -0,0 +1,356 @@
+package work
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/redhatinsights/yggdrasil/internal/config"
+	"github.com/redhatinsights/yggdrasil/ipc"
+	"github.com/subpop/go-log"
+)
+
+const (
+	// MetadataKeyUploadMethod is the metadata key of data transmitted to a URL
+	// whose value is the HTTP method the data is uploaded with: POST (the
+	// default) or PUT.
+	MetadataKeyUploadMethod = "upload_method"
+
+	// MetadataKeyUploadContentType is the metadata key of data transmitted to
+	// a URL whose value is the content type of the data.
+	MetadataKeyUploadContentType = "upload_content_type"
+
+	// MetadataKeyUploadMultipart is the metadata key of data transmitted to a
+	// URL whose value, if not empty, is the name of the form field the data is
+	// uploaded in as a multipart/form-data file.
+	MetadataKeyUploadMultipart = "upload_multipart"
+
+	// MetadataKeyUploadFilename is the metadata key of data transmitted to a
+	// URL whose value is the file name of a multipart/form-data upload.
+	MetadataKeyUploadFilename = "upload_filename"
+
+	// MetadataKeyUploadRetries is the metadata key of data transmitted to a
+	// URL whose value is the number of times the upload is retried after it
+	// fails to connect or receives a server error response.
+	MetadataKeyUploadRetries = "upload_retries"
+)
+
+// defaultUploadFilename is the file name of a multipart/form-data upload
+// without an upload_filename metadata value.
+const defaultUploadFilename = "upload"
+
+// uploadRetryDelay is the duration waited before retrying a failed upload. It
+// doubles after each attempt.
+var uploadRetryDelay = time.Second
+
+// uploadOptions are the options of an upload, parsed from the metadata of the
+// transmitted data.
+type uploadOptions struct {
+	method      string
+	contentType string
+	field       string
+	filename    string
+	retries     int
+
+	// headers are the metadata values that are not upload options, which are
+	// sent as HTTP headers.
+	headers map[string]string
+}
+
+// parseUploadOptions parses the upload options in metadata.
+func parseUploadOptions(metadata map[string]string) (uploadOptions, error) {
+	opts := uploadOptions{
+		method:      http.MethodPost,
+		contentType: metadata[MetadataKeyUploadContentType],
+		field:       metadata[MetadataKeyUploadMultipart],
+		filename:    metadata[MetadataKeyUploadFilename],
+		headers:     map[string]string{},
+	}
+
+	if value, has := metadata[MetadataKeyUploadMethod]; has {
+		switch method := strings.ToUpper(value); method {
+		case http.MethodPost, http.MethodPut:
+			opts.method = method
+		default:
+			return uploadOptions{}, fmt.Errorf("unsupported upload method '%v'", value)
+		}
+	}
+
+	if value, has := metadata[MetadataKeyUploadRetries]; has {
+		retries, err := strconv.Atoi(value)
+		if err != nil || retries < 0 {
+			return uploadOptions{}, fmt.Errorf("invalid upload retries '%v'", value)
+		}
+		opts.retries = retries
+	}
+
+	if opts.filename == "" {
+		opts.filename = defaultUploadFilename
+	}
+
+	for k, v := range metadata {
+		switch k {
+		case MetadataKeyUploadMethod,
+			MetadataKeyUploadContentType,
+			MetadataKeyUploadMultipart,
+			MetadataKeyUploadFilename,
+			MetadataKeyUploadRetries:
+			continue
+		}
+		opts.headers[k] = v
+	}
+
+	return opts, nil
+}
+
+// uploadBody returns the content of an upload. It is called once for each
+// attempt to upload the content.
+type uploadBody func() (io.Reader, error)
+
+// bytesBody returns an uploadBody of data, which is sent with a Content-Length.
+func bytesBody(data []byte) uploadBody {
+	return func() (io.Reader, error) {
+		return bytes.NewReader(data), nil
+	}
+}
+
+// fileBody returns an uploadBody streaming the content read from f, which is
+// sent with chunked transfer encoding. The content can only be uploaded again
+// if f is seekable.
+func fileBody(f *os.File) uploadBody {
+	attempts := 0
+	return func() (io.Reader, error) {
+		if attempts > 0 {
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return nil, fmt.Errorf("cannot upload content again: %w", err)
+			}
+		}
+		attempts++
+		// Hide the Close method of f, which the HTTP client would otherwise
+		// call after the request is sent.
+		return struct{ io.Reader }{f}, nil
+	}
+}
+
+// multipartBody returns a reader of a multipart/form-data body with the content
+// read from r as its single file part, along with the content type of the body.
+func multipartBody(r io.Reader, opts uploadOptions) (io.Reader, string) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+
+	go func() {
+		contentType := opts.contentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", fmt.Sprintf(
+			`form-data; name="%v"; filename="%v"`,
+			quoteEscaper.Replace(opts.field),
+			quoteEscaper.Replace(opts.filename),
+		))
+		header.Set("Content-Type", contentType)
+
+		part, err := mw.CreatePart(header)
+		if err == nil {
+			_, err = io.Copy(part, r)
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	return pr, mw.FormDataContentType()
+}
+
+// quoteEscaper escapes the quoted parameters of a Content-Disposition header.
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// upload sends the content returned by body to URL as described by opts. The
+// upload is retried up to opts.retries times if the request fails or the
+// server responds with a server error status. The caller must close the body of
+// the returned response.
+func (d *Dispatcher) upload(URL string, opts uploadOptions, body uploadBody) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		r, err := body()
+		if err != nil {
+			return nil, err
+		}
+
+		headers := make(map[string]string, len(opts.headers)+1)
+		for k, v := range opts.headers {
+			headers[k] = v
+		}
+		if opts.field != "" {
+			r, headers["Content-Type"] = multipartBody(r, opts)
+		} else if opts.contentType != "" {
+			headers["Content-Type"] = opts.contentType
+		}
+
+		resp, err := d.HTTPClient.Send(opts.method, URL, headers, r)
+		if err == nil && resp.StatusCode < http.StatusInternalServerError {
+			return resp, nil
+		}
+		if attempt >= opts.retries {
+			return resp, err
+		}
+		if err == nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+			err = fmt.Errorf("unexpected response status: %v", resp.Status)
+		}
+
+		delay := uploadRetryDelay << attempt
+		log.Warnf("cannot upload to %v, retrying in %v: %v", URL, delay, err)
+		time.Sleep(delay)
+	}
+}
+
+// transmitHTTP uploads the data transmitted by the worker directive to URL,
+// returning the response of the server. The host of URL is replaced with
+// DataHost, if set, before it is checked against the worker's transmit policy.
+func (d *Dispatcher) transmitHTTP(
+	directive string,
+	addr string,
+	messageID string,
+	responseTo string,
+	URL *url.URL,
+	metadata map[string]string,
+	body uploadBody,
+) (int, map[string]string, []byte, *dbus.Error) {
+	if config.DefaultConfig.DataHost != "" {
+		URL.Host = config.DefaultConfig.DataHost
+	}
+	if !transmitHostAllowed(directive, URL.Hostname()) {
+		return TransmitResponseErr, nil, nil, d.denyTransmit(
+			directive,
+			addr,
+			messageID,
+			responseTo,
+			fmt.Sprintf("worker %v is not allowed to transmit to host %v", directive, URL.Hostname()),
+		)
+	}
+
+	opts, err := parseUploadOptions(metadata)
+	if err != nil {
+		return TransmitResponseErr, nil, nil, NewDBusError(
+			ipc.ErrorNameTransmit,
+			fmt.Sprintf("cannot parse upload options: %v", err),
+		)
+	}
+
+	resp, err := d.upload(URL.String(), opts, body)
+	if err != nil {
+		return TransmitResponseErr, nil, nil, NewDBusError(
+			ipc.ErrorNameTransmit,
+			fmt.Sprintf("cannot perform HTTP request: %v", err),
+		)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return TransmitResponseErr, nil, nil, NewDBusError(
+			ipc.ErrorNameTransmit,
+			fmt.Sprintf("cannot read HTTP response body: %v", err),
+		)
+	}
+	if err := resp.Body.Close(); err != nil {
+		return TransmitResponseErr, nil, nil, NewDBusError(
+			ipc.ErrorNameTransmit,
+			fmt.Sprintf("cannot close HTTP response body: %v", err),
+		)
+	}
+
+	responseMetadata := make(map[string]string)
+	for header := range resp.Header {
+		responseMetadata[header] = resp.Header.Get(header)
+	}
+	return resp.StatusCode, responseMetadata, data, nil
+}
+
+// TransmitStream implements the com.redhat.Yggdrasil1.Dispatcher1.TransmitStream
+// method. It uploads the content read from the file descriptor content to the
+// HTTP(S) URL addr as it is read, rather than holding it in memory, with the
+// upload options in metadata.
+func (d *Dispatcher) TransmitStream(
+	sender dbus.Sender,
+	addr string,
+	messageID string,
+	responseTo string,
+	metadata map[string]string,
+	content dbus.UnixFD,
+) (responseCode int, responseMetadata map[string]string, responseData []byte, responseError *dbus.Error) {
+	f := os.NewFile(uintptr(content), "content")
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Errorf("cannot close transmitted content: %v", err)
+		}
+	}()
+
+	name, err := d.senderName(sender)
+	if err != nil {
+		return TransmitResponseErr, nil, nil, NewDBusError(
+			ipc.ErrorNameTransmit,
+			fmt.Sprintf("cannot get name for sender: %v", err),
+		)
+	}
+	directive := strings.TrimPrefix(name, "com.redhat.Yggdrasil1.Worker1.")
+
+	return d.transmitStream(directive, addr, messageID, responseTo, metadata, f)
+}
+
+// transmitStream uploads the content read from f, transmitted by the worker
+// directive, to the HTTP(S) URL addr. Like data passed to Transmit, content is
+// only uploaded to a URL if the worker receives remote content.
+func (d *Dispatcher) transmitStream(
+	directive string,
+	addr string,
+	messageID string,
+	responseTo string,
+	metadata map[string]string,
+	f *os.File,
+) (responseCode int, responseMetadata map[string]string, responseData []byte, responseError *dbus.Error) {
+	remoteContent, dbusErr := d.isRemoteContentWorker(directive)
+	if dbusErr != nil {
+		return TransmitResponseErr, nil, nil, dbusErr
+	}
+	if !remoteContent {
+		return TransmitResponseErr, nil, nil, NewDBusError(
+			ipc.ErrorNameTransmit,
+			fmt.Sprintf("worker %v cannot stream content to '%v': it does not receive remote content", directive, addr),
+		)
+	}
+
+	URL, err := url.Parse(addr)
+	if err != nil || !isHTTPScheme(URL.Scheme) {
+		return TransmitResponseErr, nil, nil, NewDBusError(
+			ipc.ErrorNameTransmit,
+			fmt.Sprintf("cannot stream content to '%v': not an HTTP URL", addr),
+		)
+	}
+
+	// The size of content read from a pipe is not known in advance, and is
+	// not counted against the worker's daily byte quota.
+	var size int
+	if info, err := f.Stat(); err == nil && info.Mode().IsRegular() {
+		size = int(info.Size())
+	}
+	if err := d.throttleTransmit(directive, size); err != nil {
+		log.Warnf("throttled worker %v transmitting message %v to %v: %v", directive, messageID, addr, err)
+		return TransmitResponseErr, nil, nil, NewDBusError(
+			ipc.ErrorNameThrottled,
+			fmt.Sprintf("worker %v cannot transmit: %v", directive, err),
+		)
+	}
+
+	return d.transmitHTTP(directive, addr, messageID, responseTo, URL, metadata, fileBody(f))
+}
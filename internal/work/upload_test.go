@@ -0,0 +1,288 @@
+package work
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/redhatinsights/yggdrasil/internal/config"
+	internalhttp "github.com/redhatinsights/yggdrasil/internal/http"
+	"github.com/redhatinsights/yggdrasil/ipc"
+)
+
+func TestParseUploadOptions(t *testing.T) {
+	tests := []struct {
+		description string
+		input       map[string]string
+		want        uploadOptions
+		wantError   bool
+	}{
+		{
+			description: "defaults",
+			input:       map[string]string{"X-Header": "value"},
+			want: uploadOptions{
+				method:   http.MethodPost,
+				filename: defaultUploadFilename,
+				headers:  map[string]string{"X-Header": "value"},
+			},
+		},
+		{
+			description: "options",
+			input: map[string]string{
+				MetadataKeyUploadMethod:      "put",
+				MetadataKeyUploadContentType: "application/vnd.redhat.advisor.collection+tgz",
+				MetadataKeyUploadMultipart:   "file",
+				MetadataKeyUploadFilename:    "archive.tar.gz",
+				MetadataKeyUploadRetries:     "3",
+			},
+			want: uploadOptions{
+				method:      http.MethodPut,
+				contentType: "application/vnd.redhat.advisor.collection+tgz",
+				field:       "file",
+				filename:    "archive.tar.gz",
+				retries:     3,
+				headers:     map[string]string{},
+			},
+		},
+		{
+			description: "unsupported method",
+			input:       map[string]string{MetadataKeyUploadMethod: "DELETE"},
+			wantError:   true,
+		},
+		{
+			description: "invalid retries",
+			input:       map[string]string{MetadataKeyUploadRetries: "-1"},
+			wantError:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			got, err := parseUploadOptions(test.input)
+			if test.wantError {
+				if err == nil {
+					t.Errorf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got, test.want, cmp.AllowUnexported(uploadOptions{})) {
+				t.Errorf("%v", cmp.Diff(got, test.want, cmp.AllowUnexported(uploadOptions{})))
+			}
+		})
+	}
+}
+
+func TestUpload(t *testing.T) {
+	uploadRetryDelay = 0
+
+	// request is the upload received by the test server.
+	type request struct {
+		Method      string
+		ContentType string
+		Chunked     bool
+		Filename    string
+		Body        string
+	}
+
+	tests := []struct {
+		description string
+		metadata    map[string]string
+		stream      bool
+		failures    int
+		wantCode    int
+		want        request
+	}{
+		{
+			description: "post",
+			metadata:    map[string]string{MetadataKeyUploadContentType: "application/json"},
+			wantCode:    http.StatusOK,
+			want: request{
+				Method:      http.MethodPost,
+				ContentType: "application/json",
+				Body:        "hello world",
+			},
+		},
+		{
+			description: "put",
+			metadata:    map[string]string{MetadataKeyUploadMethod: "PUT"},
+			wantCode:    http.StatusOK,
+			want: request{
+				Method: http.MethodPut,
+				Body:   "hello world",
+			},
+		},
+		{
+			description: "multipart",
+			metadata: map[string]string{
+				MetadataKeyUploadMultipart:   "file",
+				MetadataKeyUploadFilename:    "archive.tar.gz",
+				MetadataKeyUploadContentType: "application/vnd.redhat.advisor.collection+tgz",
+			},
+			wantCode: http.StatusOK,
+			want: request{
+				Method:      http.MethodPost,
+				ContentType: "application/vnd.redhat.advisor.collection+tgz",
+				Chunked:     true,
+				Filename:    "archive.tar.gz",
+				Body:        "hello world",
+			},
+		},
+		{
+			description: "stream",
+			stream:      true,
+			wantCode:    http.StatusOK,
+			want: request{
+				Method:  http.MethodPost,
+				Chunked: true,
+				Body:    "hello world",
+			},
+		},
+		{
+			description: "retried",
+			metadata:    map[string]string{MetadataKeyUploadRetries: "2"},
+			stream:      true,
+			failures:    2,
+			wantCode:    http.StatusOK,
+			want: request{
+				Method:  http.MethodPost,
+				Chunked: true,
+				Body:    "hello world",
+			},
+		},
+		{
+			description: "too many failures",
+			metadata:    map[string]string{MetadataKeyUploadRetries: "1"},
+			failures:    2,
+			wantCode:    http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			var got request
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if requests <= test.failures {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				got = request{
+					Method:      r.Method,
+					ContentType: r.Header.Get("Content-Type"),
+					Chunked:     r.ContentLength == -1,
+				}
+				body := r.Body
+				if test.metadata[MetadataKeyUploadMultipart] != "" {
+					file, header, err := r.FormFile(test.metadata[MetadataKeyUploadMultipart])
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					body = file
+					got.ContentType = header.Header.Get("Content-Type")
+					got.Filename = header.Filename
+				}
+				data, err := io.ReadAll(body)
+				if err != nil {
+					t.Error(err)
+				}
+				got.Body = string(data)
+			}))
+			defer server.Close()
+
+			d := &Dispatcher{HTTPClient: internalhttp.NewHTTPClient(nil, "test")}
+			opts, err := parseUploadOptions(test.metadata)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body := bytesBody([]byte("hello world"))
+			if test.stream {
+				path := filepath.Join(t.TempDir(), "content")
+				if err := os.WriteFile(path, []byte("hello world"), 0600); err != nil {
+					t.Fatal(err)
+				}
+				f, err := os.Open(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+				body = fileBody(f)
+			}
+
+			resp, err := d.upload(server.URL, opts, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+
+			if resp.StatusCode != test.wantCode {
+				t.Errorf("%v != %v", resp.StatusCode, test.wantCode)
+			}
+			if !cmp.Equal(got, test.want) {
+				t.Errorf("%v", cmp.Diff(got, test.want))
+			}
+		})
+	}
+}
+
+func TestTransmitHTTPDataHost(t *testing.T) {
+	config.DefaultConfig.Workers = map[string]config.WorkerConfig{
+		"test": {TransmitHosts: []string{"example.com"}},
+	}
+	config.DefaultConfig.DataHost = "internal.example.net"
+	defer func() {
+		config.DefaultConfig.Workers = nil
+		config.DefaultConfig.DataHost = ""
+	}()
+
+	d := &Dispatcher{WorkerEvents: make(chan ipc.WorkerEvent, 1)}
+	addr := "https://example.com/upload"
+	URL, err := url.Parse(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, _, dbusErr := d.transmitHTTP("test", addr, "1", "", URL, nil, bytesBody([]byte("hello world")))
+	if dbusErr == nil {
+		t.Fatal("expected transmit to the data host to be denied")
+	}
+	if event := <-d.WorkerEvents; event.Name != ipc.WorkerEventNameDenied {
+		t.Errorf("%v != %v", event.Name, ipc.WorkerEventNameDenied)
+	}
+}
+
+func TestTransmitStreamRequiresRemoteContent(t *testing.T) {
+	config.DefaultConfig.Workers = map[string]config.WorkerConfig{
+		"test": {TransmitHosts: []string{"*"}},
+	}
+	defer func() {
+		config.DefaultConfig.Workers = nil
+	}()
+
+	d := NewDispatcher(nil)
+	d.StartExecWorkers(map[string]config.ExecWorkerConfig{
+		"test": {Directive: "test", Command: "/bin/true"},
+	})
+	f, err := os.CreateTemp(t.TempDir(), "content-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	_, _, _, dbusErr := d.transmitStream("test", "https://example.com/upload", "1", "", nil, f)
+	if dbusErr == nil {
+		t.Fatal("expected a worker without remote content to be refused")
+	}
+	if dbusErr.Name != ipc.ErrorNameTransmit {
+		t.Errorf("%v != %v", dbusErr.Name, ipc.ErrorNameTransmit)
+	}
+}
@@ -0,0 +1,138 @@
+package work
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redhatinsights/yggdrasil/internal/config"
+)
+
+// TransmitUsage describes how much data a worker has transmitted on the
+// current day, and the limits that apply to it.
+type TransmitUsage struct {
+	Worker        string
+	Day           string
+	Messages      int
+	Bytes         int64
+	Throttled     int
+	Rate          int
+	DailyMessages int
+	DailyBytes    int64
+}
+
+// transmitUsage tracks the Transmit calls of a worker, enforcing its rate
+// limit with a token bucket and its daily quotas with counters that are reset
+// at the start of each day.
+type transmitUsage struct {
+	mu        sync.Mutex
+	tokens    float64
+	last      time.Time
+	day       string
+	messages  int
+	bytes     int64
+	throttled int
+}
+
+// take records a Transmit call of size bytes made at now. It returns an error
+// without recording the call if the call exceeds a limit of limits.
+func (u *transmitUsage) take(limits config.WorkerConfig, size int, now time.Time) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.reset(now)
+
+	if limits.TransmitDailyMessages > 0 && u.messages >= limits.TransmitDailyMessages {
+		u.throttled++
+		return fmt.Errorf("daily quota of %v messages exceeded", limits.TransmitDailyMessages)
+	}
+	if limits.TransmitDailyBytes > 0 && u.bytes+int64(size) > limits.TransmitDailyBytes {
+		u.throttled++
+		return fmt.Errorf("daily quota of %v bytes exceeded", limits.TransmitDailyBytes)
+	}
+
+	if limits.TransmitRate > 0 {
+		burst := float64(limits.TransmitBurst)
+		if burst <= 0 {
+			burst = float64(limits.TransmitRate)
+		}
+		if u.last.IsZero() {
+			u.tokens = burst
+		} else {
+			refill := now.Sub(u.last).Minutes() * float64(limits.TransmitRate)
+			u.tokens = math.Min(burst, u.tokens+refill)
+		}
+		u.last = now
+		if u.tokens < 1 {
+			u.throttled++
+			return fmt.Errorf("rate limit of %v messages per minute exceeded", limits.TransmitRate)
+		}
+		u.tokens--
+	}
+
+	u.messages++
+	u.bytes += int64(size)
+	return nil
+}
+
+// reset clears the daily counters if now is on a different day than the
+// counters.
+func (u *transmitUsage) reset(now time.Time) {
+	day := now.Format(time.DateOnly)
+	if day == u.day {
+		return
+	}
+	u.day = day
+	u.messages = 0
+	u.bytes = 0
+	u.throttled = 0
+}
+
+// status returns the usage of worker on the day of now.
+func (u *transmitUsage) status(worker string, limits config.WorkerConfig, now time.Time) TransmitUsage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.reset(now)
+
+	return TransmitUsage{
+		Worker:        worker,
+		Day:           u.day,
+		Messages:      u.messages,
+		Bytes:         u.bytes,
+		Throttled:     u.throttled,
+		Rate:          limits.TransmitRate,
+		DailyMessages: limits.TransmitDailyMessages,
+		DailyBytes:    limits.TransmitDailyBytes,
+	}
+}
+
+// throttleTransmit records a Transmit call of worker transmitting size bytes.
+// It returns an error if the call exceeds the worker's rate limit or daily
+// quotas.
+func (d *Dispatcher) throttleTransmit(worker string, size int) error {
+	u, _ := d.usage.GetOrSet(worker, &transmitUsage{})
+	return u.take(config.DefaultConfig.Workers[worker], size, time.Now())
+}
+
+// ListTransmitUsage returns the transmit usage of every worker that has
+// called Transmit or has transmit limits configured, sorted by worker.
+func (d *Dispatcher) ListTransmitUsage() []TransmitUsage {
+	now := time.Now()
+	for worker, limits := range config.DefaultConfig.Workers {
+		if limits.TransmitRate > 0 || limits.TransmitDailyMessages > 0 || limits.TransmitDailyBytes > 0 {
+			d.usage.GetOrSet(worker, &transmitUsage{})
+		}
+	}
+
+	var usage []TransmitUsage
+	d.usage.Visit(func(worker string, u *transmitUsage) {
+		usage = append(usage, u.status(worker, config.DefaultConfig.Workers[worker], now))
+	})
+	sort.Slice(usage, func(i, k int) bool {
+		return usage[i].Worker < usage[k].Worker
+	})
+	return usage
+}
@@ -0,0 +1,108 @@
+package work
+
+import (
+	"testing"
+	"time"
+
+	"github.com/redhatinsights/yggdrasil/internal/config"
+)
+
+func TestTransmitUsageTake(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	type call struct {
+		at   time.Duration
+		size int
+		want bool
+	}
+
+	tests := []struct {
+		description string
+		limits      config.WorkerConfig
+		calls       []call
+		want        TransmitUsage
+	}{
+		{
+			description: "no limits",
+			calls: []call{
+				{at: 0, size: 10, want: true},
+				{at: 0, size: 10, want: true},
+			},
+			want: TransmitUsage{Day: "2024-06-01", Messages: 2, Bytes: 20},
+		},
+		{
+			description: "rate limit",
+			limits:      config.WorkerConfig{TransmitRate: 60, TransmitBurst: 2},
+			calls: []call{
+				{at: 0, want: true},
+				{at: 0, want: true},
+				{at: 0, want: false},
+				{at: time.Second, want: true},
+				{at: time.Second, want: false},
+				{at: time.Minute, want: true},
+				{at: time.Minute, want: true},
+			},
+			want: TransmitUsage{Day: "2024-06-01", Messages: 5, Throttled: 2, Rate: 60},
+		},
+		{
+			description: "burst defaults to rate",
+			limits:      config.WorkerConfig{TransmitRate: 2},
+			calls: []call{
+				{at: 0, want: true},
+				{at: 0, want: true},
+				{at: 0, want: false},
+				{at: 30 * time.Second, want: true},
+			},
+			want: TransmitUsage{Day: "2024-06-01", Messages: 3, Throttled: 1, Rate: 2},
+		},
+		{
+			description: "daily messages",
+			limits:      config.WorkerConfig{TransmitDailyMessages: 2},
+			calls: []call{
+				{at: 0, want: true},
+				{at: time.Hour, want: true},
+				{at: 2 * time.Hour, want: false},
+			},
+			want: TransmitUsage{Day: "2024-06-01", Messages: 2, Throttled: 1, DailyMessages: 2},
+		},
+		{
+			description: "daily bytes",
+			limits:      config.WorkerConfig{TransmitDailyBytes: 100},
+			calls: []call{
+				{at: 0, size: 60, want: true},
+				{at: 0, size: 60, want: false},
+				{at: 0, size: 40, want: true},
+			},
+			want: TransmitUsage{Day: "2024-06-01", Messages: 2, Bytes: 100, Throttled: 1, DailyBytes: 100},
+		},
+		{
+			description: "quota reset on next day",
+			limits:      config.WorkerConfig{TransmitDailyMessages: 1},
+			calls: []call{
+				{at: 0, want: true},
+				{at: time.Hour, want: false},
+				{at: 12 * time.Hour, want: true},
+			},
+			want: TransmitUsage{Day: "2024-06-02", Messages: 1, DailyMessages: 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			u := &transmitUsage{}
+			var now time.Time
+			for i, c := range test.calls {
+				now = start.Add(c.at)
+				err := u.take(test.limits, c.size, now)
+				if got := err == nil; got != c.want {
+					t.Errorf("call %v: %v != %v (%v)", i, got, c.want, err)
+				}
+			}
+
+			got := u.status("", test.limits, now)
+			if got != test.want {
+				t.Errorf("%+v != %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package ipc
 
 import (
 	_ "embed"
+	"encoding/json"
 	"fmt"
 )
 
@@ -26,9 +27,61 @@ const (
 	DispatcherEventConnectionRestored DispatcherEvent = 3
 )
 
+// ErrorNameThrottled is the name of the D-Bus error returned by the
+// com.redhat.Yggdrasil1.Dispatcher1.Transmit method when the calling worker
+// has exceeded its transmit rate limit or daily quota. The data was not
+// transmitted.
+const ErrorNameThrottled = "com.redhat.Yggdrasil1.Dispatcher1.Throttled"
+
+// ErrorNameTransmit is the name of the D-Bus error returned by the
+// com.redhat.Yggdrasil1.Dispatcher1.Transmit and TransmitStream methods when
+// the data cannot be transmitted, such as when the destination is not allowed
+// or the transport fails.
+const ErrorNameTransmit = "com.redhat.Yggdrasil1.Dispatcher1.Transmit"
+
 //go:embed com.redhat.Yggdrasil1.Worker1.xml
 var InterfaceWorker string
 
+// ErrorNameWorkerDraining is the name of the D-Bus error returned by the
+// com.redhat.Yggdrasil1.Worker1.Dispatch method of a worker that is shutting
+// down and no longer accepts messages. The message can be dispatched again
+// later.
+const ErrorNameWorkerDraining = "com.redhat.Yggdrasil1.Worker1.Draining"
+
+// MetadataKeyDeadline is the data message metadata key that optionally
+// contains an RFC 3339 timestamp by which the worker must finish working on the
+// message. The dispatcher cancels a message whose worker has not emitted an END
+// event by the deadline, and the worker library cancels the context of the
+// message's handler.
+const MetadataKeyDeadline = "deadline"
+
+// WorkerManifest describes a worker and the data it accepts. A worker exposes
+// its manifest, encoded as JSON, in the com.redhat.Yggdrasil1.Worker1.Manifest
+// property.
+type WorkerManifest struct {
+	// Version is the version of the worker.
+	Version string `json:"version,omitempty"`
+
+	// Description is a human-readable description of the worker.
+	Description string `json:"description,omitempty"`
+
+	// ContentTypes are the media types of the content the worker accepts. A
+	// data message whose "content_type" metadata value is not one of them is
+	// not dispatched to the worker. An empty list accepts any content type.
+	ContentTypes []string `json:"content_types,omitempty"`
+
+	// ContentSchema is a JSON Schema the content of data messages must be
+	// valid against to be dispatched to the worker.
+	ContentSchema json.RawMessage `json:"content_schema,omitempty"`
+
+	// MetadataSchema is a JSON Schema the metadata of data messages must be
+	// valid against to be dispatched to the worker.
+	MetadataSchema json.RawMessage `json:"metadata_schema,omitempty"`
+
+	// Cancellable is true if the worker handles cancel messages.
+	Cancellable bool `json:"cancellable"`
+}
+
 type WorkerEventName uint
 
 const (
@@ -51,6 +104,37 @@ const (
 	// WorkerEventNameStopped is emitted when worker is stopped,
 	// and it cannot process any message.
 	WorkerEventNameStopped WorkerEventName = 5
+
+	// WorkerEventNameTimeout is emitted by the dispatcher on behalf of a worker
+	// when the worker does not finish working on a message before the
+	// message's deadline.
+	WorkerEventNameTimeout WorkerEventName = 6
+
+	// WorkerEventNameFailed is emitted instead of WorkerEventNameEnd when a
+	// worker fails to finish working on a message. The event data includes an
+	// error "code" and "message". The dispatcher emits this event on behalf of
+	// a worker that exits before finishing a message.
+	WorkerEventNameFailed WorkerEventName = 7
+
+	// WorkerEventNameDenied is emitted by the dispatcher on behalf of a worker
+	// when the worker's transmit policy does not allow it to transmit data to
+	// an address. The event data includes the "addr" and a "message"
+	// describing why the address was denied.
+	WorkerEventNameDenied WorkerEventName = 8
+)
+
+const (
+	// WorkerEventDataKeyCode is the key of the event data value containing a
+	// machine-readable error code of a FAILED event.
+	WorkerEventDataKeyCode = "code"
+
+	// WorkerEventDataKeyMessage is the key of the event data value containing
+	// a human-readable description of the error of a FAILED event.
+	WorkerEventDataKeyMessage = "message"
+
+	// WorkerEventDataKeyAddr is the key of the event data value containing the
+	// address a worker was denied transmitting data to in a DENIED event.
+	WorkerEventDataKeyAddr = "addr"
 )
 
 func (e WorkerEventName) String() string {
@@ -65,6 +149,12 @@ func (e WorkerEventName) String() string {
 		return "STARTED"
 	case WorkerEventNameStopped:
 		return "STOPPED"
+	case WorkerEventNameTimeout:
+		return "TIMEOUT"
+	case WorkerEventNameFailed:
+		return "FAILED"
+	case WorkerEventNameDenied:
+		return "DENIED"
 	}
 	return fmt.Sprintf("UNKNOWN (value: %d)", e)
 }
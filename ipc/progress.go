@@ -0,0 +1,125 @@
+package ipc
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// WorkerEventDataKeyPercent is the key of the event data value of a
+	// WORKING event containing the percentage of work completed.
+	WorkerEventDataKeyPercent = "percent"
+
+	// WorkerEventDataKeyStep is the key of the event data value of a WORKING
+	// event containing the number of the step the worker is working on.
+	WorkerEventDataKeyStep = "step"
+
+	// WorkerEventDataKeyTotalSteps is the key of the event data value of a
+	// WORKING event containing the total number of steps.
+	WorkerEventDataKeyTotalSteps = "total_steps"
+)
+
+// Progress describes how far a worker has progressed working on a message. It
+// is sent as the data of a WORKING event.
+type Progress struct {
+	// Percent is the percentage of work completed, from 0 to 100.
+	Percent int
+
+	// Step is the number of the step the worker is working on, starting at 1.
+	// A zero value omits the step.
+	Step int
+
+	// TotalSteps is the total number of steps. A zero value omits the total
+	// number of steps.
+	TotalSteps int
+
+	// Message is an optional human-readable description of the current step.
+	Message string
+}
+
+// Validate returns an error if any of the values of p are out of range.
+func (p Progress) Validate() error {
+	if p.Percent < 0 || p.Percent > 100 {
+		return fmt.Errorf("percent %v out of range [0, 100]", p.Percent)
+	}
+	if p.Step < 0 {
+		return fmt.Errorf("step %v cannot be negative", p.Step)
+	}
+	if p.TotalSteps < 0 {
+		return fmt.Errorf("total steps %v cannot be negative", p.TotalSteps)
+	}
+	if p.TotalSteps > 0 && p.Step > p.TotalSteps {
+		return fmt.Errorf("step %v greater than total steps %v", p.Step, p.TotalSteps)
+	}
+	return nil
+}
+
+// Data returns p encoded as WORKING event data.
+func (p Progress) Data() map[string]string {
+	data := map[string]string{
+		WorkerEventDataKeyPercent: strconv.Itoa(p.Percent),
+	}
+	if p.Step > 0 {
+		data[WorkerEventDataKeyStep] = strconv.Itoa(p.Step)
+	}
+	if p.TotalSteps > 0 {
+		data[WorkerEventDataKeyTotalSteps] = strconv.Itoa(p.TotalSteps)
+	}
+	if p.Message != "" {
+		data[WorkerEventDataKeyMessage] = p.Message
+	}
+	return data
+}
+
+// String returns a human-readable representation of p, such as
+// "[50%] step 2/4: copying files".
+func (p Progress) String() string {
+	s := fmt.Sprintf("[%v%%]", p.Percent)
+	if p.Step > 0 {
+		s += fmt.Sprintf(" step %v", p.Step)
+		if p.TotalSteps > 0 {
+			s += fmt.Sprintf("/%v", p.TotalSteps)
+		}
+	}
+	if p.Message != "" {
+		s += ": " + p.Message
+	}
+	return s
+}
+
+// ParseProgress decodes WORKING event data into a Progress. It returns nil if
+// data does not include a percentage, and an error if any of the progress
+// values are invalid.
+func ParseProgress(data map[string]string) (*Progress, error) {
+	value, has := data[WorkerEventDataKeyPercent]
+	if !has {
+		return nil, nil
+	}
+
+	var p Progress
+	var err error
+
+	p.Percent, err = strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse percent '%v': %w", value, err)
+	}
+	if value, has := data[WorkerEventDataKeyStep]; has {
+		p.Step, err = strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse step '%v': %w", value, err)
+		}
+	}
+	if value, has := data[WorkerEventDataKeyTotalSteps]; has {
+		p.TotalSteps, err = strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse total steps '%v': %w", value, err)
+		}
+	}
+	p.Message = data[WorkerEventDataKeyMessage]
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
@@ -0,0 +1,101 @@
+package ipc
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestParseProgress(t *testing.T) {
+	tests := []struct {
+		description string
+		input       map[string]string
+		want        *Progress
+		wantError   error
+	}{
+		{
+			description: "full",
+			input: map[string]string{
+				"percent":     "50",
+				"step":        "2",
+				"total_steps": "4",
+				"message":     "copying files",
+			},
+			want: &Progress{Percent: 50, Step: 2, TotalSteps: 4, Message: "copying files"},
+		},
+		{
+			description: "percent only",
+			input:       map[string]string{"percent": "10"},
+			want:        &Progress{Percent: 10},
+		},
+		{
+			description: "no progress",
+			input:       map[string]string{"message": "working"},
+			want:        nil,
+		},
+		{
+			description: "invalid - percent",
+			input:       map[string]string{"percent": "half"},
+			wantError:   cmpopts.AnyError,
+		},
+		{
+			description: "invalid - percent range",
+			input:       map[string]string{"percent": "101"},
+			wantError:   cmpopts.AnyError,
+		},
+		{
+			description: "invalid - step range",
+			input:       map[string]string{"percent": "50", "step": "5", "total_steps": "4"},
+			wantError:   cmpopts.AnyError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			got, err := ParseProgress(test.input)
+
+			if test.wantError != nil {
+				if !cmp.Equal(err, test.wantError, cmpopts.EquateErrors()) {
+					t.Errorf("%#v != %#v", err, test.wantError)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !cmp.Equal(got, test.want) {
+					t.Errorf("%v", cmp.Diff(got, test.want))
+				}
+			}
+		})
+	}
+}
+
+func TestProgressString(t *testing.T) {
+	tests := []struct {
+		description string
+		input       Progress
+		want        string
+	}{
+		{
+			description: "full",
+			input:       Progress{Percent: 50, Step: 2, TotalSteps: 4, Message: "copying files"},
+			want:        "[50%] step 2/4: copying files",
+		},
+		{
+			description: "percent only",
+			input:       Progress{Percent: 10},
+			want:        "[10%]",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			got := test.input.String()
+
+			if got != test.want {
+				t.Errorf("%v != %v", got, test.want)
+			}
+		})
+	}
+}
@@ -59,6 +59,39 @@ const (
 	// EventNamePong informs the server that the client has received a "ping"
 	// command.
 	EventNamePong EventName = "pong"
+
+	// EventNameDispatchError informs the server that the client was unable to
+	// dispatch a data message to a worker. The event's ResponseTo field is set
+	// to the ID of the data message, and its Data field contains the "code",
+	// "reason" and "directive" of the failure.
+	EventNameDispatchError EventName = "dispatch-error"
+
+	// EventNameTimeout informs the server that a worker did not finish working
+	// on a data message before the message's deadline, and the message was
+	// cancelled. The event's ResponseTo field is set to the ID of the data
+	// message, and its Data field contains the "directive" and "deadline" of
+	// the message.
+	EventNameTimeout EventName = "timeout"
+
+	// EventNameFailed informs the server that a worker failed to finish working
+	// on a data message. The event's ResponseTo field is set to the ID of the
+	// data message, and its Data field contains the "code", "reason" and
+	// "directive" of the failure.
+	EventNameFailed EventName = "failed"
+
+	// EventNameProgress informs the server how far a worker has progressed
+	// working on a data message. The event's ResponseTo field is set to the ID
+	// of the data message, and its Data field contains the "directive" of the
+	// message and the "percent", "step", "total_steps" and "message" values
+	// reported by the worker.
+	EventNameProgress EventName = "progress"
+
+	// EventNameBroadcastComplete informs the server that every worker a
+	// broadcast data message was dispatched to has finished working on it. The
+	// event's ResponseTo field is set to the ID of the data message, and its
+	// Data field contains the overall "status" of the broadcast and the
+	// JSON-encoded "results" of each worker.
+	EventNameBroadcastComplete EventName = "broadcast-complete"
 )
 
 // A ConnectionStatus message is published by the client when it connects to
@@ -77,9 +110,21 @@ type ConnectionStatus struct {
 		State          ConnectionState              `json:"state"`
 		Tags           map[string]string            `json:"tags,omitempty"`
 		ClientVersion  string                       `json:"client_version,omitempty"`
+		Manifests      map[string]WorkerManifest    `json:"manifests,omitempty"`
 	} `json:"content"`
 }
 
+// A WorkerManifest describes a connected worker and the data it accepts in a
+// ConnectionStatus message.
+type WorkerManifest struct {
+	Version        string          `json:"version,omitempty"`
+	Description    string          `json:"description,omitempty"`
+	ContentTypes   []string        `json:"content_types,omitempty"`
+	ContentSchema  json.RawMessage `json:"content_schema,omitempty"`
+	MetadataSchema json.RawMessage `json:"metadata_schema,omitempty"`
+	Cancellable    bool            `json:"cancellable"`
+}
+
 // A Command message is published by the server on the "control" topic when it
 // needs to instruct a client to perform an operation.
 type Command struct {
@@ -88,14 +133,16 @@ type Command struct {
 }
 
 // An Event message is published by the client on the "control" topic when it
-// wishes to inform the server that a notable event occurred.
+// wishes to inform the server that a notable event occurred. Some events
+// include additional key/value pairs describing the event in the Data field.
 type Event struct {
-	Type       MessageType `json:"type"`
-	MessageID  string      `json:"message_id"`
-	ResponseTo string      `json:"response_to"`
-	Version    int         `json:"version"`
-	Sent       time.Time   `json:"sent"`
-	Content    string      `json:"content"`
+	Type       MessageType       `json:"type"`
+	MessageID  string            `json:"message_id"`
+	ResponseTo string            `json:"response_to"`
+	Version    int               `json:"version"`
+	Sent       time.Time         `json:"sent"`
+	Content    string            `json:"content"`
+	Data       map[string]string `json:"data,omitempty"`
 }
 
 type Control struct {
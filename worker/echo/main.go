@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"syscall"
@@ -12,24 +14,20 @@ import (
 
 	"github.com/subpop/go-log"
 
-	"github.com/redhatinsights/yggdrasil/internal/sync"
 	"github.com/redhatinsights/yggdrasil/ipc"
 	"github.com/redhatinsights/yggdrasil/worker"
 )
 
 var sleepTime time.Duration
 var loopIt int
+var async bool
 
-// syncMapCancelChan is a map of channels that maps message ID and its current
-// work. This allows for the cancellation of a message that has not finished.
-var syncMapCancelChan sync.RWMutexMap[chan struct{}]
-
-// echo handles the echo message and sets the channel that will manage the
-// cancel message. It runs a loop and a sleep according to the loop and
-// sleep parameters, then calls the echo function to transmit the
-// message. If there is a cancellation message during the loop or the sleep
-// time, it will cancel the transmission of the message and finish the work.
+// echo handles the echo message. It runs a loop and a sleep according to the
+// loop and sleep parameters, then calls the echo function to transmit the
+// message. If the message is cancelled during the loop or the sleep time, it
+// will cancel the transmission of the message and finish the work.
 func echo(
+	ctx context.Context,
 	w *worker.Worker,
 	addr string,
 	rcvId string,
@@ -50,40 +48,63 @@ func echo(
 		return fmt.Errorf("cannot call EmitEvent: %w", err)
 	}
 
-	// Setting the channel to handle cancellation
-	syncMapCancelChan.Set(rcvId, make(chan struct{}))
+	// Reply to the sending worker if the message was transmitted by another
+	// worker on this host.
+	if sender, has := metadata["sender"]; has {
+		addr = "local:" + sender
+	}
 
 	// Loop the echoes
 	for i := 0; i < loopIt; i++ {
 		// Sleep time between receiving the message and sending it
 		if sleepTime > 0 {
 			log.Infof("sleeping: %v", sleepTime)
-			time.Sleep(sleepTime)
 		}
-		// Cancel message if it has been sent a cancel message
-		// during sleep time or during the loop
-		cancelChan, _ := syncMapCancelChan.Get(rcvId)
+		// Cancel message if it has been cancelled during sleep time or
+		// during the loop
 		select {
-		case <-cancelChan:
-			// if the channel is close delete the channel from map
-			// it will not be longer used.
-			log.Tracef("canceled echo message id: %v", rcvId)
-			log.Tracef("deleting channel from map")
-			syncMapCancelChan.Del(rcvId)
+		case <-ctx.Done():
+			log.Tracef("canceled echo message id: %v: %v", rcvId, ctx.Err())
 			return nil
-		default:
+		case <-time.After(sleepTime):
 			if err := sendEchoMessage(w, addr, rcvId, responseTo, metadata, data, i); err != nil {
 				return err
 			}
+			// Report how many of the echoes have been sent.
+			if err := w.EmitProgress(rcvId, responseTo, ipc.Progress{
+				Percent:    (i + 1) * 100 / loopIt,
+				Step:       i + 1,
+				TotalSteps: loopIt,
+				Message:    "echoed message",
+			}); err != nil {
+				return fmt.Errorf("cannot call EmitProgress: %w", err)
+			}
 		}
 
 	}
 
-	log.Tracef("deleting channel from map")
-	syncMapCancelChan.Del(rcvId)
 	return nil
 }
 
+// echoStream handles the echo message of a worker receiving content through a
+// file descriptor by reading the content and echoing it like echo.
+func echoStream(
+	ctx context.Context,
+	w *worker.Worker,
+	addr string,
+	rcvId string,
+	responseTo string,
+	metadata map[string]string,
+	content io.Reader,
+) error {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return fmt.Errorf("cannot read content: %w", err)
+	}
+	log.Infof("read %v bytes of content", len(data))
+	return echo(ctx, w, addr, rcvId, responseTo, metadata, data)
+}
+
 // sendEchoMessage opens a new dbus connection and calls the
 // com.redhat.Yggdrasil1.Dispatcher1.Transmit method, returning the
 // metadata and data. New ID is generated for the message, and
@@ -102,13 +123,31 @@ func sendEchoMessage(
 	// Create new echoId for the message we are going to send
 	echoId := uuid.New().String()
 
-	responseCode, responseMetadata, responseData, err := w.Transmit(
-		addr,
-		echoId,
-		echoResponseTo,
-		metadata,
-		data,
+	var (
+		responseCode     int
+		responseMetadata map[string]string
+		responseData     []byte
+		err              error
 	)
+	if async {
+		// Transmit the message without waiting for the dispatcher to send it,
+		// then wait for the response.
+		var ch <-chan worker.TransmitResult
+		ch, err = w.TransmitAsync(addr, echoId, echoResponseTo, metadata, data)
+		if err != nil {
+			return fmt.Errorf("cannot call TransmitAsync: %w", err)
+		}
+		result := <-ch
+		responseCode, responseMetadata, responseData, err = result.ResponseCode, result.ResponseMetadata, result.ResponseData, result.Err
+	} else {
+		responseCode, responseMetadata, responseData, err = w.Transmit(
+			addr,
+			echoId,
+			echoResponseTo,
+			metadata,
+			data,
+		)
+	}
 	if err != nil {
 		return fmt.Errorf("cannot call Transmit: %w", err)
 	}
@@ -125,19 +164,6 @@ func sendEchoMessage(
 	return nil
 }
 
-// cancelEcho receives a cancel message id via com.redhat.Yggdrasil1.Worker1.Cancel method
-// closes the channel associated with that message to cancel its current run
-func cancelEcho(w *worker.Worker, addr string, id string, cancelID string) error {
-	log.Infof("cancelling message with id %v", cancelID)
-	if cancelChan, exists := syncMapCancelChan.Get(cancelID); exists {
-		close(cancelChan)
-	} else {
-		return fmt.Errorf("message with given id: %v does not exist and cannot be canceled", cancelID)
-	}
-
-	return nil
-}
-
 func events(event ipc.DispatcherEvent) {
 	switch event {
 	case ipc.DispatcherEventReceivedDisconnect:
@@ -149,12 +175,15 @@ func main() {
 	var (
 		logLevel      string
 		remoteContent bool
+		stream        bool
 	)
 
 	flag.StringVar(&logLevel, "log-level", "error", "set log level")
 	flag.BoolVar(&remoteContent, "remote-content", false, "connect as a remote content worker")
 	flag.DurationVar(&sleepTime, "sleep", 0, "sleep time in seconds before echoing the response")
 	flag.IntVar(&loopIt, "loop", 1, "number of loop echoes before finish echoing.")
+	flag.BoolVar(&async, "async", false, "transmit echoes with TransmitAsync")
+	flag.BoolVar(&stream, "stream", false, "connect as a remote content worker receiving content through a file descriptor")
 	flag.Parse()
 
 	level, err := log.ParseLevel(logLevel)
@@ -163,17 +192,20 @@ func main() {
 	}
 	log.SetLevel(level)
 
-	w, err := worker.NewWorker(
-		"echo",
-		remoteContent,
-		map[string]string{"DispatchedAt": "", "Version": "1"},
-		cancelEcho,
-		echo,
-		events,
-	)
+	features := map[string]string{"DispatchedAt": "", "Version": "1"}
+	var w *worker.Worker
+	if stream {
+		w, err = worker.NewStreamWorker("echo", features, echoStream, events)
+	} else {
+		w, err = worker.NewContextWorker("echo", remoteContent, features, echo, events)
+	}
 	if err != nil {
 		log.Fatalf("error: cannot create worker: %v", err)
 	}
+	w.Manifest = ipc.WorkerManifest{
+		Version:     "1",
+		Description: "Transmits the data it receives back to the server",
+	}
 
 	// Set up a channel to receive the TERM or INT signal over and clean up
 	// before quitting.
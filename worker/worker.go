@@ -1,14 +1,22 @@
 package worker
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"regexp"
+	gosync "sync"
+	"time"
 
 	"github.com/godbus/dbus/v5"
 	"github.com/godbus/dbus/v5/introspect"
 	"github.com/godbus/dbus/v5/prop"
+	"github.com/redhatinsights/yggdrasil/internal/sync"
 	"github.com/redhatinsights/yggdrasil/ipc"
 	"github.com/subpop/go-log"
 )
@@ -16,25 +24,136 @@ import (
 // RxFunc is a function type that gets called each time the worker receives data.
 type RxFunc func(w *Worker, addr string, id string, responseTo string, metadata map[string]string, data []byte) error
 
+// RxContextFunc is a function type that gets called each time the worker
+// receives data. The context is cancelled when the worker receives a cancel
+// message for the data, when the deadline included in the data's metadata
+// passes, or when the worker stops.
+type RxContextFunc func(
+	ctx context.Context,
+	w *Worker,
+	addr string,
+	id string,
+	responseTo string,
+	metadata map[string]string,
+	data []byte,
+) error
+
+// RxStreamFunc is a function type that gets called each time a worker created
+// with NewStreamWorker receives data. The content of the data is read from
+// content, which is closed when the function returns. The context is cancelled
+// like that of an RxContextFunc.
+type RxStreamFunc func(
+	ctx context.Context,
+	w *Worker,
+	addr string,
+	id string,
+	responseTo string,
+	metadata map[string]string,
+	content io.Reader,
+) error
+
 // CancelRxFunc is a function type that gets called each time the worker receives
 // a cancel message
 type CancelRxFunc func(w *Worker, addr string, id string, cancelID string) error
 
+const (
+	// ErrorCodeUnknown is the error code reported in the FAILED event emitted
+	// when an RxFunc or CancelRxFunc returns an error that is not an *Error.
+	ErrorCodeUnknown = "unknown"
+
+	// ErrorCodeCancelled is the error code reported in the FAILED event
+	// emitted when an RxContextFunc returns an error caused by its context
+	// being cancelled.
+	ErrorCodeCancelled = "cancelled"
+
+	// ErrorCodeDeadlineExceeded is the error code reported in the FAILED event
+	// emitted when an RxContextFunc returns an error caused by the message's
+	// deadline passing.
+	ErrorCodeDeadlineExceeded = "deadline-exceeded"
+
+	// ErrorCodeUnknownMessage is the error code reported in the FAILED event
+	// emitted when a worker created with NewContextWorker receives a cancel
+	// message for a message it is not working on.
+	ErrorCodeUnknownMessage = "unknown-message"
+)
+
+// DefaultDrainTimeout is the default duration a worker waits for the messages
+// it is working on to finish when it is shutting down.
+const DefaultDrainTimeout = 30 * time.Second
+
+// drainCancelTimeout is the duration a worker waits for message handlers to
+// return after cancelling them when it is shutting down.
+const drainCancelTimeout = 5 * time.Second
+
+// Error is an error an RxFunc or CancelRxFunc can return to report a specific
+// error code in the FAILED event emitted on its behalf.
+type Error struct {
+	// Code is a machine-readable error code, such as "invalid-content".
+	Code string
+
+	// Err is the underlying error.
+	Err error
+}
+
+// NewError creates an Error with the given code, wrapping err.
+func NewError(code string, err error) *Error {
+	return &Error{Code: code, Err: err}
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// TransmitResult is the response to data transmitted with TransmitAsync.
+type TransmitResult struct {
+	ResponseCode     int
+	ResponseMetadata map[string]string
+	ResponseData     []byte
+
+	// Err is a dbus.Error if the dispatcher could not transmit the data.
+	Err error
+}
+
 // EventHandlerFunc is a function type that gets called each time the worker
 // receives a com.redhat.Yggdrasil1.Dispatcher1.Event signal.
 type EventHandlerFunc func(e ipc.DispatcherEvent)
 
 // Worker implements the com.redhat.Yggdrasil1.Worker1 interface.
 type Worker struct {
+	// DrainTimeout is the duration the worker waits for the messages it is
+	// working on to finish when it is shutting down. The context of messages
+	// that have not finished by then is cancelled.
+	DrainTimeout time.Duration
+
+	// Manifest describes the worker and the data it accepts. It is exported
+	// in the Manifest property when the worker connects, so it must be set
+	// before calling Connect. Its Cancellable field is set by Connect, based
+	// on whether the worker handles cancel messages.
+	Manifest ipc.WorkerManifest
+
 	directive     string
 	features      map[string]string
 	remoteContent bool
 	rx            RxFunc
+	rxContext     RxContextFunc
+	rxStream      RxStreamFunc
 	cancelRx      CancelRxFunc
+	cancelFuncs   sync.RWMutexMap[context.CancelFunc]
+	ctx           context.Context
+	stop          context.CancelFunc
 	conn          *dbus.Conn
 	objectPath    dbus.ObjectPath
 	busName       string
 	eventHandler  EventHandlerFunc
+	transmits     sync.RWMutexMap[chan TransmitResult]
+	transmitMu    gosync.Mutex
+	draining      bool
+	mu            gosync.Mutex
+	wg            gosync.WaitGroup
 }
 
 // NewWorker creates a new worker.
@@ -52,6 +171,7 @@ func NewWorker(
 	}
 
 	w := Worker{
+		DrainTimeout:  DefaultDrainTimeout,
 		directive:     directive,
 		features:      features,
 		remoteContent: remoteContent,
@@ -61,15 +181,57 @@ func NewWorker(
 		busName:       fmt.Sprintf("com.redhat.Yggdrasil1.Worker1.%v", directive),
 		eventHandler:  events,
 	}
+	w.ctx, w.stop = context.WithCancel(context.Background())
 
 	return &w, nil
 }
 
+// NewContextWorker creates a new worker that calls rx with a context for each
+// message it receives. Unlike a worker created with NewWorker, the worker
+// handles cancel messages itself by cancelling the context of the message.
+func NewContextWorker(
+	directive string,
+	remoteContent bool,
+	features map[string]string,
+	rx RxContextFunc,
+	events EventHandlerFunc,
+) (*Worker, error) {
+	w, err := NewWorker(directive, remoteContent, features, cancelContext, nil, events)
+	if err != nil {
+		return nil, err
+	}
+	w.rxContext = rx
+
+	return w, nil
+}
+
+// NewStreamWorker creates a new remote content worker that calls rx with a
+// reader of the content of each message it receives. The dispatcher downloads
+// the content and passes it to the worker as a file descriptor, so content
+// larger than the worker or dispatcher could hold in memory can be received.
+// The worker handles cancel messages itself, like a worker created with
+// NewContextWorker.
+func NewStreamWorker(
+	directive string,
+	features map[string]string,
+	rx RxStreamFunc,
+	events EventHandlerFunc,
+) (*Worker, error) {
+	w, err := NewWorker(directive, true, features, cancelContext, nil, events)
+	if err != nil {
+		return nil, err
+	}
+	w.rxStream = rx
+
+	return w, nil
+}
+
 // Connect connects to the bus, exports the worker on its object path, and
 // requests a well-known bus name. It connects to a private session bus, if
 // DBUS_SESSION_BUS_ADDRESS is set in the environment. Otherwise it connects to
 // the system bus. It exports w onto the bus and waits until a signal is
-// received on quit.
+// received on quit. It then stops accepting messages, waits up to DrainTimeout
+// for the messages it is working on to finish, and releases its bus name.
 func (w *Worker) Connect(quit <-chan os.Signal) error {
 	var err error
 
@@ -84,6 +246,12 @@ func (w *Worker) Connect(quit <-chan os.Signal) error {
 		return fmt.Errorf("error: cannot connect to bus: %w", err)
 	}
 
+	w.Manifest.Cancellable = w.cancelRx != nil
+	manifest, err := json.Marshal(w.Manifest)
+	if err != nil {
+		return fmt.Errorf("cannot marshal manifest: %w", err)
+	}
+
 	// Export properties onto the bus as an org.freedesktop.DBus.Properties
 	// interface.
 	propertySpec := prop.Map{
@@ -98,6 +266,16 @@ func (w *Worker) Connect(quit <-chan os.Signal) error {
 				Writable: false,
 				Emit:     prop.EmitTrue,
 			},
+			"StreamContent": {
+				Value:    w.rxStream != nil,
+				Writable: false,
+				Emit:     prop.EmitTrue,
+			},
+			"Manifest": {
+				Value:    string(manifest),
+				Writable: false,
+				Emit:     prop.EmitTrue,
+			},
 		},
 	}
 
@@ -109,7 +287,11 @@ func (w *Worker) Connect(quit <-chan os.Signal) error {
 	// Export worker onto the bus, implementing the com.redhat.Yggdrasil1.Worker1
 	// and org.freedesktop.DBus.Introspectable interfaces. The path name the
 	// worker exports includes the directive name.
-	if err := w.conn.ExportMethodTable(map[string]interface{}{"Dispatch": w.dispatch, "Cancel": w.cancel}, w.objectPath, "com.redhat.Yggdrasil1.Worker1"); err != nil {
+	methods := map[string]interface{}{"Dispatch": w.dispatch, "Cancel": w.cancel}
+	if w.rxStream != nil {
+		methods["DispatchStream"] = w.dispatchStream
+	}
+	if err := w.conn.ExportMethodTable(methods, w.objectPath, "com.redhat.Yggdrasil1.Worker1"); err != nil {
 		return fmt.Errorf("cannot export com.redhat.Yggdrasil1.Worker1 interface: %w", err)
 	}
 
@@ -126,15 +308,20 @@ func (w *Worker) Connect(quit <-chan os.Signal) error {
 		return fmt.Errorf("name: %s already taken", w.busName)
 	}
 
-	// Emit a started event
-	err = w.EmitEvent(
-		ipc.WorkerEventNameStarted,
-		"",
-		"",
-		map[string]string{},
-	)
-	if err != nil {
-		return fmt.Errorf("cannot emit event: %w", err)
+	// Subscribe to events emitted by the dispatcher.
+	if err := w.conn.AddMatchSignal(
+		dbus.WithMatchInterface("com.redhat.Yggdrasil1.Dispatcher1"),
+		dbus.WithMatchMember("Event"),
+	); err != nil {
+		return fmt.Errorf("cannot add signal match on com.redhat.Yggdrasil1.Dispatcher1.Event: %w", err)
+	}
+
+	// Subscribe to the responses to data transmitted with TransmitAsync.
+	if err := w.conn.AddMatchSignal(
+		dbus.WithMatchInterface("com.redhat.Yggdrasil1.Dispatcher1"),
+		dbus.WithMatchMember("TransmitComplete"),
+	); err != nil {
+		return fmt.Errorf("cannot add signal match on com.redhat.Yggdrasil1.Dispatcher1.TransmitComplete: %w", err)
 	}
 
 	signals := make(chan *dbus.Signal)
@@ -143,17 +330,36 @@ func (w *Worker) Connect(quit <-chan os.Signal) error {
 		for s := range signals {
 			switch s.Name {
 			case "com.redhat.Yggdrasil1.Dispatcher1.Event":
-				event := s.Body[0].(uint)
+				event, ok := s.Body[0].(uint32)
+				if !ok {
+					log.Errorf("cannot convert %T to uint32", s.Body[0])
+					continue
+				}
 				if w.eventHandler == nil {
 					continue
 				}
 				w.eventHandler(ipc.DispatcherEvent(event))
+			case "com.redhat.Yggdrasil1.Dispatcher1.TransmitComplete":
+				w.completeTransmit(s)
 			}
 		}
 	}()
 
+	// Emit a started event
+	err = w.EmitEvent(
+		ipc.WorkerEventNameStarted,
+		"",
+		"",
+		map[string]string{},
+	)
+	if err != nil {
+		return fmt.Errorf("cannot emit event: %w", err)
+	}
+
 	<-quit
 
+	w.drain()
+
 	// Emit a stopped event
 	err = w.EmitEvent(
 		ipc.WorkerEventNameStopped,
@@ -165,9 +371,57 @@ func (w *Worker) Connect(quit <-chan os.Signal) error {
 		return fmt.Errorf("cannot emit event: %w", err)
 	}
 
+	if _, err := w.conn.ReleaseName(w.busName); err != nil {
+		return fmt.Errorf("cannot release name %s on bus: %w", w.busName, err)
+	}
+
 	return nil
 }
 
+// drain stops the worker from accepting new messages and waits up to
+// DrainTimeout for the messages it is working on to finish. The context of
+// messages that have not finished by then is cancelled.
+func (w *Worker) drain() {
+	w.mu.Lock()
+	w.draining = true
+	w.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	log.Debugf("waiting up to %v for messages to finish", w.DrainTimeout)
+	select {
+	case <-done:
+	case <-time.After(w.DrainTimeout):
+		log.Warnf("messages did not finish within %v; cancelling", w.DrainTimeout)
+		w.stop()
+		select {
+		case <-done:
+		case <-time.After(drainCancelTimeout):
+			log.Warnf("messages did not finish after being cancelled")
+		}
+	}
+
+	// Cancel the context of any messages still being worked on.
+	w.stop()
+}
+
+// beginMessage records that the worker started working on a message. It returns
+// false if the worker is draining and cannot accept the message.
+func (w *Worker) beginMessage() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.draining {
+		return false
+	}
+	w.wg.Add(1)
+	return true
+}
+
 // SetFeature sets the value for the given key in the feature map and emits the
 // PropertiesChanged signal.
 func (w *Worker) SetFeature(name, value string) error {
@@ -180,6 +434,11 @@ func (w *Worker) SetFeature(name, value string) error {
 	)
 }
 
+// Directive returns the directive the worker handles.
+func (w *Worker) Directive() string {
+	return w.directive
+}
+
 // GetFeature retrieves the value from the feature map for given key.
 func (w *Worker) GetFeature(name string) string {
 	return w.features[name]
@@ -206,6 +465,94 @@ func (w *Worker) Transmit(
 	return
 }
 
+// TransmitStream wraps a com.redhat.Yggdrasil1.Dispatcher1.TransmitStream
+// method call for ease of use from the worker. The content read from content is
+// uploaded to the HTTP(S) URL addr as it is read. content remains open and must
+// be closed by the caller.
+func (w *Worker) TransmitStream(
+	addr string,
+	id string,
+	responseTo string,
+	metadata map[string]string,
+	content *os.File,
+) (responseCode int, responseMetadata map[string]string, responseData []byte, err error) {
+	obj := w.conn.Object("com.redhat.Yggdrasil1.Dispatcher1", "/com/redhat/Yggdrasil1/Dispatcher1")
+	err = obj.Call("com.redhat.Yggdrasil1.Dispatcher1.TransmitStream", 0, addr, id, responseTo, metadata, dbus.UnixFD(content.Fd())).
+		Store(&responseCode, &responseMetadata, &responseData)
+	if err != nil {
+		responseCode = -1
+		return
+	}
+	return
+}
+
+// TransmitAsync wraps a com.redhat.Yggdrasil1.Dispatcher1.TransmitAsync method
+// call for ease of use from the worker. It returns as soon as the dispatcher
+// accepts the data. The response is sent on the returned channel once the
+// dispatcher has transmitted the data, or failed to.
+func (w *Worker) TransmitAsync(
+	addr string,
+	id string,
+	responseTo string,
+	metadata map[string]string,
+	data []byte,
+) (<-chan TransmitResult, error) {
+	// Hold the lock until the token is recorded, so that a TransmitComplete
+	// signal received before the method call returns waits for it.
+	w.transmitMu.Lock()
+	defer w.transmitMu.Unlock()
+
+	var token string
+	obj := w.conn.Object("com.redhat.Yggdrasil1.Dispatcher1", "/com/redhat/Yggdrasil1/Dispatcher1")
+	err := obj.Call("com.redhat.Yggdrasil1.Dispatcher1.TransmitAsync", 0, addr, id, responseTo, metadata, data).
+		Store(&token)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan TransmitResult, 1)
+	w.transmits.Set(token, ch)
+
+	return ch, nil
+}
+
+// completeTransmit sends the response carried by a
+// com.redhat.Yggdrasil1.Dispatcher1.TransmitComplete signal to the channel
+// returned by the TransmitAsync call it answers.
+func (w *Worker) completeTransmit(s *dbus.Signal) {
+	var (
+		token            string
+		responseCode     int32
+		responseMetadata map[string]string
+		responseData     []byte
+		errorName        string
+		errorMessage     string
+	)
+	if err := dbus.Store(s.Body, &token, &responseCode, &responseMetadata, &responseData, &errorName, &errorMessage); err != nil {
+		log.Errorf("cannot read TransmitComplete signal: %v", err)
+		return
+	}
+
+	w.transmitMu.Lock()
+	ch, has := w.transmits.Pop(token)
+	w.transmitMu.Unlock()
+	if !has {
+		log.Debugf("ignoring TransmitComplete signal for unknown token %v", token)
+		return
+	}
+
+	result := TransmitResult{
+		ResponseCode:     int(responseCode),
+		ResponseMetadata: responseMetadata,
+		ResponseData:     responseData,
+	}
+	if errorName != "" {
+		result.ResponseCode = -1
+		result.Err = dbus.Error{Name: errorName, Body: []interface{}{errorMessage}}
+	}
+	ch <- result
+}
+
 // EmitEvent emits a WorkerEvent, worker message id, and key-value pairs of optional data.
 func (w *Worker) EmitEvent(
 	event ipc.WorkerEventName,
@@ -226,6 +573,15 @@ func (w *Worker) EmitEvent(
 		args...)
 }
 
+// EmitProgress emits a WORKING event reporting how far the worker has
+// progressed working on the message with the given ID.
+func (w *Worker) EmitProgress(messageID string, responseTo string, progress ipc.Progress) error {
+	if err := progress.Validate(); err != nil {
+		return fmt.Errorf("invalid progress: %w", err)
+	}
+	return w.EmitEvent(ipc.WorkerEventNameWorking, messageID, responseTo, progress.Data())
+}
+
 // cancel implements com.redhat.Yggdrasil1.Worker1.Cancel method by calling the
 // worker's cancelRxFunc in a goroutine.
 func (w *Worker) cancel(addr string, id string, cancelID string) *dbus.Error {
@@ -252,6 +608,11 @@ func (w *Worker) cancel(addr string, id string, cancelID string) *dbus.Error {
 	go func() {
 		if err := w.cancelRx(w, addr, id, cancelID); err != nil {
 			log.Errorf("callback function cancelRx() was terminated: %v", err)
+			// Communicate to yggd client that the work has failed.
+			if err := w.EmitEvent(ipc.WorkerEventNameFailed, id, "", failedEventData(err)); err != nil {
+				log.Errorf("cannot emit event: %v", err)
+			}
+			return
 		}
 		// Communicate to yggd client that the work has finished.
 		if err := w.EmitEvent(ipc.WorkerEventNameEnd, id, "", map[string]string{}); err != nil {
@@ -278,13 +639,84 @@ func (w *Worker) dispatch(
 	log.Tracef("metadata = %#v", metadata)
 	log.Tracef("data = %v", data)
 
+	return w.startMessage(id, responseTo, metadata, func(ctx context.Context) error {
+		return w.callRx(ctx, addr, id, responseTo, metadata, data)
+	})
+}
+
+// dispatchStream implements the com.redhat.Yggdrasil1.Worker1.DispatchStream
+// method by calling the worker's RxStreamFunc in a goroutine with a reader of
+// the file descriptor content.
+func (w *Worker) dispatchStream(
+	addr string,
+	id string,
+	responseTo string,
+	metadata map[string]string,
+	content dbus.UnixFD,
+) *dbus.Error {
+	log.Tracef("addr = %v", addr)
+	log.Tracef("id = %v", id)
+	log.Tracef("responseTo = %v", responseTo)
+	log.Tracef("metadata = %#v", metadata)
+	log.Tracef("content = %v", content)
+
+	f := os.NewFile(uintptr(content), "content")
+	if f == nil {
+		return dbus.MakeFailedError(fmt.Errorf("invalid file descriptor %v", content))
+	}
+
+	dbusErr := w.startMessage(id, responseTo, metadata, func(ctx context.Context) error {
+		defer func() {
+			if err := f.Close(); err != nil {
+				log.Errorf("cannot close content of message %v: %v", id, err)
+			}
+		}()
+		return w.callRxStream(ctx, addr, id, responseTo, metadata, f)
+	})
+	if dbusErr != nil {
+		_ = f.Close()
+	}
+	return dbusErr
+}
+
+// startMessage emits a BEGIN event for the message with the given ID and calls
+// rx in a goroutine with the context of the message, emitting an END or FAILED
+// event when it returns. It returns an error without calling rx if the worker
+// is shutting down.
+func (w *Worker) startMessage(
+	id string,
+	responseTo string,
+	metadata map[string]string,
+	rx func(ctx context.Context) error,
+) *dbus.Error {
+	if !w.beginMessage() {
+		log.Debugf("rejecting message %v; worker is shutting down", id)
+		return dbus.NewError(ipc.ErrorNameWorkerDraining, []interface{}{"worker is shutting down"})
+	}
+
+	// Register the message's cancel function before emitting BEGIN, so that
+	// the message can be cancelled as soon as the dispatcher learns of it.
+	ctx, cancel := messageContext(w.ctx, metadata)
+	w.cancelFuncs.Set(id, cancel)
+
 	if err := w.EmitEvent(ipc.WorkerEventNameBegin, id, responseTo, map[string]string{}); err != nil {
+		w.cancelFuncs.Del(id)
+		cancel()
+		w.wg.Done()
 		return dbus.NewError("com.redhat.Yggdrasil1.Worker1.EventError", []interface{}{err.Error()})
 	}
 
 	go func() {
-		if err := w.rx(w, addr, id, responseTo, metadata, data); err != nil {
+		defer w.wg.Done()
+		defer cancel()
+		defer w.cancelFuncs.Del(id)
+
+		if err := rx(ctx); err != nil {
 			log.Errorf("cannot call rx: %v", err)
+			if err := w.EmitEvent(ipc.WorkerEventNameFailed, id, responseTo, failedEventData(err)); err != nil {
+				log.Errorf("cannot emit event: %v", err)
+			}
+			return
 		}
 		if err := w.EmitEvent(ipc.WorkerEventNameEnd, id, responseTo, map[string]string{}); err != nil {
 			log.Errorf("cannot emit event: %v", err)
@@ -293,3 +725,84 @@ func (w *Worker) dispatch(
 
 	return nil
 }
+
+// callRx calls the worker's RxStreamFunc with a reader of data, if it has
+// one, its RxContextFunc, if it has one, or its RxFunc.
+func (w *Worker) callRx(
+	ctx context.Context,
+	addr string,
+	id string,
+	responseTo string,
+	metadata map[string]string,
+	data []byte,
+) error {
+	if w.rxStream != nil {
+		return w.callRxStream(ctx, addr, id, responseTo, metadata, bytes.NewReader(data))
+	}
+	if w.rxContext == nil {
+		return w.rx(w, addr, id, responseTo, metadata, data)
+	}
+	return w.rxContext(ctx, w, addr, id, responseTo, metadata, data)
+}
+
+// callRxStream calls the worker's RxStreamFunc with content.
+func (w *Worker) callRxStream(
+	ctx context.Context,
+	addr string,
+	id string,
+	responseTo string,
+	metadata map[string]string,
+	content io.Reader,
+) error {
+	return w.rxStream(ctx, w, addr, id, responseTo, metadata, content)
+}
+
+// cancelContext is the CancelRxFunc of a worker created with NewContextWorker.
+// It cancels the context of the message with the given cancelID.
+func cancelContext(w *Worker, addr string, id string, cancelID string) error {
+	cancel, has := w.cancelFuncs.Get(cancelID)
+	if !has {
+		return NewError(
+			ErrorCodeUnknownMessage,
+			fmt.Errorf("message %v is not in progress and cannot be cancelled", cancelID),
+		)
+	}
+	log.Debugf("cancelling message %v", cancelID)
+	cancel()
+	return nil
+}
+
+// messageContext returns a copy of parent for a message with the given
+// metadata. The context's deadline is set to the message's deadline, if the
+// metadata includes one.
+func messageContext(
+	parent context.Context,
+	metadata map[string]string,
+) (context.Context, context.CancelFunc) {
+	if value, has := metadata[ipc.MetadataKeyDeadline]; has {
+		deadline, err := time.Parse(time.RFC3339, value)
+		if err == nil {
+			return context.WithDeadline(parent, deadline)
+		}
+		log.Warnf("ignoring invalid deadline '%v': %v", value, err)
+	}
+	return context.WithCancel(parent)
+}
+
+// failedEventData returns the data of the FAILED event describing err.
+func failedEventData(err error) map[string]string {
+	code := ErrorCodeUnknown
+	var workerErr *Error
+	switch {
+	case errors.As(err, &workerErr) && workerErr.Code != "":
+		code = workerErr.Code
+	case errors.Is(err, context.DeadlineExceeded):
+		code = ErrorCodeDeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		code = ErrorCodeCancelled
+	}
+	return map[string]string{
+		ipc.WorkerEventDataKeyCode:    code,
+		ipc.WorkerEventDataKeyMessage: err.Error(),
+	}
+}
@@ -0,0 +1,112 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFailedEventData(t *testing.T) {
+	tests := []struct {
+		description string
+		input       error
+		want        map[string]string
+	}{
+		{
+			description: "error",
+			input:       fmt.Errorf("cannot open file"),
+			want: map[string]string{
+				"code":    "unknown",
+				"message": "cannot open file",
+			},
+		},
+		{
+			description: "worker error",
+			input:       NewError("invalid-content", fmt.Errorf("cannot parse content")),
+			want: map[string]string{
+				"code":    "invalid-content",
+				"message": "cannot parse content",
+			},
+		},
+		{
+			description: "wrapped worker error",
+			input: fmt.Errorf(
+				"cannot echo: %w",
+				NewError("transmit-failed", fmt.Errorf("connection refused")),
+			),
+			want: map[string]string{
+				"code":    "transmit-failed",
+				"message": "cannot echo: connection refused",
+			},
+		},
+		{
+			description: "cancelled",
+			input:       fmt.Errorf("cannot echo: %w", context.Canceled),
+			want: map[string]string{
+				"code":    "cancelled",
+				"message": "cannot echo: context canceled",
+			},
+		},
+		{
+			description: "deadline exceeded",
+			input:       fmt.Errorf("cannot echo: %w", context.DeadlineExceeded),
+			want: map[string]string{
+				"code":    "deadline-exceeded",
+				"message": "cannot echo: context deadline exceeded",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			got := failedEventData(test.input)
+
+			if !cmp.Equal(got, test.want) {
+				t.Errorf("%v", cmp.Diff(got, test.want))
+			}
+		})
+	}
+}
+
+func TestMessageContext(t *testing.T) {
+	tests := []struct {
+		description  string
+		input        map[string]string
+		want         time.Time
+		wantDeadline bool
+	}{
+		{
+			description:  "deadline",
+			input:        map[string]string{"deadline": "2000-01-01T01:00:00Z"},
+			want:         time.Date(2000, time.January, 1, 1, 0, 0, 0, time.UTC),
+			wantDeadline: true,
+		},
+		{
+			description: "no deadline",
+			input:       map[string]string{},
+		},
+		{
+			description: "invalid deadline",
+			input:       map[string]string{"deadline": "tomorrow"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			ctx, cancel := messageContext(context.Background(), test.input)
+			defer cancel()
+
+			got, has := ctx.Deadline()
+
+			if has != test.wantDeadline {
+				t.Errorf("%v != %v", has, test.wantDeadline)
+			}
+			if !got.Equal(test.want) {
+				t.Errorf("%v != %v", got, test.want)
+			}
+		})
+	}
+}
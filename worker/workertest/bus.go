@@ -0,0 +1,70 @@
+package workertest
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// busConfig is the configuration of the private message bus. It allows any
+// connection to own any name and to send messages to any destination, and
+// does not activate services.
+const busConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:dir=%v</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+
+// StartBus starts a private dbus-daemon for the duration of the test and sets
+// DBUS_SESSION_BUS_ADDRESS to its address, so that workers connect to it. It
+// returns the address of the bus. The test is skipped if dbus-daemon is not
+// installed.
+func StartBus(t testing.TB) string {
+	t.Helper()
+
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skipf("cannot find dbus-daemon: %v", err)
+	}
+
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "bus.conf")
+	if err := os.WriteFile(configFile, []byte(fmt.Sprintf(busConfig, dir)), 0600); err != nil {
+		t.Fatalf("cannot write bus configuration: %v", err)
+	}
+
+	cmd := exec.Command(daemon, "--config-file="+configFile, "--nofork", "--print-address=1")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("cannot get stdout of dbus-daemon: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("cannot start dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("cannot read address of dbus-daemon: %v", err)
+	}
+	address = strings.TrimSpace(address)
+
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", address)
+
+	return address
+}
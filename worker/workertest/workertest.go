@@ -0,0 +1,544 @@
+// Package workertest provides utilities for testing workers without a running
+// yggd or system bus.
+//
+// A Harness starts a private message bus, connects a worker to it and plays
+// the role of yggd's com.redhat.Yggdrasil1.Dispatcher1 object:
+//
+//	func TestEcho(t *testing.T) {
+//		w, _ := worker.NewContextWorker("echo", false, nil, echo, nil)
+//		h := workertest.New(t, w)
+//
+//		if err := h.Dispatch("echo", "1", "", nil, []byte("hello")); err != nil {
+//			t.Fatal(err)
+//		}
+//		h.WaitEvent(ipc.WorkerEventNameEnd, "1")
+//
+//		if got := h.Transmits(); len(got) != 1 {
+//			t.Errorf("expected 1 transmit, got %v", len(got))
+//		}
+//	}
+package workertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/redhatinsights/yggdrasil/ipc"
+	"github.com/redhatinsights/yggdrasil/worker"
+)
+
+// DefaultTimeout is the default duration a Harness waits for the worker to
+// start, emit an event or change a feature before failing the test.
+const DefaultTimeout = 5 * time.Second
+
+// Transmit records a call of the com.redhat.Yggdrasil1.Dispatcher1.Transmit,
+// TransmitAsync or TransmitStream method. The Data of a TransmitStream call is
+// the content read from its file descriptor.
+type Transmit struct {
+	Addr       string
+	MessageID  string
+	ResponseTo string
+	Metadata   map[string]string
+	Data       []byte
+}
+
+// TransmitResponse is the response returned to the worker by the
+// com.redhat.Yggdrasil1.Dispatcher1.Transmit or TransmitStream method, or sent to it in the
+// TransmitComplete signal answering a TransmitAsync call.
+type TransmitResponse struct {
+	Code     int
+	Metadata map[string]string
+	Data     []byte
+}
+
+// TransmitFunc is a function type that gets called each time the worker calls
+// the com.redhat.Yggdrasil1.Dispatcher1.Transmit, TransmitAsync or
+// TransmitStream method. If it returns an error, the worker receives a D-Bus
+// error.
+type TransmitFunc func(tx Transmit) (TransmitResponse, error)
+
+// Harness connects a worker to a private message bus and implements the
+// com.redhat.Yggdrasil1.Dispatcher1 interface for it.
+type Harness struct {
+	// Timeout is the duration the harness waits for the worker to emit an
+	// event or change a feature before failing the test.
+	Timeout time.Duration
+
+	t      testing.TB
+	worker *worker.Worker
+	conn   *dbus.Conn
+	quit   chan os.Signal
+	stop   sync.Once
+
+	mu         sync.Mutex
+	notify     chan struct{}
+	transmitFn TransmitFunc
+	transmits  []Transmit
+	tokens     int
+	events     []ipc.WorkerEvent
+	pending    []ipc.WorkerEvent
+	features   map[string]string
+}
+
+// New starts a private message bus, exports a fake dispatcher onto it and
+// connects w to it. The worker is stopped, and the bus torn down, when the test
+// finishes. The test is skipped if dbus-daemon is not installed.
+func New(t testing.TB, w *worker.Worker) *Harness {
+	t.Helper()
+
+	address := StartBus(t)
+
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatalf("cannot connect to bus: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	h := &Harness{
+		Timeout:  DefaultTimeout,
+		t:        t,
+		worker:   w,
+		conn:     conn,
+		quit:     make(chan os.Signal, 1),
+		notify:   make(chan struct{}, 1),
+		features: map[string]string{},
+	}
+
+	if err := conn.ExportMethodTable(
+		map[string]interface{}{
+			"Transmit":       h.transmit,
+			"TransmitAsync":  h.transmitAsync,
+			"TransmitStream": h.transmitStream,
+		},
+		"/com/redhat/Yggdrasil1/Dispatcher1",
+		"com.redhat.Yggdrasil1.Dispatcher1",
+	); err != nil {
+		t.Fatalf("cannot export com.redhat.Yggdrasil1.Dispatcher1 interface: %v", err)
+	}
+	reply, err := conn.RequestName("com.redhat.Yggdrasil1.Dispatcher1", dbus.NameFlagDoNotQueue)
+	if err != nil {
+		t.Fatalf("cannot request name com.redhat.Yggdrasil1.Dispatcher1: %v", err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("name com.redhat.Yggdrasil1.Dispatcher1 already taken")
+	}
+
+	if err := conn.AddMatchSignal(dbus.WithMatchObjectPath(h.objectPath())); err != nil {
+		t.Fatalf("cannot add signal match on %v: %v", h.objectPath(), err)
+	}
+	signals := make(chan *dbus.Signal, 64)
+	conn.Signal(signals)
+	go h.receiveSignals(signals)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Connect(h.quit)
+	}()
+	t.Cleanup(func() {
+		h.Stop()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("cannot connect worker: %v", err)
+			}
+		case <-time.After(w.DrainTimeout + h.Timeout):
+			t.Errorf("worker did not stop")
+		}
+	})
+
+	h.WaitEvent(ipc.WorkerEventNameStarted, "")
+
+	return h
+}
+
+// Stop signals the worker to shut down, as yggd stopping the worker's service
+// would. The worker drains the messages it is working on and emits a STOPPED
+// event before disconnecting; Stop does not wait for it. The worker is stopped
+// at most once, and is stopped when the test finishes if Stop is not called.
+func (h *Harness) Stop() {
+	h.stop.Do(func() {
+		h.quit <- os.Interrupt
+	})
+}
+
+// Dispatch calls the worker's com.redhat.Yggdrasil1.Worker1.Dispatch method.
+func (h *Harness) Dispatch(
+	addr string,
+	id string,
+	responseTo string,
+	metadata map[string]string,
+	data []byte,
+) error {
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	return h.workerObject().
+		Call("com.redhat.Yggdrasil1.Worker1.Dispatch", 0, addr, id, responseTo, metadata, data).
+		Store()
+}
+
+// DispatchStream calls the worker's com.redhat.Yggdrasil1.Worker1.DispatchStream
+// method, passing the file descriptor of content. The worker reads the content
+// from its own copy of the file descriptor, from the current offset of
+// content.
+func (h *Harness) DispatchStream(
+	addr string,
+	id string,
+	responseTo string,
+	metadata map[string]string,
+	content *os.File,
+) error {
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	return h.workerObject().
+		Call("com.redhat.Yggdrasil1.Worker1.DispatchStream", 0, addr, id, responseTo, metadata, dbus.UnixFD(content.Fd())).
+		Store()
+}
+
+// Cancel calls the worker's com.redhat.Yggdrasil1.Worker1.Cancel method,
+// requesting the cancellation of the message with the given cancelID.
+func (h *Harness) Cancel(id string, cancelID string) error {
+	return h.workerObject().
+		Call("com.redhat.Yggdrasil1.Worker1.Cancel", 0, h.worker.Directive(), id, cancelID).
+		Store()
+}
+
+// EmitDispatcherEvent emits a com.redhat.Yggdrasil1.Dispatcher1.Event signal.
+func (h *Harness) EmitDispatcherEvent(event ipc.DispatcherEvent) error {
+	return h.conn.Emit(
+		"/com/redhat/Yggdrasil1/Dispatcher1",
+		"com.redhat.Yggdrasil1.Dispatcher1.Event",
+		event,
+	)
+}
+
+// OnTransmit sets the function that gets called each time the worker calls the
+// com.redhat.Yggdrasil1.Dispatcher1.Transmit, TransmitAsync or TransmitStream
+// method. By default, the response is a zero response code and no data.
+func (h *Harness) OnTransmit(f TransmitFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.transmitFn = f
+}
+
+// Transmits returns the calls of the com.redhat.Yggdrasil1.Dispatcher1.Transmit,
+// TransmitAsync and TransmitStream methods made by the worker.
+func (h *Harness) Transmits() []Transmit {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]Transmit{}, h.transmits...)
+}
+
+// Events returns the events emitted by the worker.
+func (h *Harness) Events() []ipc.WorkerEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]ipc.WorkerEvent{}, h.events...)
+}
+
+// WaitEvent waits for the worker to emit an event with the given name for the
+// message with the given ID, failing the test if it does not within Timeout.
+// Each event is returned by WaitEvent at most once.
+func (h *Harness) WaitEvent(name ipc.WorkerEventName, messageID string) ipc.WorkerEvent {
+	h.t.Helper()
+
+	timeout := time.After(h.Timeout)
+	for {
+		h.mu.Lock()
+		for i, event := range h.pending {
+			if event.Name == name && event.MessageID == messageID {
+				h.pending = append(h.pending[:i], h.pending[i+1:]...)
+				h.mu.Unlock()
+				return event
+			}
+		}
+		h.mu.Unlock()
+
+		select {
+		case <-h.notify:
+		case <-timeout:
+			h.t.Fatalf("worker did not emit %v event for message '%v' within %v", name, messageID, h.Timeout)
+		}
+	}
+}
+
+// Features returns the features of the worker, as of the last change the
+// worker announced.
+func (h *Harness) Features() map[string]string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	features := map[string]string{}
+	for k, v := range h.features {
+		features[k] = v
+	}
+	return features
+}
+
+// Manifest returns the manifest the worker exports in its Manifest property.
+func (h *Harness) Manifest() ipc.WorkerManifest {
+	h.t.Helper()
+
+	v, err := h.workerObject().GetProperty("com.redhat.Yggdrasil1.Worker1.Manifest")
+	if err != nil {
+		h.t.Fatalf("cannot get property 'com.redhat.Yggdrasil1.Worker1.Manifest': %v", err)
+	}
+	value, ok := v.Value().(string)
+	if !ok {
+		h.t.Fatalf("cannot convert %T to string", v.Value())
+	}
+	var manifest ipc.WorkerManifest
+	if err := json.Unmarshal([]byte(value), &manifest); err != nil {
+		h.t.Fatalf("cannot unmarshal manifest: %v", err)
+	}
+	return manifest
+}
+
+// WaitFeature waits for the worker to change the feature with the given name
+// to value, failing the test if it does not within Timeout.
+func (h *Harness) WaitFeature(name string, value string) {
+	h.t.Helper()
+
+	timeout := time.After(h.Timeout)
+	for {
+		h.mu.Lock()
+		got, has := h.features[name]
+		h.mu.Unlock()
+		if has && got == value {
+			return
+		}
+
+		select {
+		case <-h.notify:
+		case <-timeout:
+			h.t.Fatalf("worker did not set feature '%v' to '%v' within %v", name, value, h.Timeout)
+		}
+	}
+}
+
+// transmit implements the com.redhat.Yggdrasil1.Dispatcher1.Transmit method.
+func (h *Harness) transmit(
+	addr string,
+	messageID string,
+	responseTo string,
+	metadata map[string]string,
+	data []byte,
+) (int, map[string]string, []byte, *dbus.Error) {
+	tx := Transmit{
+		Addr:       addr,
+		MessageID:  messageID,
+		ResponseTo: responseTo,
+		Metadata:   metadata,
+		Data:       data,
+	}
+
+	return h.respond(tx)
+}
+
+// transmitStream implements the
+// com.redhat.Yggdrasil1.Dispatcher1.TransmitStream method, reading the content
+// from the file descriptor before responding.
+func (h *Harness) transmitStream(
+	addr string,
+	messageID string,
+	responseTo string,
+	metadata map[string]string,
+	content dbus.UnixFD,
+) (int, map[string]string, []byte, *dbus.Error) {
+	f := os.NewFile(uintptr(content), "content")
+	defer func() {
+		_ = f.Close()
+	}()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return -1, nil, nil, dbus.MakeFailedError(fmt.Errorf("cannot read content: %w", err))
+	}
+
+	tx := Transmit{
+		Addr:       addr,
+		MessageID:  messageID,
+		ResponseTo: responseTo,
+		Metadata:   metadata,
+		Data:       data,
+	}
+
+	return h.respond(tx)
+}
+
+// transmitAsync implements the com.redhat.Yggdrasil1.Dispatcher1.TransmitAsync
+// method, sending the TransmitComplete signal to the worker after returning.
+func (h *Harness) transmitAsync(
+	sender dbus.Sender,
+	addr string,
+	messageID string,
+	responseTo string,
+	metadata map[string]string,
+	data []byte,
+) (string, *dbus.Error) {
+	tx := Transmit{
+		Addr:       addr,
+		MessageID:  messageID,
+		ResponseTo: responseTo,
+		Metadata:   metadata,
+		Data:       data,
+	}
+
+	h.mu.Lock()
+	h.tokens++
+	token := fmt.Sprintf("%v", h.tokens)
+	h.mu.Unlock()
+
+	go func() {
+		code, responseMetadata, responseData, err := h.respond(tx)
+		var errorName, errorMessage string
+		if err != nil {
+			errorName = err.Name
+			errorMessage = err.Error()
+			responseMetadata = map[string]string{}
+			responseData = []byte{}
+		}
+		args := []interface{}{token, int32(code), responseMetadata, responseData, errorName, errorMessage}
+		msg := &dbus.Message{
+			Type: dbus.TypeSignal,
+			Headers: map[dbus.HeaderField]dbus.Variant{
+				dbus.FieldPath:        dbus.MakeVariant(dbus.ObjectPath("/com/redhat/Yggdrasil1/Dispatcher1")),
+				dbus.FieldInterface:   dbus.MakeVariant("com.redhat.Yggdrasil1.Dispatcher1"),
+				dbus.FieldMember:      dbus.MakeVariant("TransmitComplete"),
+				dbus.FieldDestination: dbus.MakeVariant(string(sender)),
+				dbus.FieldSignature:   dbus.MakeVariant(dbus.SignatureOf(args...)),
+			},
+			Body: args,
+		}
+		if call := h.conn.Send(msg, nil); call.Err != nil {
+			h.t.Errorf("cannot send TransmitComplete signal: %v", call.Err)
+		}
+	}()
+
+	return token, nil
+}
+
+// respond records tx and returns the response of the TransmitFunc set with
+// OnTransmit.
+func (h *Harness) respond(tx Transmit) (int, map[string]string, []byte, *dbus.Error) {
+	h.mu.Lock()
+	h.transmits = append(h.transmits, tx)
+	f := h.transmitFn
+	h.mu.Unlock()
+
+	if f == nil {
+		return 0, map[string]string{}, []byte{}, nil
+	}
+
+	resp, err := f(tx)
+	if err != nil {
+		return -1, nil, nil, dbus.MakeFailedError(err)
+	}
+	if resp.Metadata == nil {
+		resp.Metadata = map[string]string{}
+	}
+	if resp.Data == nil {
+		resp.Data = []byte{}
+	}
+	return resp.Code, resp.Metadata, resp.Data, nil
+}
+
+// receiveSignals records the events and feature changes of the worker received
+// on signals.
+func (h *Harness) receiveSignals(signals <-chan *dbus.Signal) {
+	for s := range signals {
+		switch s.Name {
+		case "com.redhat.Yggdrasil1.Worker1.Event":
+			event, err := workerEventFromSignal(s)
+			if err != nil {
+				h.t.Errorf("cannot unpack signal: %v", err)
+				continue
+			}
+			event.Worker = h.worker.Directive()
+
+			h.mu.Lock()
+			h.events = append(h.events, *event)
+			h.pending = append(h.pending, *event)
+			h.mu.Unlock()
+		case "org.freedesktop.DBus.Properties.PropertiesChanged":
+			if len(s.Body) < 2 {
+				continue
+			}
+			changedProperties, ok := s.Body[1].(map[string]dbus.Variant)
+			if !ok {
+				continue
+			}
+			features, ok := changedProperties["Features"].Value().(map[string]string)
+			if !ok {
+				continue
+			}
+
+			h.mu.Lock()
+			h.features = features
+			h.mu.Unlock()
+		default:
+			continue
+		}
+
+		select {
+		case h.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// objectPath returns the path of the worker's object.
+func (h *Harness) objectPath() dbus.ObjectPath {
+	return dbus.ObjectPath(path.Join("/com/redhat/Yggdrasil1/Worker1", h.worker.Directive()))
+}
+
+// workerObject returns the worker's object on the bus.
+func (h *Harness) workerObject() dbus.BusObject {
+	return h.conn.Object(
+		fmt.Sprintf("com.redhat.Yggdrasil1.Worker1.%v", h.worker.Directive()),
+		h.objectPath(),
+	)
+}
+
+// workerEventFromSignal unpacks a com.redhat.Yggdrasil1.Worker1.Event signal.
+func workerEventFromSignal(s *dbus.Signal) (*ipc.WorkerEvent, error) {
+	if len(s.Body) < 4 {
+		return nil, fmt.Errorf("signal body has %v elements, expected 4", len(s.Body))
+	}
+	name, ok := s.Body[0].(uint32)
+	if !ok {
+		return nil, fmt.Errorf("cannot convert %T to uint32", s.Body[0])
+	}
+	messageID, ok := s.Body[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("cannot convert %T to string", s.Body[1])
+	}
+	responseTo, ok := s.Body[2].(string)
+	if !ok {
+		return nil, fmt.Errorf("cannot convert %T to string", s.Body[2])
+	}
+	data, ok := s.Body[3].(map[string]string)
+	if !ok {
+		return nil, fmt.Errorf("cannot convert %T to map[string]string", s.Body[3])
+	}
+
+	return &ipc.WorkerEvent{
+		Name:       ipc.WorkerEventName(name),
+		MessageID:  messageID,
+		ResponseTo: responseTo,
+		Data:       data,
+	}, nil
+}
@@ -0,0 +1,474 @@
+package workertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/google/go-cmp/cmp"
+	"github.com/redhatinsights/yggdrasil/ipc"
+	"github.com/redhatinsights/yggdrasil/worker"
+)
+
+// echo transmits the data it receives back to the dispatcher, unless the data
+// is "wait", in which case it waits for the message to be cancelled. If the
+// data is "async", it is transmitted with TransmitAsync, and if it is
+// "stream", it is written to a pipe transmitted with TransmitStream.
+func echo(
+	ctx context.Context,
+	w *worker.Worker,
+	addr string,
+	id string,
+	responseTo string,
+	metadata map[string]string,
+	data []byte,
+) error {
+	if string(data) == "wait" {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if string(data) == "async" {
+		ch, err := w.TransmitAsync(addr, "reply-"+id, id, metadata, data)
+		if err != nil {
+			return fmt.Errorf("cannot call TransmitAsync: %w", err)
+		}
+		select {
+		case result := <-ch:
+			if result.Err != nil {
+				return fmt.Errorf("cannot transmit: %w", result.Err)
+			}
+			return w.SetFeature("Response", fmt.Sprintf("%v %s", result.ResponseCode, result.ResponseData))
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if string(data) == "stream" {
+		r, pw, err := os.Pipe()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		go func() {
+			_, _ = pw.Write(data)
+			_ = pw.Close()
+		}()
+		code, _, _, err := w.TransmitStream(addr, "reply-"+id, id, metadata, r)
+		if err != nil {
+			return fmt.Errorf("cannot call TransmitStream: %w", err)
+		}
+		return w.SetFeature("Response", fmt.Sprintf("%v", code))
+	}
+	code, _, _, err := w.Transmit(addr, "reply-"+id, id, metadata, data)
+	if err != nil {
+		return fmt.Errorf("cannot call Transmit: %w", err)
+	}
+	if code != 0 {
+		return worker.NewError("transmit-failed", fmt.Errorf("unexpected response code %v", code))
+	}
+	return w.SetFeature("Echoed", id)
+}
+
+func newHarness(t *testing.T, events worker.EventHandlerFunc) *Harness {
+	w, err := worker.NewContextWorker("test", false, map[string]string{}, echo, events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(t, w)
+}
+
+func TestDispatch(t *testing.T) {
+	h := newHarness(t, nil)
+
+	if err := h.Dispatch("test", "1", "", map[string]string{"k": "v"}, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	h.WaitEvent(ipc.WorkerEventNameBegin, "1")
+	h.WaitEvent(ipc.WorkerEventNameEnd, "1")
+	h.WaitFeature("Echoed", "1")
+
+	want := []Transmit{
+		{
+			Addr:       "test",
+			MessageID:  "reply-1",
+			ResponseTo: "1",
+			Metadata:   map[string]string{"k": "v"},
+			Data:       []byte("hello"),
+		},
+	}
+	if got := h.Transmits(); !cmp.Equal(got, want) {
+		t.Errorf("%v", cmp.Diff(got, want))
+	}
+}
+
+func TestTransmitResponse(t *testing.T) {
+	h := newHarness(t, nil)
+	h.OnTransmit(func(tx Transmit) (TransmitResponse, error) {
+		return TransmitResponse{Code: 500}, nil
+	})
+
+	if err := h.Dispatch("test", "1", "", nil, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	got := h.WaitEvent(ipc.WorkerEventNameFailed, "1")
+
+	want := map[string]string{
+		"code":    "transmit-failed",
+		"message": "unexpected response code 500",
+	}
+	if !cmp.Equal(got.Data, want) {
+		t.Errorf("%v", cmp.Diff(got.Data, want))
+	}
+}
+
+func TestTransmitStream(t *testing.T) {
+	h := newHarness(t, nil)
+	h.OnTransmit(func(tx Transmit) (TransmitResponse, error) {
+		return TransmitResponse{Code: 201}, nil
+	})
+
+	if err := h.Dispatch("test", "1", "", map[string]string{"k": "v"}, []byte("stream")); err != nil {
+		t.Fatal(err)
+	}
+	h.WaitEvent(ipc.WorkerEventNameEnd, "1")
+	h.WaitFeature("Response", "201")
+
+	want := []Transmit{
+		{
+			Addr:       "test",
+			MessageID:  "reply-1",
+			ResponseTo: "1",
+			Metadata:   map[string]string{"k": "v"},
+			Data:       []byte("stream"),
+		},
+	}
+	if got := h.Transmits(); !cmp.Equal(got, want) {
+		t.Errorf("%v", cmp.Diff(got, want))
+	}
+}
+
+func TestTransmitAsync(t *testing.T) {
+	tests := []struct {
+		description string
+		response    TransmitResponse
+		err         error
+		wantFeature string
+		wantEvent   ipc.WorkerEventName
+		wantData    map[string]string
+	}{
+		{
+			description: "response",
+			response:    TransmitResponse{Code: 201, Data: []byte("created")},
+			wantFeature: "201 created",
+			wantEvent:   ipc.WorkerEventNameEnd,
+		},
+		{
+			description: "error",
+			err:         fmt.Errorf("broker unavailable"),
+			wantEvent:   ipc.WorkerEventNameFailed,
+			wantData: map[string]string{
+				"code":    "unknown",
+				"message": "cannot transmit: broker unavailable",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			h := newHarness(t, nil)
+			h.OnTransmit(func(tx Transmit) (TransmitResponse, error) {
+				return test.response, test.err
+			})
+
+			if err := h.Dispatch("test", "1", "", nil, []byte("async")); err != nil {
+				t.Fatal(err)
+			}
+			got := h.WaitEvent(test.wantEvent, "1")
+
+			if test.wantData != nil && !cmp.Equal(got.Data, test.wantData) {
+				t.Errorf("%v", cmp.Diff(got.Data, test.wantData))
+			}
+			if test.wantFeature != "" {
+				h.WaitFeature("Response", test.wantFeature)
+			}
+			if got := len(h.Transmits()); got != 1 {
+				t.Errorf("%v != 1", got)
+			}
+		})
+	}
+}
+
+func TestDispatchStream(t *testing.T) {
+	w, err := worker.NewStreamWorker(
+		"test",
+		map[string]string{},
+		func(
+			ctx context.Context,
+			w *worker.Worker,
+			addr string,
+			id string,
+			responseTo string,
+			metadata map[string]string,
+			content io.Reader,
+		) error {
+			data, err := io.ReadAll(content)
+			if err != nil {
+				return err
+			}
+			_, _, _, err = w.Transmit(addr, "reply-"+id, id, metadata, data)
+			return err
+		},
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := New(t, w)
+
+	f, err := os.CreateTemp(t.TempDir(), "content-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString("streamed content"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.DispatchStream("test", "1", "", nil, f); err != nil {
+		t.Fatal(err)
+	}
+	h.WaitEvent(ipc.WorkerEventNameEnd, "1")
+
+	if err := h.Dispatch("test", "2", "", nil, []byte("byte content")); err != nil {
+		t.Fatal(err)
+	}
+	h.WaitEvent(ipc.WorkerEventNameEnd, "2")
+
+	want := []Transmit{
+		{
+			Addr:       "test",
+			MessageID:  "reply-1",
+			ResponseTo: "1",
+			Metadata:   map[string]string{},
+			Data:       []byte("streamed content"),
+		},
+		{
+			Addr:       "test",
+			MessageID:  "reply-2",
+			ResponseTo: "2",
+			Metadata:   map[string]string{},
+			Data:       []byte("byte content"),
+		},
+	}
+	if got := h.Transmits(); !cmp.Equal(got, want) {
+		t.Errorf("%v", cmp.Diff(got, want))
+	}
+}
+
+func TestCancel(t *testing.T) {
+	h := newHarness(t, nil)
+
+	if err := h.Dispatch("test", "1", "", nil, []byte("wait")); err != nil {
+		t.Fatal(err)
+	}
+	h.WaitEvent(ipc.WorkerEventNameBegin, "1")
+
+	if err := h.Cancel("2", "1"); err != nil {
+		t.Fatal(err)
+	}
+	h.WaitEvent(ipc.WorkerEventNameEnd, "2")
+	got := h.WaitEvent(ipc.WorkerEventNameFailed, "1")
+
+	if got.Data["code"] != "cancelled" {
+		t.Errorf("%v != %v", got.Data["code"], "cancelled")
+	}
+}
+
+func TestCancelImmediately(t *testing.T) {
+	h := newHarness(t, nil)
+
+	// Cancel each message as soon as Dispatch returns, before the handler
+	// necessarily runs.
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprintf("%v", i)
+		if err := h.Dispatch("test", id, "", nil, []byte("wait")); err != nil {
+			t.Fatal(err)
+		}
+		if err := h.Cancel("cancel-"+id, id); err != nil {
+			t.Fatal(err)
+		}
+		h.WaitEvent(ipc.WorkerEventNameEnd, "cancel-"+id)
+		got := h.WaitEvent(ipc.WorkerEventNameFailed, id)
+
+		if got.Data["code"] != "cancelled" {
+			t.Errorf("%v: %v != %v", id, got.Data["code"], "cancelled")
+		}
+	}
+}
+
+func TestManifest(t *testing.T) {
+	w, err := worker.NewContextWorker("test", false, map[string]string{}, echo, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Manifest = ipc.WorkerManifest{
+		Version:       "1.0",
+		Description:   "Echoes data back to the dispatcher",
+		ContentTypes:  []string{"text/plain"},
+		ContentSchema: []byte(`{"type":"string"}`),
+	}
+	h := New(t, w)
+
+	want := ipc.WorkerManifest{
+		Version:       "1.0",
+		Description:   "Echoes data back to the dispatcher",
+		ContentTypes:  []string{"text/plain"},
+		ContentSchema: []byte(`{"type":"string"}`),
+		Cancellable:   true,
+	}
+	got := h.Manifest()
+	if !cmp.Equal(got, want) {
+		t.Errorf("%v", cmp.Diff(got, want))
+	}
+}
+
+func TestEmitDispatcherEvent(t *testing.T) {
+	received := make(chan ipc.DispatcherEvent, 1)
+	h := newHarness(t, func(e ipc.DispatcherEvent) {
+		received <- e
+	})
+
+	if err := h.EmitDispatcherEvent(ipc.DispatcherEventConnectionRestored); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-received:
+		if got != ipc.DispatcherEventConnectionRestored {
+			t.Errorf("%v != %v", got, ipc.DispatcherEventConnectionRestored)
+		}
+	case <-time.After(h.Timeout):
+		t.Fatal("worker did not receive dispatcher event")
+	}
+}
+
+// newBlockingHarness connects a worker whose messages block until release is
+// closed or their context is cancelled.
+func newBlockingHarness(t *testing.T, release <-chan struct{}) *Harness {
+	w, err := worker.NewContextWorker(
+		"test",
+		false,
+		map[string]string{},
+		func(
+			ctx context.Context,
+			w *worker.Worker,
+			addr string,
+			id string,
+			responseTo string,
+			metadata map[string]string,
+			data []byte,
+		) error {
+			select {
+			case <-release:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(t, w)
+}
+
+// eventIndex returns the index of the first event in events with the given
+// name and message ID, or -1.
+func eventIndex(events []ipc.WorkerEvent, name ipc.WorkerEventName, messageID string) int {
+	for i, event := range events {
+		if event.Name == name && event.MessageID == messageID {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestDrain(t *testing.T) {
+	release := make(chan struct{})
+	h := newBlockingHarness(t, release)
+
+	if err := h.Dispatch("test", "1", "", nil, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	h.WaitEvent(ipc.WorkerEventNameBegin, "1")
+
+	h.Stop()
+	close(release)
+	h.WaitEvent(ipc.WorkerEventNameEnd, "1")
+	h.WaitEvent(ipc.WorkerEventNameStopped, "")
+
+	events := h.Events()
+	if end, stopped := eventIndex(events, ipc.WorkerEventNameEnd, "1"),
+		eventIndex(events, ipc.WorkerEventNameStopped, ""); end > stopped {
+		t.Errorf("worker stopped before message finished: %v", events)
+	}
+}
+
+func TestDrainRejectsDispatch(t *testing.T) {
+	release := make(chan struct{})
+	h := newBlockingHarness(t, release)
+
+	if err := h.Dispatch("test", "1", "", nil, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	h.WaitEvent(ipc.WorkerEventNameBegin, "1")
+	h.Stop()
+
+	// The worker starts draining once it receives the signal to stop; until
+	// then, new messages are accepted.
+	var err error
+	timeout := time.After(h.Timeout)
+	for i := 2; err == nil; i++ {
+		select {
+		case <-timeout:
+			t.Fatal("worker did not reject messages while draining")
+		default:
+		}
+		err = h.Dispatch("test", fmt.Sprintf("%v", i), "", nil, []byte("hello"))
+	}
+
+	var dbusErr dbus.Error
+	if !errors.As(err, &dbusErr) || dbusErr.Name != ipc.ErrorNameWorkerDraining {
+		t.Errorf("%v != %v", err, ipc.ErrorNameWorkerDraining)
+	}
+
+	close(release)
+	h.WaitEvent(ipc.WorkerEventNameEnd, "1")
+	h.WaitEvent(ipc.WorkerEventNameStopped, "")
+}
+
+func TestDrainTimeout(t *testing.T) {
+	h := newBlockingHarness(t, make(chan struct{}))
+	h.worker.DrainTimeout = 100 * time.Millisecond
+
+	if err := h.Dispatch("test", "1", "", nil, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	h.WaitEvent(ipc.WorkerEventNameBegin, "1")
+
+	h.Stop()
+	got := h.WaitEvent(ipc.WorkerEventNameFailed, "1")
+	h.WaitEvent(ipc.WorkerEventNameStopped, "")
+
+	if got.Data["code"] != "cancelled" {
+		t.Errorf("%v != %v", got.Data["code"], "cancelled")
+	}
+}